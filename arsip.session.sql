@@ -120,6 +120,201 @@ CREATE INDEX ON archive_role_access(role_id);
 CREATE INDEX ON archive_role_access(department_id);
 CREATE INDEX ON archive_role_access(archive_hdr_id, role_id, department_id);
 
+CREATE TABLE refresh_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    token_hash VARCHAR(64) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    replaced_by_id INT,
+    status VARCHAR(1) DEFAULT 'Y' NOT NULL,
+    created_by VARCHAR(128) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    modified_by VARCHAR(128),
+    modified_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX ON refresh_tokens(token_hash);
+CREATE INDEX ON refresh_tokens(user_id);
+
+CREATE TABLE role_permissions (
+    id SERIAL PRIMARY KEY,
+    role_id INT NOT NULL,
+    permission VARCHAR(64) NOT NULL,
+    status VARCHAR(1) DEFAULT 'Y' NOT NULL,
+    created_by VARCHAR(128) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    modified_by VARCHAR(128),
+    modified_at TIMESTAMP
+);
+
+CREATE INDEX ON role_permissions(role_id, permission);
+
+-- Default grants so existing roles keep working; users:write, roles:write and
+-- master:write are left to the superuser to hand out.
+INSERT INTO role_permissions(role_id, permission, STATUS, CREATED_BY, CREATED_AT)
+SELECT id, 'master:read', 'Y', 'SYSTEM', CURRENT_TIMESTAMP FROM roles WHERE status = 'Y';
+INSERT INTO role_permissions(role_id, permission, STATUS, CREATED_BY, CREATED_AT)
+SELECT id, 'archives:write', 'Y', 'SYSTEM', CURRENT_TIMESTAMP FROM roles WHERE status = 'Y';
+INSERT INTO role_permissions(role_id, permission, STATUS, CREATED_BY, CREATED_AT)
+SELECT id, 'archives:delete', 'Y', 'SYSTEM', CURRENT_TIMESTAMP FROM roles WHERE status = 'Y';
+
+CREATE TABLE upload_sessions (
+    id SERIAL PRIMARY KEY,
+    upload_id VARCHAR(36) NOT NULL,
+    archive_hdr_id INT NOT NULL,
+    file_name VARCHAR(256) NOT NULL,
+    total_size BIGINT NOT NULL,
+    upload_offset BIGINT DEFAULT 0 NOT NULL,
+    checksum VARCHAR(64),
+    temp_location TEXT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    completed_at TIMESTAMP,
+    archive_attachment_id INT,
+    status VARCHAR(1) DEFAULT 'Y' NOT NULL,
+    created_by VARCHAR(128) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    modified_by VARCHAR(128),
+    modified_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX ON upload_sessions(upload_id);
+CREATE INDEX ON upload_sessions(expires_at) WHERE status = 'Y' AND completed_at IS NULL;
+
+ALTER TABLE archive_attachments ADD COLUMN mime_type VARCHAR(128);
+
+-- Existing rows predate content sniffing; derive their type from the stored
+-- file name once so the code never has to.
+UPDATE archive_attachments SET mime_type = CASE
+    WHEN lower(file_name) LIKE '%.pdf' THEN 'application/pdf'
+    WHEN lower(file_name) LIKE '%.png' THEN 'image/png'
+    WHEN lower(file_name) LIKE '%.jpg' OR lower(file_name) LIKE '%.jpeg' THEN 'image/jpeg'
+    WHEN lower(file_name) LIKE '%.webp' THEN 'image/webp'
+END
+WHERE mime_type IS NULL;
+
+-- file_location used to hold absolute host paths; it is now a key relative to
+-- the storage backend (archives/<archive id>/<file name>).
+UPDATE archive_attachments
+SET file_location = regexp_replace(replace(file_location, '\', '/'), '^.*/uploads/', '')
+WHERE file_location LIKE '%uploads%';
+
+CREATE TABLE attachment_blobs (
+    id SERIAL PRIMARY KEY,
+    hash VARCHAR(64) NOT NULL,
+    storage_key TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    mime_type VARCHAR(128),
+    ref_count INT DEFAULT 0 NOT NULL,
+    created_by VARCHAR(128) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    modified_by VARCHAR(128),
+    modified_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX ON attachment_blobs(hash);
+
+-- Attachments stored before deduplication keep their own file and a NULL hash.
+ALTER TABLE archive_attachments ADD COLUMN file_hash VARCHAR(64);
+CREATE INDEX ON archive_attachments(file_hash) WHERE status = 'Y';
+
+CREATE TABLE scrub_reports (
+    id SERIAL PRIMARY KEY,
+    state VARCHAR(16) NOT NULL,
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP,
+    checked INT DEFAULT 0 NOT NULL,
+    unverified INT DEFAULT 0 NOT NULL,
+    missing INT DEFAULT 0 NOT NULL,
+    corrupt INT DEFAULT 0 NOT NULL,
+    orphaned INT DEFAULT 0 NOT NULL,
+    unreferenced INT DEFAULT 0 NOT NULL,
+    errors INT DEFAULT 0 NOT NULL,
+    message TEXT,
+    status VARCHAR(1) DEFAULT 'Y' NOT NULL,
+    created_by VARCHAR(128) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    modified_by VARCHAR(128),
+    modified_at TIMESTAMP
+);
+
+CREATE TABLE scrub_issues (
+    id SERIAL PRIMARY KEY,
+    scrub_report_id INT NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    archive_attachment_id INT,
+    archive_hdr_id INT,
+    storage_key TEXT NOT NULL,
+    detail TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX ON scrub_issues(scrub_report_id);
+
+CREATE TABLE jobs (
+    id SERIAL PRIMARY KEY,
+    job_id VARCHAR(36) NOT NULL,
+    kind VARCHAR(32) NOT NULL,
+    archive_hdr_id INT NOT NULL,
+    state VARCHAR(16) NOT NULL,
+    progress INT DEFAULT 0 NOT NULL,
+    message TEXT,
+    result_url TEXT,
+    lease_id VARCHAR(36),
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP,
+    status VARCHAR(1) DEFAULT 'Y' NOT NULL,
+    created_by VARCHAR(128) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    modified_by VARCHAR(128),
+    modified_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX ON jobs(job_id);
+CREATE INDEX ON jobs(id) WHERE state = 'queued' AND status = 'Y';
+CREATE INDEX ON jobs(kind, archive_hdr_id) WHERE state IN ('queued', 'running') AND status = 'Y';
+
+-- Rotation is clockwise in degrees and is applied when the archive PDF is built.
+ALTER TABLE archive_attachments ADD COLUMN sort_order INT DEFAULT 0 NOT NULL;
+ALTER TABLE archive_attachments ADD COLUMN rotation INT DEFAULT 0 NOT NULL;
+
+-- Existing attachments keep the order they were uploaded in.
+UPDATE archive_attachments
+SET sort_order = numbered.rn
+FROM (
+    SELECT id, row_number() OVER (PARTITION BY archive_hdr_id ORDER BY id) AS rn
+    FROM archive_attachments
+) numbered
+WHERE archive_attachments.id = numbered.id;
+
+CREATE INDEX ON archive_attachments(archive_hdr_id, sort_order) WHERE status = 'Y';
+
+-- Text extracted from attachments for full-text search. The 'indonesian'
+-- configuration needs PostgreSQL 12 or later; queries must use the same one.
+CREATE TABLE attachment_texts (
+    id SERIAL PRIMARY KEY,
+    archive_attachment_id INT NOT NULL,
+    state VARCHAR(16) NOT NULL,
+    content TEXT,
+    message TEXT,
+    search_vector TSVECTOR GENERATED ALWAYS AS (to_tsvector('indonesian', coalesce(content, ''))) STORED,
+    status VARCHAR(1) DEFAULT 'Y' NOT NULL,
+    created_by VARCHAR(128) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    modified_by VARCHAR(128),
+    modified_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX ON attachment_texts(archive_attachment_id);
+CREATE INDEX ON attachment_texts USING GIN (search_vector);
+
+-- Typo tolerant archive search matches names and numbers by trigram
+-- similarity.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX ON archive_hdr USING GIN (archive_name gin_trgm_ops);
+CREATE INDEX ON archive_hdr USING GIN (archive_number gin_trgm_ops);
+
 drop table users;
 drop table roles;
 drop table archive_hdr;
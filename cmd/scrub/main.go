@@ -0,0 +1,80 @@
+// Command scrub verifies every active attachment against its stored file and
+// lists stored files no attachment refers to, and blobs only deleted
+// attachments refer to. The report is saved like one started from
+// /api/admin/scrub. It exits with status 1 when missing, corrupt or orphaned
+// files or errors are found, so it can run from cron; unreferenced blobs are
+// expected after deletes and only reported.
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/mugnialby/arsip-backend/internal/appcontext"
+	"github.com/mugnialby/arsip-backend/internal/config"
+	"github.com/mugnialby/arsip-backend/internal/repository"
+	"github.com/mugnialby/arsip-backend/internal/service"
+	"github.com/mugnialby/arsip-backend/pkg/logger"
+	"go.uber.org/zap"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	/*------ LOGGER ------*/
+	logger.Init()
+	defer logger.Log.Sync()
+
+	/*------ CONFIG ------*/
+	cfg := config.Load()
+
+	ctx, err := appcontext.NewAppContext(cfg)
+	if err != nil {
+		logger.Log.Error("scrub.failed",
+			zap.Error(err),
+		)
+		return 2
+	}
+
+	archiveAttachmentRepo := repository.NewArchiveAttachmentRepository(ctx.DB)
+	attachmentBlobRepo := repository.NewAttachmentBlobRepository(ctx.DB)
+	scrubReportRepo := repository.NewScrubReportRepository(ctx.DB)
+	scrubService := service.NewScrubService(archiveAttachmentRepo, attachmentBlobRepo, scrubReportRepo, ctx.Storage, time.Hour)
+
+	scrubReport, err := scrubService.Run(context.Background(), "SYSTEM")
+	if err != nil {
+		logger.Log.Error("scrub.failed",
+			zap.Error(err),
+		)
+		return 2
+	}
+
+	for _, issue := range scrubReport.Issues {
+		logger.Log.Warn("scrub.issue",
+			zap.String("kind", issue.Kind),
+			zap.String("storage_key", issue.StorageKey),
+			zap.Uint("report_id", scrubReport.ID),
+			zap.String("detail", issue.Detail),
+		)
+	}
+
+	logger.Log.Info("scrub.success",
+		zap.Uint("report_id", scrubReport.ID),
+		zap.Int("checked", scrubReport.Checked),
+		zap.Int("unverified", scrubReport.Unverified),
+		zap.Int("missing", scrubReport.Missing),
+		zap.Int("corrupt", scrubReport.Corrupt),
+		zap.Int("orphaned", scrubReport.Orphaned),
+		zap.Int("unreferenced", scrubReport.Unreferenced),
+		zap.Int("errors", scrubReport.Errors),
+	)
+
+	if scrubReport.Missing+scrubReport.Corrupt+scrubReport.Orphaned+scrubReport.Errors > 0 {
+		return 1
+	}
+
+	return 0
+}
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"time"
+
 	"github.com/mugnialby/arsip-backend/internal/api"
 	"github.com/mugnialby/arsip-backend/internal/appcontext"
 	"github.com/mugnialby/arsip-backend/internal/config"
 	"github.com/mugnialby/arsip-backend/internal/repository"
 	"github.com/mugnialby/arsip-backend/internal/service"
+	"github.com/mugnialby/arsip-backend/internal/utils"
 	"github.com/mugnialby/arsip-backend/pkg/logger"
 	"go.uber.org/zap"
 )
@@ -25,18 +28,30 @@ func main() {
 		)
 	}
 
+	jwtService := utils.NewJWTService(cfg.JWTSecret, cfg.AppName, time.Duration(cfg.JWTExpiresIn)*time.Minute)
+
 	/*------ SERVICES ------*/
 	archiveRepo := repository.NewArchiveRepository(ctx.DB)
-	archiveService := service.NewArchiveService(archiveRepo)
-
+	archiveRoleAccessRepo := repository.NewArchiveRoleAccessRepository(ctx.DB)
 	archiveAttachmentRepo := repository.NewArchiveAttachmentRepository(ctx.DB)
+	attachmentBlobRepo := repository.NewAttachmentBlobRepository(ctx.DB)
+	attachmentTextRepo := repository.NewAttachmentTextRepository(ctx.DB)
+	thumbnailService := service.NewThumbnailService(ctx.Storage, ctx.ThumbnailTools.MagickPath, ctx.ThumbnailTools.PdftoppmPath)
+	thumbnailService.Start(2)
+	textExtractionService := service.NewTextExtractionService(attachmentTextRepo, ctx.Storage, ctx.TextExtractor)
+	textExtractionService.Start(1, 10*time.Minute)
+	archiveService := service.NewArchiveService(ctx.DB, ctx.Storage, archiveRepo, archiveAttachmentRepo, attachmentBlobRepo, archiveRoleAccessRepo, attachmentTextRepo, thumbnailService, textExtractionService, ctx.PDFMerger, cfg.SuperuserRoleID, int64(cfg.UploadMaxSizeMB)<<20, cfg.PDFWatermarkTemplate)
+
 	archiveAttachmentService := service.NewArchiveAttachmentService(archiveAttachmentRepo)
 
-	userRepo := repository.NewUserRepository(ctx.DB)
-	userService := service.NewUserService(userRepo)
+	userRepo := repository.NewUserRepository(ctx.DB, cfg.SuperuserRoleID)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(ctx.DB)
+	userService := service.NewUserService(ctx.DB, userRepo, refreshTokenRepo)
+	authService := service.NewAuthService(userRepo, refreshTokenRepo, jwtService, time.Duration(cfg.JWTRefreshExpiresIn)*time.Minute)
 
-	roleRepo := repository.NewRoleRepository(ctx.DB)
-	roleService := service.NewRoleService(roleRepo)
+	roleRepo := repository.NewRoleRepository(ctx.DB, cfg.SuperuserRoleID)
+	rolePermissionRepo := repository.NewRolePermissionRepository(ctx.DB)
+	roleService := service.NewRoleService(roleRepo, rolePermissionRepo, cfg.SuperuserRoleID)
 
 	departmentRepo := repository.NewDepartmentRepository(ctx.DB)
 	departmentService := service.NewDepartmentService(departmentRepo)
@@ -47,23 +62,38 @@ func main() {
 	archiveCharacteristicRepo := repository.NewArchiveCharacteristicRepository(ctx.DB)
 	archiveCharacteristicService := service.NewArchiveCharacteristicService(archiveCharacteristicRepo)
 
-	archiveRoleAccessRepo := repository.NewArchiveRoleAccessRepository(ctx.DB)
 	archiveRoleAccessService := service.NewArchiveRoleAccessService(archiveRoleAccessRepo)
 
+	uploadSessionRepo := repository.NewUploadSessionRepository(ctx.DB)
+	uploadService := service.NewUploadService(uploadSessionRepo, archiveService, int64(cfg.UploadChunkedMaxSizeMB)<<20, time.Duration(cfg.UploadSessionExpiresIn)*time.Minute)
+	uploadService.StartExpiredUploadCleanup(time.Hour)
+
+	jobRepo := repository.NewJobRepository(ctx.DB)
+	jobService := service.NewJobService(jobRepo, archiveService)
+	jobService.Start(2)
+
+	scrubReportRepo := repository.NewScrubReportRepository(ctx.DB)
+	scrubService := service.NewScrubService(archiveAttachmentRepo, attachmentBlobRepo, scrubReportRepo, ctx.Storage, time.Hour)
+
 	/*------ ROUTERS ------*/
 	router := api.NewRouter(
 		userService,
 		roleService,
 		departmentService,
+		authService,
 		archiveService,
 		archiveAttachmentService,
 		archiveTypeService,
 		archiveCharacteristicService,
 		archiveRoleAccessService,
+		uploadService,
+		scrubService,
+		jobService,
 	)
 
 	logger.Log.Info("main.success",
 		zap.Any("message", "Server starting in"+cfg.AppEnv+" mode on port "+cfg.Port),
+		zap.Uint("superuser_role_id", cfg.SuperuserRoleID),
 	)
 
 	router.Run(":" + cfg.Port)
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mugnialby/arsip-backend/internal/listquery"
 	"github.com/mugnialby/arsip-backend/internal/model"
 	request "github.com/mugnialby/arsip-backend/internal/model/dto/request/archiveCharacteristic"
 	"github.com/mugnialby/arsip-backend/internal/service"
@@ -26,7 +27,19 @@ func (h *ArchiveCharacteristicHandler) GetAllArchiveCharacteristics(c *gin.Conte
 	start := time.Now()
 	requestID, _ := c.Get("request_id")
 
-	archiveCharacteristics, err := h.service.GetAllArchiveCharacteristics()
+	query, err := listquery.Parse(c.Request.URL.Query())
+	if err != nil {
+		logger.Log.Warn("archive_characteristic.get_all.invalid_query",
+			zap.String("request_id", requestID.(string)),
+			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
+
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	archiveCharacteristics, result, err := h.service.GetAllArchiveCharacteristics(query)
 	if err != nil {
 		logger.Log.Error("archive_characteristic.get_all.failed",
 			zap.String("request_id", requestID.(string)),
@@ -34,17 +47,18 @@ func (h *ArchiveCharacteristicHandler) GetAllArchiveCharacteristics(c *gin.Conte
 			zap.Duration("duration_ms", time.Since(start)),
 		)
 
-		response.Error(c, http.StatusInternalServerError, "Failed to get data")
+		respondListError(c, err, http.StatusInternalServerError, "Failed to get data")
 		return
 	}
 
 	logger.Log.Info("archive_characteristic.get_all.success",
 		zap.String("request_id", requestID.(string)),
 		zap.Int("count", len(archiveCharacteristics)),
+		zap.Int64("total", result.Total),
 		zap.Duration("duration_ms", time.Since(start)),
 	)
 
-	response.Success(c, archiveCharacteristics)
+	respondList(c, archiveCharacteristics, result)
 }
 
 func (h *ArchiveCharacteristicHandler) GetArchiveCharacteristicByID(c *gin.Context) {
@@ -104,7 +118,7 @@ func (h *ArchiveCharacteristicHandler) CreateArchiveCharacteristic(c *gin.Contex
 		ID:                        0,
 		ArchiveCharacteristicName: newArchiveCharacteristicRequest.ArchiveCharacteristicName,
 		Status:                    "Y",
-		CreatedBy:                 newArchiveCharacteristicRequest.SubmittedBy,
+		CreatedBy:                 currentUser(c).UserId,
 	}
 
 	if err := h.service.CreateArchiveCharacteristic(&newArchiveCharacteristic); err != nil {
@@ -156,9 +170,10 @@ func (h *ArchiveCharacteristicHandler) UpdateArchiveCharacteristicById(c *gin.Co
 		return
 	}
 
+	modifiedBy := currentUser(c).UserId
 	timeNow := time.Now()
 	archiveCharacteristic.ArchiveCharacteristicName = updateArchiveCharacteristicRequest.ArchiveCharacteristicName
-	archiveCharacteristic.ModifiedBy = &updateArchiveCharacteristicRequest.SubmittedBy
+	archiveCharacteristic.ModifiedBy = &modifiedBy
 	archiveCharacteristic.ModifiedAt = &timeNow
 
 	if err := h.service.UpdateArchiveCharacteristic(archiveCharacteristic); err != nil {
@@ -196,6 +211,8 @@ func (h *ArchiveCharacteristicHandler) DeleteArchiveCharacteristicById(c *gin.Co
 		return
 	}
 
+	deleteArchiveCharacteristicRequest.SubmittedBy = currentUser(c).UserId
+
 	if err := h.service.DeleteArchiveCharacteristic(&deleteArchiveCharacteristicRequest); err != nil {
 		logger.Log.Error("archive_characteristic.delete.failed",
 			zap.String("request_id", requestID.(string)),
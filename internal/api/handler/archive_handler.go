@@ -2,23 +2,25 @@ package handler
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"os"
-	"os/exec"
-	"path/filepath"
-	"runtime"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mugnialby/arsip-backend/internal/listquery"
 	"github.com/mugnialby/arsip-backend/internal/model"
 	archiveRequest "github.com/mugnialby/arsip-backend/internal/model/dto/request/archive"
-	archiveRoleAccessRequest "github.com/mugnialby/arsip-backend/internal/model/dto/request/archiveRoleAccess"
+	attachmentRequest "github.com/mugnialby/arsip-backend/internal/model/dto/request/archiveAttachment"
+	"github.com/mugnialby/arsip-backend/internal/pdf"
 	"github.com/mugnialby/arsip-backend/internal/service"
-	"github.com/mugnialby/arsip-backend/internal/utils"
+	"github.com/mugnialby/arsip-backend/internal/storage"
 	"github.com/mugnialby/arsip-backend/pkg/logger"
 	"github.com/mugnialby/arsip-backend/pkg/response"
 	"go.uber.org/zap"
@@ -30,13 +32,6 @@ type ArchiveHandler struct {
 	archiveRoleAccessService *service.ArchiveRoleAccessService
 }
 
-var CacheTTL = 5 * time.Minute
-
-type CacheItem struct {
-	Data      []byte
-	ExpiresAt time.Time
-}
-
 func NewArchiveHandler(
 	archiveService *service.ArchiveService,
 	archiveAttachmentService *service.ArchiveAttachmentService,
@@ -53,7 +48,19 @@ func (h *ArchiveHandler) GetAllArchives(c *gin.Context) {
 	start := time.Now()
 	requestID, _ := c.Get("request_id")
 
-	archives, err := h.archiveService.GetAllArchives()
+	query, err := listquery.Parse(c.Request.URL.Query())
+	if err != nil {
+		logger.Log.Warn("archive.get_all.invalid_query",
+			zap.String("request_id", requestID.(string)),
+			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
+
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	archives, result, err := h.archiveService.GetAllArchives(currentUser(c), query)
 	if err != nil {
 		logger.Log.Error("archive.get_all.failed",
 			zap.String("request_id", requestID.(string)),
@@ -61,54 +68,62 @@ func (h *ArchiveHandler) GetAllArchives(c *gin.Context) {
 			zap.Duration("duration_ms", time.Since(start)),
 		)
 
-		response.Error(c, http.StatusInternalServerError, "Failed to get all data")
+		respondListError(c, err, http.StatusInternalServerError, "Failed to get all data")
 		return
 	}
 
 	logger.Log.Info("archive.get_all.success",
 		zap.String("request_id", requestID.(string)),
 		zap.Int("count", len(archives)),
+		zap.Int64("total", result.Total),
 		zap.Duration("duration_ms", time.Since(start)),
 	)
 
-	response.Success(c, archives)
+	respondList(c, archives, result)
 }
 
 func (h *ArchiveHandler) GetAllArchivesByData(c *gin.Context) {
 	start := time.Now()
 	requestID, _ := c.Get("request_id")
 
-	var getArchiveByDataRequest archiveRequest.GetArchiveByDataRequest
-	if err := c.ShouldBindJSON(&getArchiveByDataRequest); err != nil {
-		logger.Log.Warn("archive.get_by_data.invalid_request",
+	// Role and department come from the access token; any filters sent in the
+	// body by older clients are ignored.
+	user := currentUser(c)
+
+	query, err := listquery.Parse(c.Request.URL.Query())
+	if err != nil {
+		logger.Log.Warn("archive.get_by_data.invalid_query",
 			zap.String("request_id", requestID.(string)),
 			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
 		)
 
-		response.Error(c, http.StatusBadRequest, "JSON request is not valid")
+		response.Error(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	archives, err := h.archiveService.GetAllArchivesByData(getArchiveByDataRequest)
+	archives, result, err := h.archiveService.GetAllArchivesByData(user, query)
 	if err != nil {
 		logger.Log.Error("archive.get_by_data.failed",
 			zap.String("request_id", requestID.(string)),
-			zap.Any("filters", getArchiveByDataRequest),
+			zap.Uint("role_id", user.RoleID),
+			zap.Uint("department_id", user.DepartmentID),
 			zap.Error(err),
 			zap.Duration("duration_ms", time.Since(start)),
 		)
 
-		response.Error(c, http.StatusInternalServerError, "Failed to get all data")
+		respondListError(c, err, http.StatusInternalServerError, "Failed to get all data")
 		return
 	}
 
 	logger.Log.Info("archive.get_by_data.success",
 		zap.String("request_id", requestID.(string)),
 		zap.Int("count", len(archives)),
+		zap.Int64("total", result.Total),
 		zap.Duration("duration_ms", time.Since(start)),
 	)
 
-	response.Success(c, archives)
+	respondList(c, archives, result)
 }
 
 func (h *ArchiveHandler) GetArchiveByID(c *gin.Context) {
@@ -128,7 +143,7 @@ func (h *ArchiveHandler) GetArchiveByID(c *gin.Context) {
 		return
 	}
 
-	archive, err := h.archiveService.GetArchiveByID(uint(id))
+	archive, err := h.archiveService.GetArchiveByID(uint(id), currentUser(c))
 	if err != nil {
 		logger.Log.Info("archive.get_by_id.failed",
 			zap.String("request_id", requestID.(string)),
@@ -137,7 +152,7 @@ func (h *ArchiveHandler) GetArchiveByID(c *gin.Context) {
 			zap.Duration("duration_ms", time.Since(start)),
 		)
 
-		response.Error(c, http.StatusNotFound, "Failed to get data")
+		respondArchiveError(c, err, http.StatusNotFound, "Failed to get data")
 		return
 	}
 
@@ -148,13 +163,26 @@ func (h *ArchiveHandler) GetArchiveByID(c *gin.Context) {
 		zap.Duration("duration_ms", time.Since(start)),
 	)
 
+	// Attachments are served by DownloadArchiveAttachment. Inlining them as
+	// base64 is kept for older clients that ask with ?content=base64.
+	inlineContent := c.Query("content") == "base64"
 	for _, archiveAttachment := range archive.ArchiveAttachments {
-		if archiveAttachment.FileLocation == "" {
+		setAttachmentURLs(h.archiveService, archiveAttachment)
+
+		if !inlineContent || archiveAttachment.FileLocation == "" {
 			continue
 		}
 
-		fileBytes, err := os.ReadFile(archiveAttachment.FileLocation)
+		fileBytes, err := readAttachment(c, h.archiveService, archiveAttachment)
 		if err != nil {
+			logger.Log.Warn("archive.get_by_id.attachment_unreadable",
+				zap.String("request_id", requestID.(string)),
+				zap.Uint("archive_id", uint(id)),
+				zap.Uint("attachment_id", archiveAttachment.ID),
+				zap.String("file_location", archiveAttachment.FileLocation),
+				zap.Error(err),
+			)
+
 			archiveAttachment.FileBase64 = ""
 			continue
 		}
@@ -162,16 +190,9 @@ func (h *ArchiveHandler) GetArchiveByID(c *gin.Context) {
 		// Encode to Base64
 		base64Data := base64.StdEncoding.EncodeToString(fileBytes)
 
-		ext := strings.ToLower(filepath.Ext(archiveAttachment.FileName))
-
-		switch ext {
-		case ".jpg", ".jpeg", ".png", ".gif", ".webp":
-			archiveAttachment.FileBase64 = "data:image/" + strings.TrimPrefix(ext, ".") + ";base64," + base64Data
-
-		case ".pdf":
-			archiveAttachment.FileBase64 = "data:application/pdf;base64," + base64Data
-
-		default:
+		if archiveAttachment.MimeType != "" {
+			archiveAttachment.FileBase64 = "data:" + archiveAttachment.MimeType + ";base64," + base64Data
+		} else {
 			archiveAttachment.FileBase64 = base64Data
 		}
 	}
@@ -201,30 +222,21 @@ func (h *ArchiveHandler) CreateArchive(c *gin.Context) {
 		return
 	}
 
-	newArchive := model.ArchiveHdr{
-		ArchiveDate:             newArchiveRequest.ArchiveDate,
-		ArchiveNumber:           newArchiveRequest.ArchiveNumber,
-		ArchiveName:             newArchiveRequest.ArchiveName,
-		ArchiveCharacteristicID: newArchiveRequest.ArchiveCharacteristicID,
-		ArchiveTypeID:           newArchiveRequest.ArchiveTypeID,
-		DepartmentID:            newArchiveRequest.DepartmentID,
-		Status:                  "Y",
-		CreatedBy:               newArchiveRequest.SubmittedBy,
-	}
-
-	if err := h.archiveService.CreateArchive(&newArchive); err != nil {
-		logger.Log.Error("archive.create.create_archive_hdr.failed",
+	newArchive, err := h.archiveService.CreateArchive(&newArchiveRequest, currentUser(c))
+	if err != nil {
+		logger.Log.Error("archive.create.failed",
 			zap.String("request_id", requestID.(string)),
-			zap.Any("payload", newArchive),
+			zap.Int("attachments", len(newArchiveRequest.ListArchiveAttachments)),
+			zap.Int("role_access", len(newArchiveRequest.RoleAccess)),
 			zap.Error(err),
 			zap.Duration("duration_ms", time.Since(start)),
 		)
 
-		response.Error(c, http.StatusInternalServerError, "Failed to create archive hdr")
+		respondArchiveError(c, err, http.StatusInternalServerError, "Failed to create archive")
 		return
 	}
 
-	logger.Log.Info("archive.create.archive_hdr.success",
+	logger.Log.Info("archive.create.success",
 		zap.String("request_id", requestID.(string)),
 		zap.Uint("archive_id", newArchive.ID),
 		zap.Int("attachments", len(newArchiveRequest.ListArchiveAttachments)),
@@ -232,419 +244,416 @@ func (h *ArchiveHandler) CreateArchive(c *gin.Context) {
 		zap.Duration("duration_ms", time.Since(start)),
 	)
 
-	for _, roleAccess := range newArchiveRequest.RoleAccess {
-		newArchiveRoleAccess := model.ArchiveRoleAccess{
-			ArchiveHdrID: newArchive.ID,
-			RoleID:       roleAccess.RoleID,
-			DepartmentID: roleAccess.DepartmentID,
-			Status:       "Y",
-			CreatedBy:    newArchiveRequest.SubmittedBy,
-		}
+	for _, archiveAttachment := range newArchive.ArchiveAttachments {
+		setAttachmentURLs(h.archiveService, archiveAttachment)
+	}
 
-		if err := h.archiveRoleAccessService.CreateArchiveRoleAccess(&newArchiveRoleAccess); err != nil {
-			logger.Log.Error("archive.create.create_archive_role_access.failed",
-				zap.String("request_id", requestID.(string)),
-				zap.Any("payload", roleAccess),
-				zap.Error(err),
-				zap.Duration("duration_ms", time.Since(start)),
-			)
+	response.Created(c, newArchive)
+}
 
-			response.Error(c, http.StatusInternalServerError, "Failed to create archive role access")
-			return
-		}
+func (h *ArchiveHandler) UpdateArchiveById(c *gin.Context) {
+	start := time.Now()
+	requestID, _ := c.Get("request_id")
+
+	var updateArchiveRequest archiveRequest.UpdateArchiveRequest
+	if err := c.ShouldBindJSON(&updateArchiveRequest); err != nil {
+		logger.Log.Warn("archive.update.invalid_request",
+			zap.String("request_id", requestID.(string)),
+			zap.Error(err),
+		)
+
+		response.Error(c, http.StatusBadRequest, "JSON Request is not valid")
+		return
 	}
 
-	for _, archiveAttachment := range newArchiveRequest.ListArchiveAttachments {
-		if archiveAttachment.IsNew {
-			base64Data := archiveAttachment.FileBase64
+	archive, err := h.archiveService.UpdateArchive(&updateArchiveRequest, currentUser(c))
+	if err != nil {
+		logger.Log.Error("archive.update.failed",
+			zap.String("request_id", requestID.(string)),
+			zap.Uint("archive_id", updateArchiveRequest.ID),
+			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
 
-			if strings.Contains(base64Data, ",") {
-				parts := strings.SplitN(base64Data, ",", 2)
-				base64Data = parts[1]
-			}
+		respondArchiveError(c, err, http.StatusInternalServerError, "Failed to update data")
+		return
+	}
 
-			fileExt := DetectBase64Extension(archiveAttachment.FileBase64)
-			if fileExt == "" {
-				logger.Log.Error("archive.create.detect_base64_extension.failed",
-					zap.String("request_id", requestID.(string)),
-					zap.Duration("duration_ms", time.Since(start)),
-				)
+	logger.Log.Info("archive.update.success",
+		zap.String("request_id", requestID.(string)),
+		zap.Uint("archive_id", archive.ID),
+		zap.Duration("duration_ms", time.Since(start)),
+	)
 
-				response.Error(c, http.StatusBadRequest, "File extension not found")
-				return
-			}
+	for _, archiveAttachment := range archive.ArchiveAttachments {
+		setAttachmentURLs(h.archiveService, archiveAttachment)
+	}
 
-			if !isAllowedFileType(fileExt) {
-				logger.Log.Error("archive.create.file_extension_not_allowed.failed",
-					zap.String("request_id", requestID.(string)),
-					zap.Duration("duration_ms", time.Since(start)),
-				)
+	response.Success(c, archive)
+}
 
-				response.Error(c, http.StatusBadRequest, "File extension not allowed")
-				return
-			}
+// UploadArchiveAttachment accepts a multipart/form-data request with a single
+// "file" part and streams it to storage without buffering it in memory.
+func (h *ArchiveHandler) UploadArchiveAttachment(c *gin.Context) {
+	start := time.Now()
+	requestID, _ := c.Get("request_id")
 
-			decodedBytes, err := base64.StdEncoding.DecodeString(base64Data)
-			if err != nil {
-				logger.Log.Error("archive.create.base64_invalid.failed",
-					zap.String("request_id", requestID.(string)),
-					zap.Duration("duration_ms", time.Since(start)),
-				)
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.Log.Warn("archive.upload_attachment.invalid_id",
+			zap.String("request_id", requestID.(string)),
+			zap.String("param", c.Param("id")),
+			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
 
-				response.Error(c, http.StatusBadRequest, "Invalid base64 file data")
-				return
-			}
+		response.Error(c, http.StatusBadRequest, "Invalid ID")
+		return
+	}
 
-			storageLocation, err := utils.GetStorageLocation()
-			if err != nil {
-				logger.Log.Error("archive.create.get_storage_location.failed",
-					zap.String("request_id", requestID.(string)),
-					zap.Error(err),
-					zap.Duration("duration_ms", time.Since(start)),
-				)
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		logger.Log.Warn("archive.upload_attachment.invalid_request",
+			zap.String("request_id", requestID.(string)),
+			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
 
-				response.Error(c, http.StatusInternalServerError, "Failed to get storage location directory")
-				return
-			}
+		response.Error(c, http.StatusBadRequest, "Multipart request is not valid")
+		return
+	}
 
-			uploadDir := filepath.Join(storageLocation, "uploads", "archives", strconv.Itoa(int(newArchive.ID)))
-			if err := os.MkdirAll(uploadDir, 0755); err != nil {
-				logger.Log.Error("archive.create.create_upload_directory.failed",
-					zap.String("request_id", requestID.(string)),
-					zap.Error(err),
-					zap.Duration("duration_ms", time.Since(start)),
-				)
+	var filePart *multipart.Part
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			logger.Log.Warn("archive.upload_attachment.read_part.failed",
+				zap.String("request_id", requestID.(string)),
+				zap.Error(err),
+				zap.Duration("duration_ms", time.Since(start)),
+			)
 
-				response.Error(c, http.StatusInternalServerError, "Failed to create upload directory")
-				return
-			}
+			response.Error(c, http.StatusBadRequest, "Multipart request is not valid")
+			return
+		}
 
-			fileName := fmt.Sprintf("%d_%d.%s", newArchive.ID, time.Now().UnixNano(), fileExt)
-			fileLocation := filepath.Join(uploadDir, fileName)
-			if err := os.WriteFile(fileLocation, decodedBytes, 0644); err != nil {
-				logger.Log.Error("archive.create.write_file.failed",
-					zap.String("request_id", requestID.(string)),
-					zap.Error(err),
-					zap.Duration("duration_ms", time.Since(start)),
-				)
+		if part.FormName() == "file" && part.FileName() != "" {
+			filePart = part
+			break
+		}
+	}
 
-				response.Error(c, http.StatusInternalServerError, "Failed to write file")
-				return
-			}
+	if filePart == nil {
+		logger.Log.Warn("archive.upload_attachment.file_not_found",
+			zap.String("request_id", requestID.(string)),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
 
-			newArchiveAttachment := model.ArchiveAttachment{
-				ArchiveHdrID: newArchive.ID,
-				FileName:     fileName,
-				FileLocation: fileLocation,
-				Status:       "Y",
-				CreatedBy:    newArchiveRequest.SubmittedBy,
-			}
+		response.Error(c, http.StatusBadRequest, "File not found in request")
+		return
+	}
+	defer filePart.Close()
 
-			if err := h.archiveAttachmentService.CreateArchiveAttachment(&newArchiveAttachment); err != nil {
-				logger.Log.Error("archive.create.create_archive_attachment_data.failed",
-					zap.String("request_id", requestID.(string)),
-					zap.Error(err),
-					zap.Duration("duration_ms", time.Since(start)),
-				)
+	archiveAttachment, err := h.archiveService.AddAttachment(uint(id), filePart.FileName(), filePart, currentUser(c))
+	if err != nil {
+		logger.Log.Error("archive.upload_attachment.failed",
+			zap.String("request_id", requestID.(string)),
+			zap.Uint("archive_id", uint(id)),
+			zap.String("file_name", filePart.FileName()),
+			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
 
-				response.Error(c, http.StatusInternalServerError, "Failed to save archive attachment")
-				return
-			}
-		}
+		respondArchiveError(c, err, http.StatusInternalServerError, "Failed to upload attachment")
+		return
 	}
 
-	logger.Log.Info("archive.create.success",
+	logger.Log.Info("archive.upload_attachment.success",
 		zap.String("request_id", requestID.(string)),
+		zap.Uint("archive_id", uint(id)),
+		zap.Uint("attachment_id", archiveAttachment.ID),
 		zap.Duration("duration_ms", time.Since(start)),
 	)
 
-	c.Status(http.StatusCreated)
+	setAttachmentURLs(h.archiveService, archiveAttachment)
+	response.Created(c, archiveAttachment)
 }
 
-func (h *ArchiveHandler) UpdateArchiveById(c *gin.Context) {
+// ReorderArchiveAttachments sets the order, and optionally the rotation, in
+// which the archive's attachments are merged into its PDF.
+func (h *ArchiveHandler) ReorderArchiveAttachments(c *gin.Context) {
 	start := time.Now()
 	requestID, _ := c.Get("request_id")
 
-	var updateArchiveRequest archiveRequest.UpdateArchiveRequest
-	if err := c.ShouldBindJSON(&updateArchiveRequest); err != nil {
-		logger.Log.Warn("archive.update.invalid_request",
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.Log.Warn("archive.reorder_attachments.invalid_id",
 			zap.String("request_id", requestID.(string)),
+			zap.String("param", c.Param("id")),
 			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
 		)
 
-		response.Error(c, http.StatusBadRequest, "JSON Request is not valid")
+		response.Error(c, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	var reorderRequest attachmentRequest.ReorderArchiveAttachmentsRequest
+	if err := c.ShouldBindJSON(&reorderRequest); err != nil {
+		logger.Log.Warn("archive.reorder_attachments.invalid_request",
+			zap.String("request_id", requestID.(string)),
+			zap.Error(err),
+		)
+
+		response.Error(c, http.StatusBadRequest, "JSON request is not valid")
 		return
 	}
 
-	archive, err := h.archiveService.GetArchiveByID(updateArchiveRequest.ID)
+	archive, err := h.archiveService.ReorderAttachments(uint(id), &reorderRequest, currentUser(c))
 	if err != nil {
-		logger.Log.Error("archive.update.get_by_id.failed",
+		logger.Log.Error("archive.reorder_attachments.failed",
 			zap.String("request_id", requestID.(string)),
-			zap.Any("payload", updateArchiveRequest.ID),
+			zap.Uint("archive_id", uint(id)),
 			zap.Error(err),
 			zap.Duration("duration_ms", time.Since(start)),
 		)
 
-		response.Error(c, http.StatusNotFound, "Failed to get data")
+		respondArchiveError(c, err, http.StatusInternalServerError, "Failed to reorder attachments")
 		return
 	}
 
-	timeNow := time.Now()
+	logger.Log.Info("archive.reorder_attachments.success",
+		zap.String("request_id", requestID.(string)),
+		zap.Uint("archive_id", archive.ID),
+		zap.Int("count", len(reorderRequest.Attachments)),
+		zap.Duration("duration_ms", time.Since(start)),
+	)
 
-	archive.ArchiveDate = updateArchiveRequest.ArchiveDate
-	archive.ArchiveNumber = updateArchiveRequest.ArchiveNumber
-	archive.ArchiveName = updateArchiveRequest.ArchiveName
-	archive.ArchiveCharacteristicID = updateArchiveRequest.ArchiveCharacteristicID
-	archive.ArchiveTypeID = updateArchiveRequest.ArchiveTypeID
-	archive.ModifiedBy = &updateArchiveRequest.SubmittedBy
-	archive.ModifiedAt = &timeNow
+	for _, archiveAttachment := range archive.ArchiveAttachments {
+		setAttachmentURLs(h.archiveService, archiveAttachment)
+	}
+
+	response.Success(c, archive)
+}
 
-	if err := h.archiveService.UpdateArchive(archive); err != nil {
-		logger.Log.Error("archive.update.save.failed",
+// DownloadArchiveAttachment streams one attachment. Range requests, ETag
+// revalidation and If-Range are handled by http.ServeContent; the ETag is the
+// file's SHA-256 when it is known. Add ?download=1 to get a save dialog
+// instead of inline display.
+func (h *ArchiveHandler) DownloadArchiveAttachment(c *gin.Context) {
+	start := time.Now()
+	requestID, _ := c.Get("request_id")
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.Log.Warn("archive.download_attachment.invalid_id",
 			zap.String("request_id", requestID.(string)),
-			zap.Any("payload", updateArchiveRequest),
+			zap.String("param", c.Param("id")),
 			zap.Error(err),
 			zap.Duration("duration_ms", time.Since(start)),
 		)
 
-		response.Error(c, http.StatusInternalServerError, "Failed to update data")
+		response.Error(c, http.StatusBadRequest, "Invalid ID")
 		return
 	}
 
-	for _, roleAccess := range updateArchiveRequest.RoleAccess {
-		if roleAccess.IsNew {
-			newArchiveRoleAccess := model.ArchiveRoleAccess{
-				ArchiveHdrID: updateArchiveRequest.ID,
-				RoleID:       roleAccess.RoleID,
-				DepartmentID: roleAccess.DepartmentID,
-				Status:       "Y",
-				CreatedBy:    updateArchiveRequest.SubmittedBy,
-			}
+	attachmentID, err := strconv.Atoi(c.Param("attId"))
+	if err != nil {
+		logger.Log.Warn("archive.download_attachment.invalid_attachment_id",
+			zap.String("request_id", requestID.(string)),
+			zap.String("param", c.Param("attId")),
+			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
 
-			if err := h.archiveRoleAccessService.CreateArchiveRoleAccess(&newArchiveRoleAccess); err != nil {
-				logger.Log.Error("archive.update.create_role_access.failed",
-					zap.String("request_id", requestID.(string)),
-					zap.Any("payload", newArchiveRoleAccess),
-					zap.Error(err),
-					zap.Duration("duration_ms", time.Since(start)),
-				)
+		response.Error(c, http.StatusBadRequest, "Invalid attachment ID")
+		return
+	}
 
-				response.Error(c, http.StatusInternalServerError, "Failed to create data")
-				return
-			}
-		}
+	archiveAttachment, err := h.archiveService.GetAttachment(uint(id), uint(attachmentID), currentUser(c))
+	if err != nil {
+		logger.Log.Info("archive.download_attachment.failed",
+			zap.String("request_id", requestID.(string)),
+			zap.Uint("archive_id", uint(id)),
+			zap.Uint("attachment_id", uint(attachmentID)),
+			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
 
-		if roleAccess.IsDelete {
-			deleteArchiveRoleAccess := archiveRoleAccessRequest.DeleteArchiveRoleAccessRequest{
-				ID:          roleAccess.ID,
-				SubmittedBy: updateArchiveRequest.SubmittedBy,
-			}
+		respondArchiveError(c, err, http.StatusInternalServerError, "Failed to get data")
+		return
+	}
 
-			if err := h.archiveRoleAccessService.DeleteArchiveRoleAccess(&deleteArchiveRoleAccess); err != nil {
-				logger.Log.Error("archive.update.delete_role_access.failed",
-					zap.String("request_id", requestID.(string)),
-					zap.Any("payload", deleteArchiveRoleAccess),
-					zap.Error(err),
-					zap.Duration("duration_ms", time.Since(start)),
-				)
+	content, info, err := h.archiveService.OpenAttachmentSeeker(c.Request.Context(), archiveAttachment)
+	if err != nil {
+		logger.Log.Error("archive.download_attachment.open.failed",
+			zap.String("request_id", requestID.(string)),
+			zap.Uint("archive_id", uint(id)),
+			zap.Uint("attachment_id", archiveAttachment.ID),
+			zap.String("file_location", archiveAttachment.FileLocation),
+			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
 
-				response.Error(c, http.StatusInternalServerError, "Failed to delete data")
-				return
-			}
+		if errors.Is(err, storage.ErrNotFound) {
+			response.Error(c, http.StatusNotFound, "Attachment file not found")
+			return
 		}
-	}
 
-	for _, archiveAttachment := range updateArchiveRequest.ListArchiveAttachments {
-		if archiveAttachment.IsNew {
-			base64Data := archiveAttachment.FileBase64
+		response.Error(c, http.StatusInternalServerError, "Failed to open file")
+		return
+	}
+	defer content.Close()
 
-			if strings.Contains(base64Data, ",") {
-				parts := strings.SplitN(base64Data, ",", 2)
-				base64Data = parts[1]
-			}
+	contentType := archiveAttachment.MimeType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
 
-			fileExt := DetectBase64Extension(archiveAttachment.FileBase64)
-			if fileExt == "" {
-				logger.Log.Error("archive.update.detect_base64_ext.failed",
-					zap.String("request_id", requestID.(string)),
-					zap.Duration("duration_ms", time.Since(start)),
-				)
+	disposition := "inline"
+	if c.Query("download") == "1" {
+		disposition = "attachment"
+	}
 
-				response.Error(c, http.StatusBadRequest, "File extension not found")
-				return
-			}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": archiveAttachment.FileName}))
+	c.Header("X-Content-Type-Options", "nosniff")
+	// Access is checked per request, so shared caches must not keep a copy.
+	c.Header("Cache-Control", "private, no-cache")
+	if archiveAttachment.FileHash != "" {
+		c.Header("ETag", `"`+archiveAttachment.FileHash+`"`)
+	}
 
-			if !isAllowedFileType(fileExt) {
-				logger.Log.Error("archive.update.file_extension.failed",
-					zap.String("request_id", requestID.(string)),
-					zap.Duration("duration_ms", time.Since(start)),
-				)
+	http.ServeContent(c.Writer, c.Request, archiveAttachment.FileName, info.ModTime, content)
 
-				response.Error(c, http.StatusBadRequest, "File extension not allowed")
-				return
-			}
-
-			decodedBytes, err := base64.StdEncoding.DecodeString(base64Data)
-			if err != nil {
-				logger.Log.Error("archive.update.base64_conversion.failed",
-					zap.String("request_id", requestID.(string)),
-					zap.Error(err),
-					zap.Duration("duration_ms", time.Since(start)),
-				)
+	logger.Log.Info("archive.download_attachment.success",
+		zap.String("request_id", requestID.(string)),
+		zap.Uint("archive_id", uint(id)),
+		zap.Uint("attachment_id", archiveAttachment.ID),
+		zap.Int("status", c.Writer.Status()),
+		zap.Int("bytes", c.Writer.Size()),
+		zap.Duration("duration_ms", time.Since(start)),
+	)
+}
 
-				response.Error(c, http.StatusBadRequest, "Invalid base64 file data")
-				return
-			}
+// GetArchiveAttachmentThumbnail serves a small JPEG preview of an image or of
+// the first page of a PDF. A thumbnail that has not been rendered yet is
+// rendered before responding.
+func (h *ArchiveHandler) GetArchiveAttachmentThumbnail(c *gin.Context) {
+	start := time.Now()
+	requestID, _ := c.Get("request_id")
 
-			storageLocation, err := utils.GetStorageLocation()
-			if err != nil {
-				logger.Log.Error("archive.update.get_storage_location.failed",
-					zap.String("request_id", requestID.(string)),
-					zap.Error(err),
-					zap.Duration("duration_ms", time.Since(start)),
-				)
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.Log.Warn("archive.attachment_thumbnail.invalid_id",
+			zap.String("request_id", requestID.(string)),
+			zap.String("param", c.Param("id")),
+			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
 
-				response.Error(c, http.StatusInternalServerError, "Failed to get storage location directory")
-				return
-			}
+		response.Error(c, http.StatusBadRequest, "Invalid ID")
+		return
+	}
 
-			uploadDir := filepath.Join(storageLocation, "uploads", "archives", strconv.Itoa(int(updateArchiveRequest.ID)))
-			if err := os.MkdirAll(uploadDir, 0755); err != nil {
-				logger.Log.Error("archive.update.create_upload_directory.failed",
-					zap.String("request_id", requestID.(string)),
-					zap.Error(err),
-					zap.Duration("duration_ms", time.Since(start)),
-				)
+	attachmentID, err := strconv.Atoi(c.Param("attId"))
+	if err != nil {
+		logger.Log.Warn("archive.attachment_thumbnail.invalid_attachment_id",
+			zap.String("request_id", requestID.(string)),
+			zap.String("param", c.Param("attId")),
+			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
 
-				response.Error(c, http.StatusInternalServerError, "Failed to create upload directory")
-				return
-			}
+		response.Error(c, http.StatusBadRequest, "Invalid attachment ID")
+		return
+	}
 
-			fileName := fmt.Sprintf("%d_%d.%s", updateArchiveRequest.ID, time.Now().UnixNano(), fileExt)
-			fileLocation := filepath.Join(uploadDir, fileName)
-			if err := os.WriteFile(fileLocation, decodedBytes, 0644); err != nil {
-				logger.Log.Error("archive.update.write_file.failed",
-					zap.String("request_id", requestID.(string)),
-					zap.Error(err),
-					zap.Duration("duration_ms", time.Since(start)),
-				)
+	archiveAttachment, err := h.archiveService.GetAttachment(uint(id), uint(attachmentID), currentUser(c))
+	if err != nil {
+		logger.Log.Info("archive.attachment_thumbnail.failed",
+			zap.String("request_id", requestID.(string)),
+			zap.Uint("archive_id", uint(id)),
+			zap.Uint("attachment_id", uint(attachmentID)),
+			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
 
-				response.Error(c, http.StatusInternalServerError, "Failed to write file")
-				return
-			}
+		respondArchiveError(c, err, http.StatusInternalServerError, "Failed to get data")
+		return
+	}
 
-			newArchiveAttachment := model.ArchiveAttachment{
-				ArchiveHdrID: updateArchiveRequest.ID,
-				FileName:     fileName,
-				FileLocation: fileLocation,
-				Status:       "Y",
-				CreatedBy:    updateArchiveRequest.SubmittedBy,
-			}
+	content, info, err := h.archiveService.OpenAttachmentThumbnail(c.Request.Context(), archiveAttachment)
+	if err != nil {
+		logger.Log.Error("archive.attachment_thumbnail.open.failed",
+			zap.String("request_id", requestID.(string)),
+			zap.Uint("archive_id", uint(id)),
+			zap.Uint("attachment_id", archiveAttachment.ID),
+			zap.String("file_location", archiveAttachment.FileLocation),
+			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
 
-			if err := h.archiveAttachmentService.CreateArchiveAttachment(&newArchiveAttachment); err != nil {
-				logger.Log.Error("archive.update.create_archive_attachment.failed",
-					zap.String("request_id", requestID.(string)),
-					zap.Error(err),
-					zap.Any("payload", &newArchiveAttachment),
-					zap.Duration("duration_ms", time.Since(start)),
-				)
+		if errors.Is(err, storage.ErrNotFound) {
+			response.Error(c, http.StatusNotFound, "Attachment file not found")
+			return
+		}
 
-				response.Error(c, http.StatusInternalServerError, "Failed to create data")
-				return
-			}
+		respondArchiveError(c, err, http.StatusInternalServerError, "Failed to render thumbnail")
+		return
+	}
+	defer content.Close()
 
-			cacheFilePath := filepath.Join(storageLocation, "cache", "archives", strconv.Itoa(int(updateArchiveRequest.ID)), fmt.Sprintf("archive_%d.pdf", updateArchiveRequest.ID))
-			if err := os.Remove(cacheFilePath); err == nil {
-				logger.Log.Info("archive.update.create_process.delete_cached_data.success",
-					zap.String("request_id", requestID.(string)),
-					zap.Any("payload", &cacheFilePath),
-					zap.Duration("duration_ms", time.Since(start)),
-				)
-			} else if os.IsNotExist(err) {
-				logger.Log.Info("archive.update.create_process.delete_cached_data.not_found",
-					zap.String("request_id", requestID.(string)),
-					zap.Any("payload", &cacheFilePath),
-					zap.Duration("duration_ms", time.Since(start)),
-				)
-			} else {
-				logger.Log.Error("archive.update.create_process.delete_cached_data.failed",
-					zap.String("request_id", requestID.(string)),
-					zap.Error(err),
-					zap.Any("payload", &cacheFilePath),
-					zap.Duration("duration_ms", time.Since(start)),
-				)
-			}
-		}
+	c.Header("Content-Type", "image/jpeg")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Cache-Control", "private, no-cache")
+	if archiveAttachment.FileHash != "" {
+		c.Header("ETag", `"`+archiveAttachment.FileHash+`-thumbnail"`)
+	}
 
-		if archiveAttachment.IsDelete {
-			archiveAttachment, err := h.archiveAttachmentService.GetArchiveAttachmentByID(archiveAttachment.ID)
-			if err != nil {
-				logger.Log.Error("archive.update.get_archive_attachment_by_id.failed",
-					zap.String("request_id", requestID.(string)),
-					zap.Error(err),
-					zap.Any("payload", &archiveAttachment),
-					zap.Duration("duration_ms", time.Since(start)),
-				)
-
-				response.Error(c, http.StatusNotFound, "Failed to get data")
-				return
-			}
+	http.ServeContent(c.Writer, c.Request, "", info.ModTime, content)
 
-			archiveAttachment.Status = "N"
-			if err := h.archiveAttachmentService.UpdateArchiveAttachment(archiveAttachment); err != nil {
-				logger.Log.Error("archive.update.update_archive_attachment.failed",
-					zap.String("request_id", requestID.(string)),
-					zap.Error(err),
-					zap.Any("payload", archiveAttachment),
-					zap.Duration("duration_ms", time.Since(start)),
-				)
+	logger.Log.Info("archive.attachment_thumbnail.success",
+		zap.String("request_id", requestID.(string)),
+		zap.Uint("archive_id", uint(id)),
+		zap.Uint("attachment_id", archiveAttachment.ID),
+		zap.Int("status", c.Writer.Status()),
+		zap.Duration("duration_ms", time.Since(start)),
+	)
+}
 
-				response.Error(c, http.StatusInternalServerError, "Failed to update data")
-				return
-			}
+// FindAttachmentDuplicates lets clients check a file's SHA-256 before
+// uploading it and warn when it is already attached to another archive.
+func (h *ArchiveHandler) FindAttachmentDuplicates(c *gin.Context) {
+	start := time.Now()
+	requestID, _ := c.Get("request_id")
 
-			storageLocation, err := utils.GetStorageLocation()
-			if err != nil {
-				logger.Log.Error("archive.update.delete_process.get_storage_location.failed",
-					zap.String("request_id", requestID.(string)),
-					zap.Error(err),
-					zap.Duration("duration_ms", time.Since(start)),
-				)
+	hash := c.Param("hash")
 
-				response.Error(c, http.StatusInternalServerError, "Failed to get storage location directory")
-				return
-			}
+	duplicates, err := h.archiveService.FindDuplicates(hash, currentUser(c))
+	if err != nil {
+		logger.Log.Error("archive.find_attachment_duplicates.failed",
+			zap.String("request_id", requestID.(string)),
+			zap.String("hash", hash),
+			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
 
-			cacheFilePath := filepath.Join(storageLocation, "cache", "archives", strconv.Itoa(int(updateArchiveRequest.ID)), fmt.Sprintf("archive_%d.pdf", updateArchiveRequest.ID))
-			if err := os.Remove(cacheFilePath); err == nil {
-				logger.Log.Info("archive.update.delete_process.delete_cached_data.success",
-					zap.String("request_id", requestID.(string)),
-					zap.Any("payload", &cacheFilePath),
-					zap.Duration("duration_ms", time.Since(start)),
-				)
-			} else if os.IsNotExist(err) {
-				logger.Log.Info("archive.update.delete_process.delete_cached_data.not_found",
-					zap.String("request_id", requestID.(string)),
-					zap.Any("payload", &cacheFilePath),
-					zap.Duration("duration_ms", time.Since(start)),
-				)
-			} else {
-				logger.Log.Error("archive.update.delete_process.delete_cached_data.failed",
-					zap.String("request_id", requestID.(string)),
-					zap.Error(err),
-					zap.Any("payload", &cacheFilePath),
-					zap.Duration("duration_ms", time.Since(start)),
-				)
-			}
-		}
+		respondArchiveError(c, err, http.StatusInternalServerError, "Failed to get data")
+		return
 	}
 
-	logger.Log.Info("archive.update.success",
+	logger.Log.Info("archive.find_attachment_duplicates.success",
 		zap.String("request_id", requestID.(string)),
+		zap.String("hash", hash),
+		zap.Int("count", len(duplicates)),
 		zap.Duration("duration_ms", time.Since(start)),
 	)
 
-	response.Success(c, archive)
+	response.Success(c, duplicates)
 }
 
 func (h *ArchiveHandler) DeleteArchiveById(c *gin.Context) {
@@ -662,39 +671,17 @@ func (h *ArchiveHandler) DeleteArchiveById(c *gin.Context) {
 		return
 	}
 
-	if err := h.archiveService.DeleteArchive(&deleteArchiveRequest); err != nil {
-		logger.Log.Error("archive.delete.archive.failed",
-			zap.String("request_id", requestID.(string)),
-			zap.Error(err),
-			zap.Any("payload", deleteArchiveRequest),
-			zap.Duration("duration_ms", time.Since(start)),
-		)
-
-		response.Error(c, http.StatusInternalServerError, "Failed to delete data")
-		return
-	}
-
-	if err := h.archiveAttachmentService.DeleteArchiveAttachmentByArchiveID(deleteArchiveRequest.ID, deleteArchiveRequest.SubmittedBy); err != nil {
-		logger.Log.Error("archive.delete.archive_attachment.failed",
-			zap.String("request_id", requestID.(string)),
-			zap.Error(err),
-			zap.Any("payload", deleteArchiveRequest),
-			zap.Duration("duration_ms", time.Since(start)),
-		)
-
-		response.Error(c, http.StatusInternalServerError, "Failed to delete data")
-		return
-	}
+	deleteArchiveRequest.SubmittedBy = currentUser(c).UserId
 
-	if err := h.archiveRoleAccessService.DeleteArchiveRoleAccessByArchiveID(deleteArchiveRequest.ID, deleteArchiveRequest.SubmittedBy); err != nil {
-		logger.Log.Error("archive.delete.archive_role_access.failed",
+	if err := h.archiveService.DeleteArchive(&deleteArchiveRequest, currentUser(c)); err != nil {
+		logger.Log.Error("archive.delete.failed",
 			zap.String("request_id", requestID.(string)),
 			zap.Error(err),
 			zap.Any("payload", deleteArchiveRequest),
 			zap.Duration("duration_ms", time.Since(start)),
 		)
 
-		response.Error(c, http.StatusInternalServerError, "Failed to delete data")
+		respondArchiveError(c, err, http.StatusInternalServerError, "Failed to delete data")
 		return
 	}
 
@@ -710,7 +697,7 @@ func (h *ArchiveHandler) FindArchiveByQuery(c *gin.Context) {
 	start := time.Now()
 	requestID, _ := c.Get("request_id")
 
-	query := c.Param("query")
+	query := strings.TrimSpace(c.Param("query"))
 	if query == "" {
 		logger.Log.Warn("archive.find_by_query.invalid_request",
 			zap.String("request_id", requestID.(string)),
@@ -721,7 +708,7 @@ func (h *ArchiveHandler) FindArchiveByQuery(c *gin.Context) {
 		return
 	}
 
-	archives, err := h.archiveService.FindArchiveByQuery(query)
+	archives, err := h.archiveService.FindArchiveByQuery(query, currentUser(c))
 	if err != nil {
 		logger.Log.Error("archive.find_by_query.failed",
 			zap.String("request_id", requestID.(string)),
@@ -757,7 +744,7 @@ func (h *ArchiveHandler) FindArchiveByAdvanceQuery(c *gin.Context) {
 		return
 	}
 
-	archives, err := h.archiveService.FindArchiveByAdvanceQuery(advancedSearchRequest)
+	archives, err := h.archiveService.FindArchiveByAdvanceQuery(advancedSearchRequest, currentUser(c))
 	if err != nil {
 		logger.Log.Error("archive.find_by_advance_query.failed",
 			zap.String("request_id", requestID.(string)),
@@ -766,7 +753,7 @@ func (h *ArchiveHandler) FindArchiveByAdvanceQuery(c *gin.Context) {
 			zap.Duration("duration_ms", time.Since(start)),
 		)
 
-		response.Error(c, http.StatusNotFound, "Failed to get data")
+		respondArchiveError(c, err, http.StatusNotFound, "Failed to get data")
 		return
 	}
 
@@ -778,152 +765,130 @@ func (h *ArchiveHandler) FindArchiveByAdvanceQuery(c *gin.Context) {
 	response.Success(c, archives)
 }
 
-func (h *ArchiveHandler) StreamMergedPDF(c *gin.Context) {
+// GetArchiveFacets counts the archives matching an advanced search by type,
+// characteristic, department and year. The body is the same as for
+// FindArchiveByAdvanceQuery; an empty body counts every accessible archive.
+func (h *ArchiveHandler) GetArchiveFacets(c *gin.Context) {
 	start := time.Now()
 	requestID, _ := c.Get("request_id")
 
-	id, err := strconv.Atoi(c.Param("id"))
+	var advancedSearchRequest archiveRequest.AdvancedSearchRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&advancedSearchRequest); err != nil {
+			logger.Log.Warn("archive.facets.invalid_request",
+				zap.String("request_id", requestID.(string)),
+				zap.Error(err),
+			)
+
+			response.Error(c, http.StatusBadRequest, "JSON request is not valid")
+			return
+		}
+	}
+
+	facets, err := h.archiveService.GetArchiveFacets(advancedSearchRequest, currentUser(c))
 	if err != nil {
-		logger.Log.Warn("archive.stream.invalid_id",
+		logger.Log.Error("archive.facets.failed",
 			zap.String("request_id", requestID.(string)),
-			zap.String("param", c.Param("id")),
 			zap.Error(err),
+			zap.Any("payload", advancedSearchRequest),
 			zap.Duration("duration_ms", time.Since(start)),
 		)
 
-		response.Error(c, http.StatusBadRequest, "Invalid ID")
+		respondArchiveError(c, err, http.StatusInternalServerError, "Failed to get data")
 		return
 	}
 
-	archive, err := h.archiveService.GetArchiveByID(uint(id))
+	logger.Log.Info("archive.facets.success",
+		zap.String("request_id", requestID.(string)),
+		zap.Int64("total", facets.Total),
+		zap.Duration("duration_ms", time.Since(start)),
+	)
+
+	response.Success(c, facets)
+}
+
+// SearchArchiveText searches the text extracted from attachments. q accepts
+// quoted phrases, "or" and "-word". Snippets are HTML with matches wrapped
+// in <mark>.
+func (h *ArchiveHandler) SearchArchiveText(c *gin.Context) {
+	start := time.Now()
+	requestID, _ := c.Get("request_id")
+
+	query := c.Query("q")
+
+	results, err := h.archiveService.SearchArchiveText(query, currentUser(c))
 	if err != nil {
-		logger.Log.Error("archive.stream.get_by_id.failed",
+		logger.Log.Error("archive.search_text.failed",
 			zap.String("request_id", requestID.(string)),
-			zap.String("param", c.Param("id")),
+			zap.String("query", query),
 			zap.Error(err),
 			zap.Duration("duration_ms", time.Since(start)),
 		)
 
-		response.Error(c, http.StatusBadRequest, "Failed to get data")
+		respondArchiveError(c, err, http.StatusInternalServerError, "Failed to get data")
 		return
 	}
 
-	if len(archive.ArchiveAttachments) == 0 {
-		logger.Log.Info("archive.stream.get_by_id.no_attachment",
-			zap.String("request_id", requestID.(string)),
-			zap.String("param", c.Param("id")),
-			zap.Duration("duration_ms", time.Since(start)),
-		)
+	logger.Log.Info("archive.search_text.success",
+		zap.String("request_id", requestID.(string)),
+		zap.Int("count", len(results)),
+		zap.Duration("duration_ms", time.Since(start)),
+	)
 
-		response.Error(c, http.StatusBadRequest, "Attachment not found")
-		return
-	}
+	response.Success(c, results)
+}
 
-	storageLocation, err := utils.GetStorageLocation()
+// StreamMergedPDF sends the archive's attachments merged into one PDF. Add
+// ?watermark=1 to stamp every page with the configured watermark and
+// ?cover=1 to start with a page listing the archive's metadata.
+func (h *ArchiveHandler) StreamMergedPDF(c *gin.Context) {
+	start := time.Now()
+	requestID, _ := c.Get("request_id")
+
+	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		logger.Log.Error("archive.stream.get_storage_location.failed",
+		logger.Log.Warn("archive.stream.invalid_id",
 			zap.String("request_id", requestID.(string)),
+			zap.String("param", c.Param("id")),
 			zap.Error(err),
 			zap.Duration("duration_ms", time.Since(start)),
 		)
 
-		response.Error(c, http.StatusInternalServerError, "Failed to get storage location directory")
+		response.Error(c, http.StatusBadRequest, "Invalid ID")
 		return
 	}
 
-	cacheDir := filepath.Join(storageLocation, "cache", "archives", strconv.Itoa(int(id)))
-	if err := os.MkdirAll(cacheDir, 0755); err != nil {
-		logger.Log.Error("archive.stream.create_cache_directory.failed",
+	archive, err := h.archiveService.GetArchiveByID(uint(id), currentUser(c))
+	if err != nil {
+		logger.Log.Error("archive.stream.get_by_id.failed",
 			zap.String("request_id", requestID.(string)),
 			zap.String("param", c.Param("id")),
 			zap.Error(err),
 			zap.Duration("duration_ms", time.Since(start)),
 		)
-	}
 
-	finalPDF := filepath.Join(cacheDir, fmt.Sprintf("archive_%d.pdf", id))
-	if stat, err := os.Stat(finalPDF); err == nil {
-		if time.Since(stat.ModTime()) < CacheTTL {
-			streamFileChunked(c, finalPDF, requestID, start)
-			return
-		}
-	}
-
-	var imageFiles []string
-	var pdfFiles []string
-
-	for _, att := range archive.ArchiveAttachments {
-		if _, err := os.Stat(att.FileLocation); err != nil {
-			continue
-		}
-
-		ext := strings.ToLower(filepath.Ext(att.FileLocation))
-		switch ext {
-		case ".jpg", ".jpeg", ".png", ".webp":
-			imageFiles = append(imageFiles, att.FileLocation)
-		case ".pdf":
-			pdfFiles = append(pdfFiles, att.FileLocation)
-		}
-	}
-
-	var tempImagePDF string
-	if len(imageFiles) > 0 {
-		tempImagePDF = filepath.Join(cacheDir, fmt.Sprintf("images_%d.pdf", id))
-		if err := convertImagesToPDF(imageFiles, tempImagePDF); err != nil {
-			logger.Log.Error("archive.stream.convert_images_to_pdf.failed",
-				zap.String("request_id", requestID.(string)),
-				zap.String("param", c.Param("id")),
-				zap.Error(err),
-				zap.Duration("duration_ms", time.Since(start)),
-			)
-
-			response.Error(c, http.StatusInternalServerError, "Failed to convert images to pdf")
-			return
-		}
-
-		pdfFiles = append([]string{tempImagePDF}, pdfFiles...)
+		respondArchiveError(c, err, http.StatusBadRequest, "Failed to get data")
+		return
 	}
 
-	if len(pdfFiles) == 0 {
-		logger.Log.Warn("archive.stream.pdf_files.not_found",
+	if len(archive.ArchiveAttachments) == 0 {
+		logger.Log.Info("archive.stream.get_by_id.no_attachment",
 			zap.String("request_id", requestID.(string)),
 			zap.String("param", c.Param("id")),
 			zap.Duration("duration_ms", time.Since(start)),
 		)
 
+		response.Error(c, http.StatusBadRequest, "Attachment not found")
 		return
 	}
 
-	for _, f := range pdfFiles {
-		info, err := os.Stat(f)
-		if err != nil {
-			logger.Log.Warn("pdfunite.input.stat_failed",
-				zap.String("file", f),
-				zap.Error(err),
-			)
-			continue
-		}
-
-		logger.Log.Info("pdfunite.input",
-			zap.String("file", f),
-			zap.Int64("size", info.Size()),
-		)
-	}
-
-	for _, input := range pdfFiles {
-		if err := validatePDF(input); err != nil {
-			logger.Log.Error("archive.stream.merge_pdfs.failed",
-				zap.String("request_id", requestID.(string)),
-				zap.String("param", c.Param("id")),
-				zap.Error(err),
-				zap.Duration("duration_ms", time.Since(start)),
-			)
-
-			return
-		}
+	exportOptions := service.PDFExportOptions{
+		Watermark: c.Query("watermark") == "1",
+		CoverPage: c.Query("cover") == "1",
 	}
 
-	if err := mergePDFs(pdfFiles, finalPDF); err != nil {
+	mergedPDF, cleanup, err := h.archiveService.ExportPDF(c.Request.Context(), archive, currentUser(c), exportOptions)
+	if err != nil {
 		logger.Log.Error("archive.stream.merge_pdfs.failed",
 			zap.String("request_id", requestID.(string)),
 			zap.String("param", c.Param("id")),
@@ -931,57 +896,85 @@ func (h *ArchiveHandler) StreamMergedPDF(c *gin.Context) {
 			zap.Duration("duration_ms", time.Since(start)),
 		)
 
+		if errors.Is(err, pdf.ErrNoInputs) {
+			response.Error(c, http.StatusBadRequest, "No attachment can be merged into a PDF")
+			return
+		}
+
 		response.Error(c, http.StatusInternalServerError, "Failed to merge pdfs")
 		return
 	}
 
-	if tempImagePDF != "" {
-		_ = os.Remove(tempImagePDF)
-	}
+	defer cleanup()
 
 	logger.Log.Info("archive.stream.success",
 		zap.String("request_id", requestID.(string)),
+		zap.Bool("watermark", exportOptions.Watermark),
+		zap.Bool("cover", exportOptions.CoverPage),
 		zap.Duration("duration_ms", time.Since(start)),
 	)
 
-	streamFileChunked(c, finalPDF, requestID, start)
+	streamFileChunked(c, mergedPDF, requestID, start)
 }
 
-func DetectBase64Extension(base64Str string) string {
-	header := ""
-
-	if strings.Contains(base64Str, ",") {
-		parts := strings.SplitN(base64Str, ",", 2)
-		header = parts[0]
-	} else {
-		return ""
-	}
-
+// respondArchiveError maps archive service errors to 4xx responses and falls
+// back to the given status for anything else.
+func respondArchiveError(c *gin.Context, err error, status int, message string) {
 	switch {
-	case strings.Contains(header, "image/jpeg"):
-		return "jpg"
-	case strings.Contains(header, "image/png"):
-		return "png"
-	case strings.Contains(header, "application/pdf"):
-		return "pdf"
-	case strings.Contains(header, "image/webp"):
-		return "webp"
+	case errors.Is(err, service.ErrArchiveForbidden):
+		response.Error(c, http.StatusForbidden, "You do not have access to this archive")
+	case errors.Is(err, service.ErrArchiveNotFound):
+		response.Error(c, http.StatusNotFound, "Archive not found")
+	case errors.Is(err, service.ErrAttachmentNotFound):
+		response.Error(c, http.StatusNotFound, "Attachment not found")
+	case errors.Is(err, service.ErrThumbnailUnsupported):
+		response.Error(c, http.StatusNotFound, "Attachment type has no thumbnail")
+	case errors.Is(err, service.ErrAttachmentTypeNotFound):
+		response.Error(c, http.StatusBadRequest, "File extension not found")
+	case errors.Is(err, service.ErrAttachmentTypeNotAllowed):
+		response.Error(c, http.StatusBadRequest, "File extension not allowed")
+	case errors.Is(err, service.ErrAttachmentInvalidData):
+		response.Error(c, http.StatusBadRequest, "Invalid base64 file data")
+	case errors.Is(err, service.ErrAttachmentTypeMismatch):
+		response.Error(c, http.StatusBadRequest, "File content does not match its type")
+	case errors.Is(err, service.ErrAttachmentTooLarge):
+		response.Error(c, http.StatusRequestEntityTooLarge, "File exceeds the maximum upload size")
+	case errors.Is(err, service.ErrAttachmentHashInvalid):
+		response.Error(c, http.StatusBadRequest, "Hash must be a hex encoded sha256")
+	case errors.Is(err, service.ErrAttachmentNotInArchive):
+		response.Error(c, http.StatusBadRequest, "Attachment does not belong to this archive")
+	case errors.Is(err, service.ErrSearchDateRangeInvalid):
+		response.Error(c, http.StatusBadRequest, "archiveDateFrom must not be after archiveDateTo")
+	case errors.Is(err, service.ErrSearchQueryEmpty):
+		response.Error(c, http.StatusBadRequest, "Search query is required")
+	case errors.Is(err, service.ErrAttachmentOrderInvalid):
+		response.Error(c, http.StatusBadRequest, "Order must list every attachment of the archive exactly once")
+	case errors.Is(err, service.ErrRoleAccessNotInArchive):
+		response.Error(c, http.StatusBadRequest, "Role access does not belong to this archive")
 	default:
-		return ""
+		response.Error(c, status, message)
 	}
 }
 
-var allowedExtensions = map[string]bool{
-	"jpg":  true,
-	"jpeg": true,
-	"png":  true,
-	"pdf":  true,
+// setAttachmentURLs fills the paths DownloadArchiveAttachment and
+// GetArchiveAttachmentThumbnail serve the attachment from.
+func setAttachmentURLs(archiveService *service.ArchiveService, archiveAttachment *model.ArchiveAttachment) {
+	archiveAttachment.DownloadURL = fmt.Sprintf("/api/archives/%d/attachments/%d", archiveAttachment.ArchiveHdrID, archiveAttachment.ID)
+
+	if archiveService.HasThumbnail(archiveAttachment) {
+		archiveAttachment.ThumbnailURL = archiveAttachment.DownloadURL + "/thumbnail"
+	}
 }
 
-// Validate file extension
-func isAllowedFileType(ext string) bool {
-	ext = strings.ToLower(ext)
-	return allowedExtensions[ext]
+// readAttachment reads the whole stored file of an attachment.
+func readAttachment(c *gin.Context, archiveService *service.ArchiveService, archiveAttachment *model.ArchiveAttachment) ([]byte, error) {
+	reader, err := archiveService.OpenAttachment(c.Request.Context(), archiveAttachment)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return io.ReadAll(reader)
 }
 
 func streamFileChunked(c *gin.Context, filePath string, requestID any, start time.Time) {
@@ -1026,72 +1019,3 @@ func streamFileChunked(c *gin.Context, filePath string, requestID any, start tim
 		zap.Duration("duration_ms", time.Since(start)),
 	)
 }
-
-func convertImagesToPDF(imageFiles []string, outputPDF string) error {
-	if len(imageFiles) == 0 {
-		return nil
-	}
-
-	args := append(imageFiles, "pdf:", outputPDF)
-	cmd := exec.Command("magick", args...)
-
-	utils.ApplySysProcAttr(cmd)
-
-	out, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("imagemagick error: %v | %s", err, string(out))
-	}
-
-	return nil
-}
-
-func mergePDFs(inputs []string, output string) error {
-	if len(inputs) == 0 {
-		return fmt.Errorf("no PDF files to merge")
-	}
-
-	args := append(inputs, output)
-
-	var cmd *exec.Cmd
-	if runtime.GOOS == "windows" {
-		cmd = exec.Command(`C:\poppler\Library\bin\pdfunite.exe`, args...)
-		utils.ApplySysProcAttr(cmd)
-	} else {
-		cmd = exec.Command("pdfunite", args...)
-	}
-
-	out, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("pdfunite error: %v | %s", err, string(out))
-	}
-
-	return nil
-}
-
-func validatePDF(path string) error {
-	info, err := os.Stat(path)
-	if err != nil {
-		return fmt.Errorf("file not found: %s", path)
-	}
-
-	if info.Size() == 0 {
-		return fmt.Errorf("file is empty: %s", path)
-	}
-
-	f, err := os.Open(path)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	header := make([]byte, 5)
-	if _, err := f.Read(header); err != nil {
-		return err
-	}
-
-	if string(header) != "%PDF-" {
-		return fmt.Errorf("not a valid PDF: %s (header=%q)", path, header)
-	}
-
-	return nil
-}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mugnialby/arsip-backend/internal/listquery"
 	"github.com/mugnialby/arsip-backend/internal/model"
 	request "github.com/mugnialby/arsip-backend/internal/model/dto/request/archiveType"
 	"github.com/mugnialby/arsip-backend/internal/service"
@@ -26,7 +27,19 @@ func (h *ArchiveTypeHandler) GetAllArchiveTypes(c *gin.Context) {
 	start := time.Now()
 	requestID, _ := c.Get("request_id")
 
-	archiveTypes, err := h.service.GetAllArchiveTypes()
+	query, err := listquery.Parse(c.Request.URL.Query())
+	if err != nil {
+		logger.Log.Warn("archive_type.get_all.invalid_query",
+			zap.String("request_id", requestID.(string)),
+			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
+
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	archiveTypes, result, err := h.service.GetAllArchiveTypes(query)
 	if err != nil {
 		logger.Log.Error("archive_type.get_all.failed",
 			zap.String("request_id", requestID.(string)),
@@ -34,17 +47,18 @@ func (h *ArchiveTypeHandler) GetAllArchiveTypes(c *gin.Context) {
 			zap.Duration("duration_ms", time.Since(start)),
 		)
 
-		response.Error(c, http.StatusInternalServerError, "Failed to get data")
+		respondListError(c, err, http.StatusInternalServerError, "Failed to get data")
 		return
 	}
 
 	logger.Log.Info("archive_type.get_all.success",
 		zap.String("request_id", requestID.(string)),
 		zap.Int("count", len(archiveTypes)),
+		zap.Int64("total", result.Total),
 		zap.Duration("duration_ms", time.Since(start)),
 	)
 
-	response.Success(c, archiveTypes)
+	respondList(c, archiveTypes, result)
 }
 
 func (h *ArchiveTypeHandler) GetArchiveTypeByID(c *gin.Context) {
@@ -104,7 +118,7 @@ func (h *ArchiveTypeHandler) CreateArchiveType(c *gin.Context) {
 		ID:              0,
 		ArchiveTypeName: newArchiveTypeRequest.ArchiveTypeName,
 		Status:          "Y",
-		CreatedBy:       newArchiveTypeRequest.SubmittedBy,
+		CreatedBy:       currentUser(c).UserId,
 	}
 
 	if err := h.service.CreateArchiveType(&newArchiveType); err != nil {
@@ -156,9 +170,10 @@ func (h *ArchiveTypeHandler) UpdateArchiveTypeById(c *gin.Context) {
 		return
 	}
 
+	modifiedBy := currentUser(c).UserId
 	timeNow := time.Now()
 	archiveType.ArchiveTypeName = updateArchiveTypeRequest.ArchiveTypeName
-	archiveType.ModifiedBy = &updateArchiveTypeRequest.SubmittedBy
+	archiveType.ModifiedBy = &modifiedBy
 	archiveType.ModifiedAt = &timeNow
 
 	if err := h.service.UpdateArchiveType(archiveType); err != nil {
@@ -196,6 +211,8 @@ func (h *ArchiveTypeHandler) DeleteArchiveTypeById(c *gin.Context) {
 		return
 	}
 
+	deleteArchiveTypeRequest.SubmittedBy = currentUser(c).UserId
+
 	if err := h.service.DeleteArchiveType(&deleteArchiveTypeRequest); err != nil {
 		logger.Log.Error("archive_type.delete.failed",
 			zap.String("request_id", requestID.(string)),
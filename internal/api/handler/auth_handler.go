@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
@@ -13,14 +14,14 @@ import (
 )
 
 type AuthHandler struct {
-	userService *service.UserService
+	authService *service.AuthService
 }
 
 func NewAuthHandler(
-	userService *service.UserService,
+	authService *service.AuthService,
 ) *AuthHandler {
 	return &AuthHandler{
-		userService: userService,
+		authService: authService,
 	}
 }
 
@@ -39,23 +40,113 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.CheckUserLoginRequest(&loginRequest)
+	loginResponse, err := h.authService.Login(&loginRequest)
+	if errors.Is(err, service.ErrInvalidCredentials) {
+		logger.Log.Warn("auth.login.invalid_credentials",
+			zap.String("request_id", requestID.(string)),
+			zap.String("user_id", loginRequest.UserId),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
+
+		response.Error(c, http.StatusNotFound, "User not found")
+		return
+	}
 	if err != nil {
-		logger.Log.Error("auth.check_user_login.failed",
+		logger.Log.Error("auth.login.failed",
 			zap.String("request_id", requestID.(string)),
-			zap.Any("payload", loginRequest),
+			zap.String("user_id", loginRequest.UserId),
 			zap.Error(err),
 			zap.Duration("duration_ms", time.Since(start)),
 		)
 
-		response.Error(c, http.StatusNotFound, "User not found")
+		response.Error(c, http.StatusInternalServerError, "Failed to login")
 		return
 	}
 
 	logger.Log.Info("auth.login.success",
+		zap.String("request_id", requestID.(string)),
+		zap.Uint("user_id", loginResponse.User.ID),
+		zap.Duration("duration_ms", time.Since(start)),
+	)
+
+	response.Success(c, loginResponse)
+}
+
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	start := time.Now()
+	requestID, _ := c.Get("request_id")
+
+	var refreshTokenRequest request.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&refreshTokenRequest); err != nil {
+		logger.Log.Warn("auth.refresh.invalid_request",
+			zap.String("request_id", requestID.(string)),
+			zap.Error(err),
+		)
+
+		response.Error(c, http.StatusBadRequest, "JSON request is not valid")
+		return
+	}
+
+	loginResponse, err := h.authService.Refresh(&refreshTokenRequest)
+	if errors.Is(err, service.ErrInvalidRefreshToken) {
+		logger.Log.Warn("auth.refresh.invalid_token",
+			zap.String("request_id", requestID.(string)),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
+
+		response.Error(c, http.StatusUnauthorized, "Invalid or expired refresh token")
+		return
+	}
+	if err != nil {
+		logger.Log.Error("auth.refresh.failed",
+			zap.String("request_id", requestID.(string)),
+			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
+
+		response.Error(c, http.StatusInternalServerError, "Failed to refresh token")
+		return
+	}
+
+	logger.Log.Info("auth.refresh.success",
+		zap.String("request_id", requestID.(string)),
+		zap.Uint("user_id", loginResponse.User.ID),
+		zap.Duration("duration_ms", time.Since(start)),
+	)
+
+	response.Success(c, loginResponse)
+}
+
+func (h *AuthHandler) Logout(c *gin.Context) {
+	start := time.Now()
+	requestID, _ := c.Get("request_id")
+
+	var refreshTokenRequest request.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&refreshTokenRequest); err != nil {
+		logger.Log.Warn("auth.logout.invalid_request",
+			zap.String("request_id", requestID.(string)),
+			zap.Error(err),
+		)
+
+		response.Error(c, http.StatusBadRequest, "JSON request is not valid")
+		return
+	}
+
+	if err := h.authService.Logout(&refreshTokenRequest); err != nil && !errors.Is(err, service.ErrInvalidRefreshToken) {
+		logger.Log.Error("auth.logout.failed",
+			zap.String("request_id", requestID.(string)),
+			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
+
+		response.Error(c, http.StatusInternalServerError, "Failed to logout")
+		return
+	}
+
+	logger.Log.Info("auth.logout.success",
 		zap.String("request_id", requestID.(string)),
 		zap.Duration("duration_ms", time.Since(start)),
 	)
 
-	response.Success(c, user)
+	c.Status(http.StatusOK)
 }
@@ -0,0 +1,13 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mugnialby/arsip-backend/internal/model"
+)
+
+// currentUser returns the user authenticated by middleware.JWTAuth. Audit
+// columns are always filled from it, never from the request body.
+func currentUser(c *gin.Context) *model.User {
+	user, _ := c.MustGet("user").(*model.User)
+	return user
+}
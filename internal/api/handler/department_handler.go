@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mugnialby/arsip-backend/internal/listquery"
 	"github.com/mugnialby/arsip-backend/internal/model"
 	request "github.com/mugnialby/arsip-backend/internal/model/dto/request/department"
 	"github.com/mugnialby/arsip-backend/internal/service"
@@ -26,7 +27,19 @@ func (h *DepartmentHandler) GetAllDepartments(c *gin.Context) {
 	start := time.Now()
 	requestID, _ := c.Get("request_id")
 
-	departments, err := h.service.GetAllDepartments()
+	query, err := listquery.Parse(c.Request.URL.Query())
+	if err != nil {
+		logger.Log.Warn("department.get_all.invalid_query",
+			zap.String("request_id", requestID.(string)),
+			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
+
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	departments, result, err := h.service.GetAllDepartments(query)
 	if err != nil {
 		logger.Log.Error("department.get_all.failed",
 			zap.String("request_id", requestID.(string)),
@@ -34,17 +47,18 @@ func (h *DepartmentHandler) GetAllDepartments(c *gin.Context) {
 			zap.Duration("duration_ms", time.Since(start)),
 		)
 
-		response.Error(c, http.StatusInternalServerError, "Failed to get data")
+		respondListError(c, err, http.StatusInternalServerError, "Failed to get data")
 		return
 	}
 
 	logger.Log.Info("department.get_all.success",
 		zap.String("request_id", requestID.(string)),
 		zap.Int("count", len(departments)),
+		zap.Int64("total", result.Total),
 		zap.Duration("duration_ms", time.Since(start)),
 	)
 
-	response.Success(c, departments)
+	respondList(c, departments, result)
 }
 
 func (h *DepartmentHandler) GetDepartmentByID(c *gin.Context) {
@@ -104,7 +118,7 @@ func (h *DepartmentHandler) CreateDepartment(c *gin.Context) {
 		ID:             0,
 		DepartmentName: newDepartmentRequest.DepartmentName,
 		Status:         "Y",
-		CreatedBy:      newDepartmentRequest.SubmittedBy,
+		CreatedBy:      currentUser(c).UserId,
 	}
 
 	if err := h.service.CreateDepartment(&newDepartment); err != nil {
@@ -156,9 +170,10 @@ func (h *DepartmentHandler) UpdateDepartmentById(c *gin.Context) {
 		return
 	}
 
+	modifiedBy := currentUser(c).UserId
 	timeNow := time.Now()
 	department.DepartmentName = updateDepartmentRequest.DepartmentName
-	department.ModifiedBy = &updateDepartmentRequest.SubmittedBy
+	department.ModifiedBy = &modifiedBy
 	department.ModifiedAt = &timeNow
 
 	if err := h.service.UpdateDepartment(department); err != nil {
@@ -196,6 +211,8 @@ func (h *DepartmentHandler) DeleteDepartmentById(c *gin.Context) {
 		return
 	}
 
+	deleteDepartmentRequest.SubmittedBy = currentUser(c).UserId
+
 	if err := h.service.DeleteDepartment(&deleteDepartmentRequest); err != nil {
 		logger.Log.Error("department.delete.failed",
 			zap.String("request_id", requestID.(string)),
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mugnialby/arsip-backend/internal/service"
+	"github.com/mugnialby/arsip-backend/pkg/logger"
+	"github.com/mugnialby/arsip-backend/pkg/response"
+	"go.uber.org/zap"
+)
+
+type JobHandler struct {
+	jobService *service.JobService
+}
+
+func NewJobHandler(jobService *service.JobService) *JobHandler {
+	return &JobHandler{jobService: jobService}
+}
+
+// EnqueuePDFBuild queues a merged PDF build for the archive and returns the
+// job to poll. Once it completes, resultUrl serves the PDF without waiting.
+func (h *JobHandler) EnqueuePDFBuild(c *gin.Context) {
+	start := time.Now()
+	requestID, _ := c.Get("request_id")
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.Log.Warn("job.pdf_build.invalid_id",
+			zap.String("request_id", requestID.(string)),
+			zap.String("param", c.Param("id")),
+			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
+
+		response.Error(c, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	job, err := h.jobService.EnqueuePDFBuild(uint(id), currentUser(c))
+	if err != nil {
+		logger.Log.Error("job.pdf_build.failed",
+			zap.String("request_id", requestID.(string)),
+			zap.Uint("archive_id", uint(id)),
+			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
+
+		respondJobError(c, err, http.StatusInternalServerError, "Failed to queue PDF build")
+		return
+	}
+
+	logger.Log.Info("job.pdf_build.success",
+		zap.String("request_id", requestID.(string)),
+		zap.Uint("archive_id", uint(id)),
+		zap.String("job_id", job.JobID),
+		zap.String("state", job.State),
+		zap.Duration("duration_ms", time.Since(start)),
+	)
+
+	c.Header("Location", "/api/jobs/"+job.JobID)
+	response.Accepted(c, job)
+}
+
+func (h *JobHandler) GetJob(c *gin.Context) {
+	start := time.Now()
+	requestID, _ := c.Get("request_id")
+
+	jobID := c.Param("jobId")
+
+	job, err := h.jobService.GetJob(jobID, currentUser(c))
+	if err != nil {
+		logger.Log.Info("job.get.failed",
+			zap.String("request_id", requestID.(string)),
+			zap.String("job_id", jobID),
+			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
+
+		respondJobError(c, err, http.StatusInternalServerError, "Failed to get data")
+		return
+	}
+
+	response.Success(c, job)
+}
+
+func respondJobError(c *gin.Context, err error, status int, message string) {
+	switch {
+	case errors.Is(err, service.ErrJobNotFound):
+		response.Error(c, http.StatusNotFound, "Job not found")
+	default:
+		respondArchiveError(c, err, status, message)
+	}
+}
@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mugnialby/arsip-backend/internal/listquery"
+	"github.com/mugnialby/arsip-backend/pkg/response"
+)
+
+// respondList sends a list with its pagination metadata.
+func respondList(c *gin.Context, data any, result listquery.Result) {
+	response.Paginated(c, data, response.Pagination{
+		Total:      result.Total,
+		Page:       result.Page,
+		PageSize:   result.PageSize,
+		NextCursor: result.NextCursor,
+	})
+}
+
+// respondListError answers invalid list parameters with 400 and anything
+// else with status and message.
+func respondListError(c *gin.Context, err error, status int, message string) {
+	if errors.Is(err, listquery.ErrInvalid) {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.Error(c, status, message)
+}
@@ -1,11 +1,13 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mugnialby/arsip-backend/internal/listquery"
 	"github.com/mugnialby/arsip-backend/internal/model"
 	request "github.com/mugnialby/arsip-backend/internal/model/dto/request/roles"
 	"github.com/mugnialby/arsip-backend/internal/service"
@@ -26,7 +28,19 @@ func (h *RoleHandler) GetAllRoles(c *gin.Context) {
 	start := time.Now()
 	requestID, _ := c.Get("request_id")
 
-	roles, err := h.service.GetAllRoles()
+	query, err := listquery.Parse(c.Request.URL.Query())
+	if err != nil {
+		logger.Log.Warn("role.get_all.invalid_query",
+			zap.String("request_id", requestID.(string)),
+			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
+
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	roles, result, err := h.service.GetAllRoles(query)
 	if err != nil {
 		logger.Log.Error("role.get_all.failed",
 			zap.String("request_id", requestID.(string)),
@@ -34,17 +48,18 @@ func (h *RoleHandler) GetAllRoles(c *gin.Context) {
 			zap.Duration("duration_ms", time.Since(start)),
 		)
 
-		response.Error(c, http.StatusInternalServerError, "Failed to get data")
+		respondListError(c, err, http.StatusInternalServerError, "Failed to get data")
 		return
 	}
 
 	logger.Log.Info("role.get_all.success",
 		zap.String("request_id", requestID.(string)),
 		zap.Int("count", len(roles)),
+		zap.Int64("total", result.Total),
 		zap.Duration("duration_ms", time.Since(start)),
 	)
 
-	response.Success(c, roles)
+	respondList(c, roles, result)
 }
 
 func (h *RoleHandler) GetRoleByID(c *gin.Context) {
@@ -105,7 +120,7 @@ func (h *RoleHandler) CreateRole(c *gin.Context) {
 		RoleName:     newRoleRequest.RoleName,
 		DepartmentID: newRoleRequest.DepartmentID,
 		Status:       "Y",
-		CreatedBy:    newRoleRequest.CreatedBy,
+		CreatedBy:    currentUser(c).UserId,
 	}
 
 	if err := h.service.CreateRole(&newRole); err != nil {
@@ -157,10 +172,11 @@ func (h *RoleHandler) UpdateRoleById(c *gin.Context) {
 		return
 	}
 
+	modifiedBy := currentUser(c).UserId
 	timeNow := time.Now()
 	role.RoleName = updateRoleRequest.RoleName
 	role.DepartmentID = updateRoleRequest.DepartmentID
-	role.ModifiedBy = &updateRoleRequest.SubmittedBy
+	role.ModifiedBy = &modifiedBy
 	role.ModifiedAt = &timeNow
 
 	if err := h.service.UpdateRole(role); err != nil {
@@ -198,6 +214,8 @@ func (h *RoleHandler) DeleteRoleById(c *gin.Context) {
 		return
 	}
 
+	deleteRoleRequest.SubmittedBy = currentUser(c).UserId
+
 	if err := h.service.DeleteRole(&deleteRoleRequest); err != nil {
 		logger.Log.Error("role.delete.failed",
 			zap.String("request_id", requestID.(string)),
@@ -255,3 +273,157 @@ func (h *RoleHandler) GetRoleByDepartmentID(c *gin.Context) {
 
 	response.Success(c, roles)
 }
+
+func (h *RoleHandler) GetAllPermissions(c *gin.Context) {
+	response.Success(c, model.Permissions)
+}
+
+func (h *RoleHandler) GetRolePermissions(c *gin.Context) {
+	start := time.Now()
+	requestID, _ := c.Get("request_id")
+
+	roleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.Log.Warn("role.get_permissions.invalid_id",
+			zap.String("request_id", requestID.(string)),
+			zap.String("param", c.Param("id")),
+			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
+
+		response.Error(c, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	rolePermissions, err := h.service.GetRolePermissions(uint(roleID))
+	if errors.Is(err, service.ErrRoleNotFound) {
+		logger.Log.Info("role.get_permissions.role_not_found",
+			zap.String("request_id", requestID.(string)),
+			zap.Uint("role_id", uint(roleID)),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
+
+		response.Error(c, http.StatusNotFound, "Role not found")
+		return
+	}
+	if err != nil {
+		logger.Log.Error("role.get_permissions.failed",
+			zap.String("request_id", requestID.(string)),
+			zap.Uint("role_id", uint(roleID)),
+			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
+
+		response.Error(c, http.StatusInternalServerError, "Failed to get data")
+		return
+	}
+
+	logger.Log.Info("role.get_permissions.success",
+		zap.String("request_id", requestID.(string)),
+		zap.Int("count", len(rolePermissions)),
+		zap.Duration("duration_ms", time.Since(start)),
+	)
+
+	response.Success(c, rolePermissions)
+}
+
+func (h *RoleHandler) CreateRolePermission(c *gin.Context) {
+	start := time.Now()
+	requestID, _ := c.Get("request_id")
+
+	roleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.Log.Warn("role.create_permission.invalid_id",
+			zap.String("request_id", requestID.(string)),
+			zap.String("param", c.Param("id")),
+			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
+
+		response.Error(c, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	var newRolePermissionRequest request.NewRolePermissionRequest
+	if err := c.ShouldBindJSON(&newRolePermissionRequest); err != nil {
+		logger.Log.Warn("role.create_permission.invalid_request",
+			zap.String("request_id", requestID.(string)),
+			zap.Error(err),
+		)
+
+		response.Error(c, http.StatusBadRequest, "JSON request is not valid")
+		return
+	}
+
+	newRolePermission := model.RolePermission{
+		RoleID:     uint(roleID),
+		Permission: newRolePermissionRequest.Permission,
+		Status:     "Y",
+		CreatedBy:  currentUser(c).UserId,
+	}
+
+	if err := h.service.AddRolePermission(&newRolePermission); err != nil {
+		logger.Log.Error("role.create_permission.failed",
+			zap.String("request_id", requestID.(string)),
+			zap.Any("payload", newRolePermission),
+			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
+
+		switch {
+		case errors.Is(err, service.ErrRoleNotFound):
+			response.Error(c, http.StatusNotFound, "Role not found")
+		case errors.Is(err, service.ErrUnknownPermission):
+			response.Error(c, http.StatusBadRequest, "Unknown permission")
+		case errors.Is(err, service.ErrPermissionAlreadyGranted):
+			response.Error(c, http.StatusConflict, "Permission already granted to role")
+		default:
+			response.Error(c, http.StatusInternalServerError, "Failed to create data")
+		}
+		return
+	}
+
+	logger.Log.Info("role.create_permission.success",
+		zap.String("request_id", requestID.(string)),
+		zap.Duration("duration_ms", time.Since(start)),
+	)
+
+	c.Status(http.StatusCreated)
+}
+
+func (h *RoleHandler) DeleteRolePermissionById(c *gin.Context) {
+	start := time.Now()
+	requestID, _ := c.Get("request_id")
+
+	var deleteRolePermissionRequest request.DeleteRolePermissionRequest
+	if err := c.ShouldBindJSON(&deleteRolePermissionRequest); err != nil {
+		logger.Log.Warn("role.delete_permission.invalid_request",
+			zap.String("request_id", requestID.(string)),
+			zap.Error(err),
+		)
+
+		response.Error(c, http.StatusBadRequest, "JSON Request is not valid")
+		return
+	}
+
+	deleteRolePermissionRequest.SubmittedBy = currentUser(c).UserId
+
+	if err := h.service.DeleteRolePermission(&deleteRolePermissionRequest); err != nil {
+		logger.Log.Error("role.delete_permission.failed",
+			zap.String("request_id", requestID.(string)),
+			zap.Error(err),
+			zap.Any("payload", deleteRolePermissionRequest),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
+
+		response.Error(c, http.StatusInternalServerError, "Failed to delete data")
+		return
+	}
+
+	logger.Log.Info("role.delete_permission.success",
+		zap.String("request_id", requestID.(string)),
+		zap.Duration("duration_ms", time.Since(start)),
+	)
+
+	c.Status(http.StatusOK)
+}
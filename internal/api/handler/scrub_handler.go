@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mugnialby/arsip-backend/internal/service"
+	"github.com/mugnialby/arsip-backend/pkg/logger"
+	"github.com/mugnialby/arsip-backend/pkg/response"
+	"go.uber.org/zap"
+)
+
+type ScrubHandler struct {
+	scrubService *service.ScrubService
+}
+
+func NewScrubHandler(scrubService *service.ScrubService) *ScrubHandler {
+	return &ScrubHandler{scrubService: scrubService}
+}
+
+// StartScrub starts an integrity check in the background and returns the
+// running report; poll it by id for the result.
+func (h *ScrubHandler) StartScrub(c *gin.Context) {
+	start := time.Now()
+	requestID, _ := c.Get("request_id")
+
+	scrubReport, err := h.scrubService.Start(currentUser(c).UserId)
+	if err != nil {
+		logger.Log.Error("scrub.start.failed",
+			zap.String("request_id", requestID.(string)),
+			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
+
+		respondScrubError(c, err, http.StatusInternalServerError, "Failed to start scrub")
+		return
+	}
+
+	logger.Log.Info("scrub.start.success",
+		zap.String("request_id", requestID.(string)),
+		zap.Uint("report_id", scrubReport.ID),
+		zap.Duration("duration_ms", time.Since(start)),
+	)
+
+	response.Accepted(c, scrubReport)
+}
+
+func (h *ScrubHandler) GetLatestReport(c *gin.Context) {
+	start := time.Now()
+	requestID, _ := c.Get("request_id")
+
+	scrubReport, err := h.scrubService.GetLatestReport()
+	if err != nil {
+		logger.Log.Info("scrub.report.latest.failed",
+			zap.String("request_id", requestID.(string)),
+			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
+
+		respondScrubError(c, err, http.StatusInternalServerError, "Failed to get data")
+		return
+	}
+
+	response.Success(c, scrubReport)
+}
+
+func (h *ScrubHandler) GetReportByID(c *gin.Context) {
+	start := time.Now()
+	requestID, _ := c.Get("request_id")
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.Log.Warn("scrub.report.get.invalid_id",
+			zap.String("request_id", requestID.(string)),
+			zap.String("id", c.Param("id")),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
+
+		response.Error(c, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	scrubReport, err := h.scrubService.GetReportByID(uint(id))
+	if err != nil {
+		logger.Log.Info("scrub.report.get.failed",
+			zap.String("request_id", requestID.(string)),
+			zap.Int("report_id", id),
+			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
+
+		respondScrubError(c, err, http.StatusInternalServerError, "Failed to get data")
+		return
+	}
+
+	response.Success(c, scrubReport)
+}
+
+func respondScrubError(c *gin.Context, err error, status int, message string) {
+	switch {
+	case errors.Is(err, service.ErrScrubReportNotFound):
+		response.Error(c, http.StatusNotFound, "Scrub report not found")
+	case errors.Is(err, service.ErrScrubRunning):
+		response.Error(c, http.StatusConflict, "A scrub is already running")
+	default:
+		response.Error(c, status, message)
+	}
+}
@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	uploadRequest "github.com/mugnialby/arsip-backend/internal/model/dto/request/uploads"
+	"github.com/mugnialby/arsip-backend/internal/service"
+	"github.com/mugnialby/arsip-backend/pkg/logger"
+	"github.com/mugnialby/arsip-backend/pkg/response"
+	"go.uber.org/zap"
+)
+
+const (
+	uploadOffsetHeader   = "Upload-Offset"
+	uploadChecksumHeader = "Upload-Checksum"
+)
+
+type UploadHandler struct {
+	uploadService *service.UploadService
+}
+
+func NewUploadHandler(uploadService *service.UploadService) *UploadHandler {
+	return &UploadHandler{uploadService: uploadService}
+}
+
+func (h *UploadHandler) InitUpload(c *gin.Context) {
+	start := time.Now()
+	requestID, _ := c.Get("request_id")
+
+	var newUploadRequest uploadRequest.NewUploadRequest
+	if err := c.ShouldBindJSON(&newUploadRequest); err != nil {
+		logger.Log.Warn("upload.init.invalid_request",
+			zap.String("request_id", requestID.(string)),
+			zap.Error(err),
+		)
+
+		response.Error(c, http.StatusBadRequest, "JSON request is not valid")
+		return
+	}
+
+	uploadSession, err := h.uploadService.InitUpload(&newUploadRequest, currentUser(c))
+	if err != nil {
+		logger.Log.Error("upload.init.failed",
+			zap.String("request_id", requestID.(string)),
+			zap.Uint("archive_id", newUploadRequest.ArchiveHdrID),
+			zap.Int64("total_size", newUploadRequest.TotalSize),
+			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
+
+		respondUploadError(c, err, http.StatusInternalServerError, "Failed to create upload")
+		return
+	}
+
+	logger.Log.Info("upload.init.success",
+		zap.String("request_id", requestID.(string)),
+		zap.String("upload_id", uploadSession.UploadID),
+		zap.Uint("archive_id", uploadSession.ArchiveHdrID),
+		zap.Int64("total_size", uploadSession.TotalSize),
+		zap.Duration("duration_ms", time.Since(start)),
+	)
+
+	c.Header(uploadOffsetHeader, strconv.FormatInt(uploadSession.Offset, 10))
+	response.Created(c, uploadSession)
+}
+
+func (h *UploadHandler) GetUpload(c *gin.Context) {
+	start := time.Now()
+	requestID, _ := c.Get("request_id")
+
+	uploadID := c.Param("uploadId")
+
+	uploadSession, err := h.uploadService.GetUpload(uploadID, currentUser(c))
+	if err != nil {
+		logger.Log.Info("upload.get.failed",
+			zap.String("request_id", requestID.(string)),
+			zap.String("upload_id", uploadID),
+			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
+
+		respondUploadError(c, err, http.StatusInternalServerError, "Failed to get data")
+		return
+	}
+
+	c.Header(uploadOffsetHeader, strconv.FormatInt(uploadSession.Offset, 10))
+	response.Success(c, uploadSession)
+}
+
+// UploadChunk appends the raw request body at the offset given in the
+// Upload-Offset header. An optional Upload-Checksum header carries the hex
+// sha256 of the chunk.
+func (h *UploadHandler) UploadChunk(c *gin.Context) {
+	start := time.Now()
+	requestID, _ := c.Get("request_id")
+
+	uploadID := c.Param("uploadId")
+
+	offset, err := strconv.ParseInt(c.GetHeader(uploadOffsetHeader), 10, 64)
+	if err != nil || offset < 0 {
+		logger.Log.Warn("upload.chunk.invalid_offset",
+			zap.String("request_id", requestID.(string)),
+			zap.String("upload_id", uploadID),
+			zap.String("offset", c.GetHeader(uploadOffsetHeader)),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
+
+		response.Error(c, http.StatusBadRequest, "Invalid Upload-Offset header")
+		return
+	}
+
+	uploadSession, err := h.uploadService.UploadChunk(uploadID, offset, c.GetHeader(uploadChecksumHeader), c.Request.Body, currentUser(c))
+	if err != nil {
+		logger.Log.Error("upload.chunk.failed",
+			zap.String("request_id", requestID.(string)),
+			zap.String("upload_id", uploadID),
+			zap.Int64("offset", offset),
+			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
+
+		if uploadSession != nil {
+			c.Header(uploadOffsetHeader, strconv.FormatInt(uploadSession.Offset, 10))
+		}
+
+		respondUploadError(c, err, http.StatusInternalServerError, "Failed to upload chunk")
+		return
+	}
+
+	logger.Log.Info("upload.chunk.success",
+		zap.String("request_id", requestID.(string)),
+		zap.String("upload_id", uploadID),
+		zap.Int64("offset", uploadSession.Offset),
+		zap.Int64("total_size", uploadSession.TotalSize),
+		zap.Duration("duration_ms", time.Since(start)),
+	)
+
+	c.Header(uploadOffsetHeader, strconv.FormatInt(uploadSession.Offset, 10))
+	response.Success(c, uploadSession)
+}
+
+func (h *UploadHandler) CompleteUpload(c *gin.Context) {
+	start := time.Now()
+	requestID, _ := c.Get("request_id")
+
+	uploadID := c.Param("uploadId")
+
+	archiveAttachment, err := h.uploadService.CompleteUpload(uploadID, currentUser(c))
+	if err != nil {
+		logger.Log.Error("upload.complete.failed",
+			zap.String("request_id", requestID.(string)),
+			zap.String("upload_id", uploadID),
+			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
+
+		respondUploadError(c, err, http.StatusInternalServerError, "Failed to complete upload")
+		return
+	}
+
+	logger.Log.Info("upload.complete.success",
+		zap.String("request_id", requestID.(string)),
+		zap.String("upload_id", uploadID),
+		zap.Uint("archive_id", archiveAttachment.ArchiveHdrID),
+		zap.Uint("attachment_id", archiveAttachment.ID),
+		zap.Duration("duration_ms", time.Since(start)),
+	)
+
+	response.Created(c, archiveAttachment)
+}
+
+func (h *UploadHandler) CancelUpload(c *gin.Context) {
+	start := time.Now()
+	requestID, _ := c.Get("request_id")
+
+	uploadID := c.Param("uploadId")
+
+	if err := h.uploadService.CancelUpload(uploadID, currentUser(c)); err != nil {
+		logger.Log.Error("upload.cancel.failed",
+			zap.String("request_id", requestID.(string)),
+			zap.String("upload_id", uploadID),
+			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
+
+		respondUploadError(c, err, http.StatusInternalServerError, "Failed to cancel upload")
+		return
+	}
+
+	logger.Log.Info("upload.cancel.success",
+		zap.String("request_id", requestID.(string)),
+		zap.String("upload_id", uploadID),
+		zap.Duration("duration_ms", time.Since(start)),
+	)
+
+	c.Status(http.StatusOK)
+}
+
+// respondUploadError maps upload errors to 4xx responses and defers to
+// respondArchiveError for archive and attachment errors.
+func respondUploadError(c *gin.Context, err error, status int, message string) {
+	switch {
+	case errors.Is(err, service.ErrUploadNotFound):
+		response.Error(c, http.StatusNotFound, "Upload not found")
+	case errors.Is(err, service.ErrUploadCompleted):
+		response.Error(c, http.StatusConflict, "Upload is already completed")
+	case errors.Is(err, service.ErrUploadOffsetMismatch):
+		response.Error(c, http.StatusConflict, "Upload offset does not match")
+	case errors.Is(err, service.ErrUploadIncomplete):
+		response.Error(c, http.StatusConflict, "Upload is not complete")
+	case errors.Is(err, service.ErrUploadChecksumInvalid):
+		response.Error(c, http.StatusBadRequest, "Checksum must be a hex encoded sha256")
+	case errors.Is(err, service.ErrUploadChecksumMismatch):
+		response.Error(c, http.StatusUnprocessableEntity, "Checksum does not match")
+	default:
+		respondArchiveError(c, err, status, message)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mugnialby/arsip-backend/internal/listquery"
 	"github.com/mugnialby/arsip-backend/internal/model"
 	request "github.com/mugnialby/arsip-backend/internal/model/dto/request/users"
 	"github.com/mugnialby/arsip-backend/internal/service"
@@ -26,7 +27,19 @@ func (h *UserHandler) GetAllUsers(c *gin.Context) {
 	start := time.Now()
 	requestID, _ := c.Get("request_id")
 
-	users, err := h.service.GetAllUsers()
+	query, err := listquery.Parse(c.Request.URL.Query())
+	if err != nil {
+		logger.Log.Warn("user.get_all.invalid_query",
+			zap.String("request_id", requestID.(string)),
+			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
+
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	users, result, err := h.service.GetAllUsers(query)
 	if err != nil {
 		logger.Log.Error("user.get_all.failed",
 			zap.String("request_id", requestID.(string)),
@@ -34,17 +47,18 @@ func (h *UserHandler) GetAllUsers(c *gin.Context) {
 			zap.Duration("duration_ms", time.Since(start)),
 		)
 
-		response.Error(c, http.StatusInternalServerError, "Failed to get data")
+		respondListError(c, err, http.StatusInternalServerError, "Failed to get data")
 		return
 	}
 
 	logger.Log.Info("user.get_all.success",
 		zap.String("request_id", requestID.(string)),
 		zap.Int("count", len(users)),
+		zap.Int64("total", result.Total),
 		zap.Duration("duration_ms", time.Since(start)),
 	)
 
-	response.Success(c, users)
+	respondList(c, users, result)
 }
 
 func (h *UserHandler) GetUserByID(c *gin.Context) {
@@ -103,15 +117,14 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 	newUser := model.User{
 		ID:           0,
 		UserId:       newUserRequest.UserId,
-		PasswordHash: newUserRequest.PasswordHash,
 		FullName:     newUserRequest.FullName,
 		DepartmentID: newUserRequest.DepartmentID,
 		RoleID:       newUserRequest.RoleID,
 		Status:       "Y",
-		CreatedBy:    newUserRequest.SubmittedBy,
+		CreatedBy:    currentUser(c).UserId,
 	}
 
-	if err := h.service.CreateUser(&newUser); err != nil {
+	if err := h.service.CreateUser(&newUser, newUserRequest.PasswordHash); err != nil {
 		logger.Log.Error("user.create.failed",
 			zap.String("request_id", requestID.(string)),
 			zap.Any("payload", newUser),
@@ -160,18 +173,19 @@ func (h *UserHandler) UpdateUserById(c *gin.Context) {
 		return
 	}
 
+	modifiedBy := currentUser(c).UserId
 	timeNow := time.Now()
 	user.UserId = updateUserRequest.UserId
 	user.FullName = updateUserRequest.FullName
 	user.DepartmentID = updateUserRequest.DepartmentID
 	user.RoleID = updateUserRequest.RoleID
-	user.ModifiedBy = &updateUserRequest.SubmittedBy
+	user.ModifiedBy = &modifiedBy
 	user.ModifiedAt = &timeNow
 
-	if err := h.service.UpdateUser(user); err != nil {
+	if err := h.service.UpdateUser(user, updateUserRequest.Password); err != nil {
 		logger.Log.Error("user.update.save.failed",
 			zap.String("request_id", requestID.(string)),
-			zap.Any("payload", updateUserRequest),
+			zap.Any("payload", updateUserRequest.ID),
 			zap.Error(err),
 			zap.Duration("duration_ms", time.Since(start)),
 		)
@@ -203,6 +217,8 @@ func (h *UserHandler) DeleteUserById(c *gin.Context) {
 		return
 	}
 
+	deleteUserRequest.SubmittedBy = currentUser(c).UserId
+
 	if err := h.service.DeleteUser(&deleteUserRequest); err != nil {
 		logger.Log.Error("user.delete.failed",
 			zap.String("request_id", requestID.(string)),
@@ -1,15 +1,16 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/mugnialby/arsip-backend/internal/utils"
+	"github.com/mugnialby/arsip-backend/internal/service"
 	"github.com/mugnialby/arsip-backend/pkg/response"
 )
 
-func JWTAuth(jwtService *utils.JWTService) gin.HandlerFunc {
+func JWTAuth(authService *service.AuthService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -26,15 +27,21 @@ func JWTAuth(jwtService *utils.JWTService) gin.HandlerFunc {
 		}
 
 		tokenStr := parts[1]
-		claims, err := jwtService.ValidateToken(tokenStr)
-		if err != nil {
+		user, err := authService.Authenticate(tokenStr)
+		if errors.Is(err, service.ErrInvalidAccessToken) {
 			response.Error(c, http.StatusUnauthorized, "Invalid or expired token")
 			c.Abort()
 			return
 		}
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "Failed to authenticate")
+			c.Abort()
+			return
+		}
 
-		// Attach user ID to context for use in handlers
-		c.Set("user_id", claims.UserID)
+		// Attach the authenticated user to context for use in handlers
+		c.Set("user_id", user.ID)
+		c.Set("user", user)
 
 		c.Next()
 	}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mugnialby/arsip-backend/internal/model"
+	"github.com/mugnialby/arsip-backend/internal/service"
+	"github.com/mugnialby/arsip-backend/pkg/logger"
+	"github.com/mugnialby/arsip-backend/pkg/response"
+	"go.uber.org/zap"
+)
+
+// RequirePermission aborts with 403 unless the role of the user attached by
+// JWTAuth has been granted the permission.
+func RequirePermission(roleService *service.RoleService, permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID, _ := c.Get("request_id")
+
+		user, ok := c.MustGet("user").(*model.User)
+		if !ok {
+			response.Error(c, http.StatusUnauthorized, "Missing authenticated user")
+			c.Abort()
+			return
+		}
+
+		allowed, err := roleService.HasPermission(user, permission)
+		if err != nil {
+			logger.Log.Error("middleware.permission.check.failed",
+				zap.Any("request_id", requestID),
+				zap.Uint("user_id", user.ID),
+				zap.String("permission", permission),
+				zap.Error(err),
+			)
+
+			response.Error(c, http.StatusInternalServerError, "Failed to check permission")
+			c.Abort()
+			return
+		}
+
+		if !allowed {
+			logger.Log.Warn("middleware.permission.denied",
+				zap.Any("request_id", requestID),
+				zap.Uint("user_id", user.ID),
+				zap.Uint("role_id", user.RoleID),
+				zap.String("permission", permission),
+			)
+
+			response.Error(c, http.StatusForbidden, "You do not have permission to perform this action")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -8,7 +8,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/mugnialby/arsip-backend/internal/api/handler"
 	"github.com/mugnialby/arsip-backend/internal/api/middleware"
-	"github.com/mugnialby/arsip-backend/internal/config"
+	"github.com/mugnialby/arsip-backend/internal/model"
 	"github.com/mugnialby/arsip-backend/internal/service"
 )
 
@@ -16,12 +16,15 @@ func NewRouter(
 	userService *service.UserService,
 	roleService *service.RoleService,
 	departmentService *service.DepartmentService,
-	// authService *service.AuthService,
+	authService *service.AuthService,
 	archiveService *service.ArchiveService,
 	archiveAttachmentService *service.ArchiveAttachmentService,
 	archiveTypeService *service.ArchiveTypeService,
 	archiveCharacteristicService *service.ArchiveCharacteristicService,
 	archiveRoleAccessService *service.ArchiveRoleAccessService,
+	uploadService *service.UploadService,
+	scrubService *service.ScrubService,
+	jobService *service.JobService,
 ) *gin.Engine {
 	r := gin.Default()
 	r.Use(middleware.RequestLogger())
@@ -39,7 +42,8 @@ func NewRouter(
 			return matched192 || matched10 || matched172
 		},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowHeaders:     []string{"Access-Control-Allow-Origin", "Origin", "Content-Type", "Accept", "Authorization", "Content-Disposition", "Cache-Control"},
+		AllowHeaders:     []string{"Access-Control-Allow-Origin", "Origin", "Content-Type", "Accept", "Authorization", "Content-Disposition", "Cache-Control", "Upload-Offset", "Upload-Checksum", "Range", "If-None-Match", "If-Range"},
+		ExposeHeaders:    []string{"Upload-Offset", "Content-Disposition", "Content-Length", "Content-Range", "Accept-Ranges", "ETag", "Location"},
 		AllowCredentials: false,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -48,16 +52,24 @@ func NewRouter(
 		c.Status(204)
 	})
 
-	config.Load()
-	// jwtService := utils.NewJWTService(cfg.JWTSecret, cfg.AppName)
-
 	userHandler := handler.NewUserHandler(userService)
 	roleHandler := handler.NewRoleHandler(roleService)
 	departmentHandler := handler.NewDepartmentHandler(departmentService)
-	authHandler := handler.NewAuthHandler(userService)
+	authHandler := handler.NewAuthHandler(authService)
 	archiveHandler := handler.NewArchiveHandler(archiveService, archiveAttachmentService, archiveRoleAccessService)
 	archiveTypeHandler := handler.NewArchiveTypeHandler(archiveTypeService)
 	archiveCharacteristicHandler := handler.NewArchiveCharacteristicHandler(archiveCharacteristicService)
+	uploadHandler := handler.NewUploadHandler(uploadService)
+	scrubHandler := handler.NewScrubHandler(scrubService)
+	jobHandler := handler.NewJobHandler(jobService)
+
+	masterRead := middleware.RequirePermission(roleService, model.PermissionMasterRead)
+	masterWrite := middleware.RequirePermission(roleService, model.PermissionMasterWrite)
+	usersWrite := middleware.RequirePermission(roleService, model.PermissionUsersWrite)
+	rolesWrite := middleware.RequirePermission(roleService, model.PermissionRolesWrite)
+	archivesWrite := middleware.RequirePermission(roleService, model.PermissionArchivesWrite)
+	archivesDelete := middleware.RequirePermission(roleService, model.PermissionArchivesDelete)
+	attachmentsScrub := middleware.RequirePermission(roleService, model.PermissionAttachmentsScrub)
 
 	api := r.Group("/api")
 	{
@@ -68,75 +80,109 @@ func NewRouter(
 		auth := api.Group("/auth")
 		{
 			auth.POST("/authenticate", authHandler.Login)
-
-			// api.POST("/login", func(c *gin.Context) {
-			// Here you’d normally validate credentials, but we mock it for example:
-			// token, _ := jwtService.GenerateToken(1) // 1 = user ID
-			// c.JSON(200, gin.H{"token": token})
-			// })
+			auth.POST("/refresh", authHandler.Refresh)
+			auth.POST("/logout", authHandler.Logout)
 		}
 
 		master := api.Group("/master")
-		// master.Use(middleware.JWTAuth(jwtService))
+		master.Use(middleware.JWTAuth(authService))
 		{
+			master.GET("/permissions", masterRead, roleHandler.GetAllPermissions)
+
 			users := master.Group("/users")
 			{
-				users.GET("/", userHandler.GetAllUsers)
-				users.GET("/:id", userHandler.GetUserByID)
-				users.POST("/", userHandler.CreateUser)
-				users.PUT("/", userHandler.UpdateUserById)
-				users.PATCH("/", userHandler.DeleteUserById)
+				users.GET("/", masterRead, userHandler.GetAllUsers)
+				users.GET("/:id", masterRead, userHandler.GetUserByID)
+				users.POST("/", usersWrite, userHandler.CreateUser)
+				users.PUT("/", usersWrite, userHandler.UpdateUserById)
+				users.PATCH("/", usersWrite, userHandler.DeleteUserById)
 			}
 
 			roles := master.Group("/roles")
 			{
-				roles.GET("/", roleHandler.GetAllRoles)
-				roles.GET("/:id", roleHandler.GetRoleByID)
-				roles.POST("/", roleHandler.CreateRole)
-				roles.PUT("/", roleHandler.UpdateRoleById)
-				roles.PATCH("/", roleHandler.DeleteRoleById)
-				roles.GET("/findByQuery/department/:id", roleHandler.GetRoleByDepartmentID)
+				roles.GET("/", masterRead, roleHandler.GetAllRoles)
+				roles.GET("/:id", masterRead, roleHandler.GetRoleByID)
+				roles.POST("/", rolesWrite, roleHandler.CreateRole)
+				roles.PUT("/", rolesWrite, roleHandler.UpdateRoleById)
+				roles.PATCH("/", rolesWrite, roleHandler.DeleteRoleById)
+				roles.GET("/findByQuery/department/:id", masterRead, roleHandler.GetRoleByDepartmentID)
+				roles.GET("/:id/permissions", masterRead, roleHandler.GetRolePermissions)
+				roles.POST("/:id/permissions", rolesWrite, roleHandler.CreateRolePermission)
+				roles.PATCH("/permissions", rolesWrite, roleHandler.DeleteRolePermissionById)
 			}
 
 			department := master.Group("/departments")
 			{
-				department.GET("/", departmentHandler.GetAllDepartments)
-				department.GET("/:id", departmentHandler.GetDepartmentByID)
-				department.POST("/", departmentHandler.CreateDepartment)
-				department.PUT("/", departmentHandler.UpdateDepartmentById)
-				department.PATCH("/", departmentHandler.DeleteDepartmentById)
+				department.GET("/", masterRead, departmentHandler.GetAllDepartments)
+				department.GET("/:id", masterRead, departmentHandler.GetDepartmentByID)
+				department.POST("/", masterWrite, departmentHandler.CreateDepartment)
+				department.PUT("/", masterWrite, departmentHandler.UpdateDepartmentById)
+				department.PATCH("/", masterWrite, departmentHandler.DeleteDepartmentById)
 			}
 
 			archiveType := master.Group("/archiveTypes")
 			{
-				archiveType.GET("/", archiveTypeHandler.GetAllArchiveTypes)
-				archiveType.GET("/:id", archiveTypeHandler.GetArchiveTypeByID)
-				archiveType.POST("/", archiveTypeHandler.CreateArchiveType)
-				archiveType.PUT("/", archiveTypeHandler.UpdateArchiveTypeById)
-				archiveType.PATCH("/", archiveTypeHandler.DeleteArchiveTypeById)
+				archiveType.GET("/", masterRead, archiveTypeHandler.GetAllArchiveTypes)
+				archiveType.GET("/:id", masterRead, archiveTypeHandler.GetArchiveTypeByID)
+				archiveType.POST("/", masterWrite, archiveTypeHandler.CreateArchiveType)
+				archiveType.PUT("/", masterWrite, archiveTypeHandler.UpdateArchiveTypeById)
+				archiveType.PATCH("/", masterWrite, archiveTypeHandler.DeleteArchiveTypeById)
 			}
 
 			archiveCharacteristic := master.Group("/archiveCharacteristics")
 			{
-				archiveCharacteristic.GET("/", archiveCharacteristicHandler.GetAllArchiveCharacteristics)
-				archiveCharacteristic.GET("/:id", archiveCharacteristicHandler.GetArchiveCharacteristicByID)
-				archiveCharacteristic.POST("/", archiveCharacteristicHandler.CreateArchiveCharacteristic)
-				archiveCharacteristic.PUT("/", archiveCharacteristicHandler.UpdateArchiveCharacteristicById)
-				archiveCharacteristic.PATCH("/", archiveCharacteristicHandler.DeleteArchiveCharacteristicById)
+				archiveCharacteristic.GET("/", masterRead, archiveCharacteristicHandler.GetAllArchiveCharacteristics)
+				archiveCharacteristic.GET("/:id", masterRead, archiveCharacteristicHandler.GetArchiveCharacteristicByID)
+				archiveCharacteristic.POST("/", masterWrite, archiveCharacteristicHandler.CreateArchiveCharacteristic)
+				archiveCharacteristic.PUT("/", masterWrite, archiveCharacteristicHandler.UpdateArchiveCharacteristicById)
+				archiveCharacteristic.PATCH("/", masterWrite, archiveCharacteristicHandler.DeleteArchiveCharacteristicById)
 			}
 		}
 
 		archives := api.Group("/archives")
+		archives.Use(middleware.JWTAuth(authService))
 		{
 			archives.GET("/", archiveHandler.GetAllArchives)
 			archives.POST("/getByData", archiveHandler.GetAllArchivesByData)
 			archives.GET("/:id", archiveHandler.GetArchiveByID)
-			archives.POST("/", archiveHandler.CreateArchive)
-			archives.PUT("/", archiveHandler.UpdateArchiveById)
-			archives.PATCH("/", archiveHandler.DeleteArchiveById)
+			archives.POST("/", archivesWrite, archiveHandler.CreateArchive)
+			archives.PUT("/", archivesWrite, archiveHandler.UpdateArchiveById)
+			archives.PATCH("/", archivesDelete, archiveHandler.DeleteArchiveById)
+			archives.POST("/:id/attachments", archivesWrite, archiveHandler.UploadArchiveAttachment)
+			archives.PUT("/:id/attachments/order", archivesWrite, archiveHandler.ReorderArchiveAttachments)
+			archives.GET("/:id/attachments/:attId", archiveHandler.DownloadArchiveAttachment)
+			archives.GET("/:id/attachments/:attId/thumbnail", archiveHandler.GetArchiveAttachmentThumbnail)
+			archives.GET("/duplicates/:hash", archiveHandler.FindAttachmentDuplicates)
 			archives.GET("/find/:query", archiveHandler.FindArchiveByQuery)
 			archives.POST("/findByQuery/advanced", archiveHandler.FindArchiveByAdvanceQuery)
+			archives.POST("/findByQuery/advanced/facets", archiveHandler.GetArchiveFacets)
+			archives.GET("/search", archiveHandler.SearchArchiveText)
 			archives.GET("/:id/pdf", archiveHandler.StreamMergedPDF)
+			archives.POST("/:id/pdf/build", jobHandler.EnqueuePDFBuild)
+		}
+
+		jobs := api.Group("/jobs")
+		jobs.Use(middleware.JWTAuth(authService))
+		{
+			jobs.GET("/:jobId", jobHandler.GetJob)
+		}
+
+		uploads := api.Group("/uploads")
+		uploads.Use(middleware.JWTAuth(authService), archivesWrite)
+		{
+			uploads.POST("/", uploadHandler.InitUpload)
+			uploads.GET("/:uploadId", uploadHandler.GetUpload)
+			uploads.PATCH("/:uploadId", uploadHandler.UploadChunk)
+			uploads.POST("/:uploadId/complete", uploadHandler.CompleteUpload)
+			uploads.DELETE("/:uploadId", uploadHandler.CancelUpload)
+		}
+
+		scrub := api.Group("/admin/scrub")
+		scrub.Use(middleware.JWTAuth(authService), attachmentsScrub)
+		{
+			scrub.POST("/", scrubHandler.StartScrub)
+			scrub.GET("/reports/latest", scrubHandler.GetLatestReport)
+			scrub.GET("/reports/:id", scrubHandler.GetReportByID)
 		}
 	}
 
@@ -1,9 +1,17 @@
 package appcontext
 
 import (
+	"context"
 	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
 
 	"github.com/mugnialby/arsip-backend/internal/config"
+	"github.com/mugnialby/arsip-backend/internal/extract"
+	"github.com/mugnialby/arsip-backend/internal/pdf"
+	"github.com/mugnialby/arsip-backend/internal/storage"
+	"github.com/mugnialby/arsip-backend/internal/utils"
 	"github.com/mugnialby/arsip-backend/pkg/logger"
 	"go.uber.org/zap"
 	"gorm.io/driver/postgres"
@@ -11,7 +19,21 @@ import (
 )
 
 type AppContext struct {
-	DB *gorm.DB
+	DB        *gorm.DB
+	Storage   storage.Backend
+	PDFMerger pdf.Merger
+
+	// TextExtractor is nil when text extraction is disabled.
+	TextExtractor extract.Extractor
+
+	// ThumbnailTools are the resolved paths thumbnails are rendered with.
+	// A tool that was not found is empty.
+	ThumbnailTools ThumbnailTools
+}
+
+type ThumbnailTools struct {
+	MagickPath   string
+	PdftoppmPath string
 }
 
 func NewAppContext(cfg *config.Config) (*AppContext, error) {
@@ -41,7 +63,131 @@ func NewAppContext(cfg *config.Config) (*AppContext, error) {
 		zap.Any("message", "Database connected and migrated successfully"),
 	)
 
+	backend, err := newStorageBackend(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialise storage: %w", err)
+	}
+
+	logger.Log.Info("main.context.storage.success",
+		zap.String("driver", cfg.StorageDriver),
+	)
+
+	merger, err := newPDFMerger(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialise pdf merger: %w", err)
+	}
+
+	logger.Log.Info("main.context.pdf_merger.success",
+		zap.String("driver", cfg.PDFMergeDriver),
+	)
+
+	extractor, err := newTextExtractor(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialise text extractor: %w", err)
+	}
+
+	logger.Log.Info("main.context.text_extractor.success",
+		zap.String("driver", cfg.TextExtractDriver),
+	)
+
+	thumbnailTools := resolveThumbnailTools(cfg)
+
+	logger.Log.Info("main.context.thumbnail_tools.success",
+		zap.String("magick_path", thumbnailTools.MagickPath),
+		zap.String("pdftoppm_path", thumbnailTools.PdftoppmPath),
+	)
+
 	return &AppContext{
-		DB: db,
+		DB:             db,
+		Storage:        backend,
+		PDFMerger:      merger,
+		TextExtractor:  extractor,
+		ThumbnailTools: thumbnailTools,
 	}, nil
 }
+
+func newStorageBackend(cfg *config.Config) (storage.Backend, error) {
+	switch cfg.StorageDriver {
+	case "s3":
+		return storage.NewS3Backend(context.Background(), storage.S3Options{
+			Endpoint:        cfg.S3Endpoint,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretAccessKey,
+			Bucket:          cfg.S3Bucket,
+			Region:          cfg.S3Region,
+			UseSSL:          cfg.S3UseSSL,
+		})
+	case "local", "":
+		root := cfg.StorageLocalRoot
+		if root == "" {
+			storageLocation, err := utils.GetStorageLocation()
+			if err != nil {
+				return nil, err
+			}
+			root = filepath.Join(storageLocation, "uploads")
+		}
+		return storage.NewLocalBackend(root)
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.StorageDriver)
+	}
+}
+
+func newPDFMerger(cfg *config.Config) (pdf.Merger, error) {
+	switch cfg.PDFMergeDriver {
+	case "external":
+		return pdf.NewExternalMerger(cfg.PDFMagickPath, cfg.PDFPdfunitePath)
+	case "native", "":
+		return pdf.NewNativeMerger(), nil
+	default:
+		return nil, fmt.Errorf("unknown pdf merge driver %q", cfg.PDFMergeDriver)
+	}
+}
+
+func newTextExtractor(cfg *config.Config) (extract.Extractor, error) {
+	switch cfg.TextExtractDriver {
+	case "command":
+		return extract.NewCommandExtractor(cfg.PdftotextPath, cfg.PdftoppmPath, cfg.TesseractPath, cfg.OCRLanguages)
+	case "none", "":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown text extract driver %q", cfg.TextExtractDriver)
+	}
+}
+
+// resolveThumbnailTools looks up ImageMagick and pdftoppm for thumbnails.
+// Empty paths fall back to the tools on PATH, or to the usual poppler install
+// location on Windows. Thumbnails are optional: a missing tool is logged and
+// left empty, and attachments it would render get no thumbnail.
+func resolveThumbnailTools(cfg *config.Config) ThumbnailTools {
+	magickPath := cfg.PDFMagickPath
+	if magickPath == "" {
+		magickPath = "magick"
+	}
+
+	pdftoppmPath := cfg.PdftoppmPath
+	if pdftoppmPath == "" {
+		pdftoppmPath = "pdftoppm"
+		if runtime.GOOS == "windows" {
+			pdftoppmPath = `C:\poppler\Library\bin\pdftoppm.exe`
+		}
+	}
+
+	return ThumbnailTools{
+		MagickPath:   lookPathOptional("imagemagick", magickPath),
+		PdftoppmPath: lookPathOptional("pdftoppm", pdftoppmPath),
+	}
+}
+
+func lookPathOptional(tool string, path string) string {
+	resolved, err := exec.LookPath(path)
+	if err != nil {
+		logger.Log.Warn("main.context.thumbnail_tools.not_found",
+			zap.String("tool", tool),
+			zap.String("path", path),
+			zap.Error(err),
+		)
+		return ""
+	}
+
+	return resolved
+}
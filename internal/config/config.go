@@ -27,9 +27,41 @@ type Config struct {
 	// Gorm
 	AutoMigrate string
 
+	// Authorization
+	SuperuserRoleID uint
+
+	// Storage
+	StorageDriver     string
+	StorageLocalRoot  string
+	S3Endpoint        string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+	S3Bucket          string
+	S3Region          string
+	S3UseSSL          bool
+
+	// PDF merge
+	PDFMergeDriver       string
+	PDFMagickPath        string
+	PDFPdfunitePath      string
+	PDFWatermarkTemplate string
+
+	// Text extraction
+	TextExtractDriver string
+	PdftotextPath     string
+	PdftoppmPath      string
+	TesseractPath     string
+	OCRLanguages      string
+
+	// Upload
+	UploadMaxSizeMB        int
+	UploadChunkedMaxSizeMB int
+	UploadSessionExpiresIn int
+
 	// JWT config
-	JWTSecret    string
-	JWTExpiresIn int
+	JWTSecret           string
+	JWTExpiresIn        int
+	JWTRefreshExpiresIn int
 }
 
 func Load() *Config {
@@ -55,6 +87,45 @@ func Load() *Config {
 		jwtExp = 60
 	}
 
+	jwtRefreshExpStr := getEnv("JWT_REFRESH_EXPIRATION_MINUTES", "10080")
+	jwtRefreshExp, err := strconv.Atoi(jwtRefreshExpStr)
+	if err != nil {
+		jwtRefreshExp = 10080
+	}
+
+	superuserRoleIDStr := getEnv("SUPERUSER_ROLE_ID", "1")
+	superuserRoleID, err := strconv.ParseUint(superuserRoleIDStr, 10, 32)
+	if err != nil || superuserRoleID == 0 {
+		logger.Log.Warn("config.env.superuser_role_id.invalid",
+			zap.String("value", superuserRoleIDStr),
+			zap.String("message", "SUPERUSER_ROLE_ID is not a role id, using 1"),
+		)
+		superuserRoleID = 1
+	}
+
+	uploadMaxSizeStr := getEnv("UPLOAD_MAX_SIZE_MB", "50")
+	uploadMaxSize, err := strconv.Atoi(uploadMaxSizeStr)
+	if err != nil {
+		uploadMaxSize = 50
+	}
+
+	uploadChunkedMaxSizeStr := getEnv("UPLOAD_CHUNKED_MAX_SIZE_MB", "1024")
+	uploadChunkedMaxSize, err := strconv.Atoi(uploadChunkedMaxSizeStr)
+	if err != nil {
+		uploadChunkedMaxSize = 1024
+	}
+
+	uploadSessionExpStr := getEnv("UPLOAD_SESSION_EXPIRATION_MINUTES", "1440")
+	uploadSessionExp, err := strconv.Atoi(uploadSessionExpStr)
+	if err != nil {
+		uploadSessionExp = 1440
+	}
+
+	s3UseSSL, err := strconv.ParseBool(getEnv("S3_USE_SSL", "false"))
+	if err != nil {
+		s3UseSSL = false
+	}
+
 	return &Config{
 		AppName: getEnv("APP_NAME", "Perpustakaan Backend"),
 		AppEnv:  getEnv("APP_ENV", "dev"),
@@ -68,8 +139,35 @@ func Load() *Config {
 		DBSSLMode:   getEnv("DB_SSLMODE", "disable"),
 		AutoMigrate: getEnv("DB_AUTO_MIGRATE", "false"),
 
-		JWTSecret:    getEnv("JWT_SECRET", "changeme"),
-		JWTExpiresIn: jwtExp,
+		SuperuserRoleID: uint(superuserRoleID),
+
+		StorageDriver:     getEnv("STORAGE_DRIVER", "local"),
+		StorageLocalRoot:  getEnv("STORAGE_LOCAL_ROOT", ""),
+		S3Endpoint:        getEnv("S3_ENDPOINT", "localhost:9000"),
+		S3AccessKeyID:     getEnv("S3_ACCESS_KEY_ID", ""),
+		S3SecretAccessKey: getEnv("S3_SECRET_ACCESS_KEY", ""),
+		S3Bucket:          getEnv("S3_BUCKET", "arsip"),
+		S3Region:          getEnv("S3_REGION", ""),
+		S3UseSSL:          s3UseSSL,
+
+		PDFMergeDriver:       getEnv("PDF_MERGE_DRIVER", "native"),
+		PDFMagickPath:        getEnv("PDF_MAGICK_PATH", ""),
+		PDFPdfunitePath:      getEnv("PDF_PDFUNITE_PATH", ""),
+		PDFWatermarkTemplate: getEnv("PDF_WATERMARK_TEMPLATE", "SALINAN - {user} - {timestamp} - {archiveNumber}"),
+
+		TextExtractDriver: getEnv("TEXT_EXTRACT_DRIVER", "none"),
+		PdftotextPath:     getEnv("PDFTOTEXT_PATH", ""),
+		PdftoppmPath:      getEnv("PDFTOPPM_PATH", ""),
+		TesseractPath:     getEnv("TESSERACT_PATH", ""),
+		OCRLanguages:      getEnv("OCR_LANGUAGES", "ind+eng"),
+
+		UploadMaxSizeMB:        uploadMaxSize,
+		UploadChunkedMaxSizeMB: uploadChunkedMaxSize,
+		UploadSessionExpiresIn: uploadSessionExp,
+
+		JWTSecret:           getEnv("JWT_SECRET", "changeme"),
+		JWTExpiresIn:        jwtExp,
+		JWTRefreshExpiresIn: jwtRefreshExp,
 	}
 }
 
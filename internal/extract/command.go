@@ -0,0 +1,185 @@
+package extract
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/mugnialby/arsip-backend/internal/utils"
+)
+
+const (
+	// minTextLength is how many non-space characters pdftotext must find
+	// before a PDF is considered to have a text layer; anything less is OCRed.
+	minTextLength = 32
+
+	// ocrMaxPages caps how many pages of a scanned PDF are OCRed.
+	ocrMaxPages = 100
+	ocrDPI      = 300
+)
+
+// CommandExtractor uses poppler's pdftotext for PDFs with a text layer and
+// Tesseract for images and scanned PDFs, whose pages are rendered with
+// pdftoppm first.
+type CommandExtractor struct {
+	pdftotextPath string
+	pdftoppmPath  string
+	tesseractPath string
+	languages     string
+}
+
+// NewCommandExtractor resolves every tool up front so a missing binary is
+// reported at startup. Empty paths fall back to the tools on PATH, or to the
+// usual install locations on Windows. languages is passed to Tesseract's -l,
+// e.g. "ind+eng".
+func NewCommandExtractor(pdftotextPath string, pdftoppmPath string, tesseractPath string, languages string) (*CommandExtractor, error) {
+	if pdftotextPath == "" {
+		pdftotextPath = "pdftotext"
+		if runtime.GOOS == "windows" {
+			pdftotextPath = `C:\poppler\Library\bin\pdftotext.exe`
+		}
+	}
+
+	if pdftoppmPath == "" {
+		pdftoppmPath = "pdftoppm"
+		if runtime.GOOS == "windows" {
+			pdftoppmPath = `C:\poppler\Library\bin\pdftoppm.exe`
+		}
+	}
+
+	if tesseractPath == "" {
+		tesseractPath = "tesseract"
+		if runtime.GOOS == "windows" {
+			tesseractPath = `C:\Program Files\Tesseract-OCR\tesseract.exe`
+		}
+	}
+
+	if languages == "" {
+		languages = "ind+eng"
+	}
+
+	resolvedPdftotext, err := exec.LookPath(pdftotextPath)
+	if err != nil {
+		return nil, fmt.Errorf("pdftotext not found: %w", err)
+	}
+
+	resolvedPdftoppm, err := exec.LookPath(pdftoppmPath)
+	if err != nil {
+		return nil, fmt.Errorf("pdftoppm not found: %w", err)
+	}
+
+	resolvedTesseract, err := exec.LookPath(tesseractPath)
+	if err != nil {
+		return nil, fmt.Errorf("tesseract not found: %w", err)
+	}
+
+	return &CommandExtractor{
+		pdftotextPath: resolvedPdftotext,
+		pdftoppmPath:  resolvedPdftoppm,
+		tesseractPath: resolvedTesseract,
+		languages:     languages,
+	}, nil
+}
+
+func (e *CommandExtractor) Extract(ctx context.Context, path string, mimeType string) (string, error) {
+	if !Supports(mimeType) {
+		return "", ErrUnsupported
+	}
+
+	if mimeType != "application/pdf" {
+		return e.ocr(ctx, path)
+	}
+
+	text, err := e.run(ctx, e.pdftotextPath, "-enc", "UTF-8", path, "-")
+	if err != nil {
+		return "", fmt.Errorf("pdftotext error: %w", err)
+	}
+
+	if textLength(text) >= minTextLength {
+		return text, nil
+	}
+
+	return e.ocrPDF(ctx, path)
+}
+
+// ocrPDF renders the pages of a scanned PDF and OCRs them in order.
+func (e *CommandExtractor) ocrPDF(ctx context.Context, path string) (string, error) {
+	pageDir, err := os.MkdirTemp(filepath.Dir(path), "ocr-")
+	if err != nil {
+		return "", fmt.Errorf("create page directory: %w", err)
+	}
+	defer os.RemoveAll(pageDir)
+
+	if _, err := e.run(ctx, e.pdftoppmPath,
+		"-r", strconv.Itoa(ocrDPI),
+		"-l", strconv.Itoa(ocrMaxPages),
+		"-gray", "-png",
+		path,
+		filepath.Join(pageDir, "page"),
+	); err != nil {
+		return "", fmt.Errorf("pdftoppm error: %w", err)
+	}
+
+	pages, err := filepath.Glob(filepath.Join(pageDir, "page-*.png"))
+	if err != nil {
+		return "", err
+	}
+
+	// pdftoppm pads page numbers to the same width, so names sort by page.
+	sort.Strings(pages)
+
+	texts := make([]string, 0, len(pages))
+	for _, page := range pages {
+		text, err := e.ocr(ctx, page)
+		if err != nil {
+			return "", err
+		}
+
+		texts = append(texts, text)
+	}
+
+	return strings.Join(texts, "\f"), nil
+}
+
+func (e *CommandExtractor) ocr(ctx context.Context, path string) (string, error) {
+	text, err := e.run(ctx, e.tesseractPath, path, "stdout", "-l", e.languages)
+	if err != nil {
+		return "", fmt.Errorf("tesseract error: %w", err)
+	}
+
+	return text, nil
+}
+
+func (e *CommandExtractor) run(ctx context.Context, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	utils.ApplySysProcAttr(cmd)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%v | %s", err, stderr.String())
+	}
+
+	return stdout.String(), nil
+}
+
+func textLength(text string) int {
+	n := 0
+	for _, r := range text {
+		if !unicode.IsSpace(r) {
+			n++
+		}
+	}
+
+	return n
+}
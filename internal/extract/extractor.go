@@ -0,0 +1,28 @@
+// Package extract reads the text of attachment files so they can be searched.
+package extract
+
+import (
+	"context"
+	"errors"
+	"slices"
+)
+
+var ErrUnsupported = errors.New("extract: unsupported file type")
+
+var supportedTypes = []string{"application/pdf", "image/jpeg", "image/png"}
+
+// Extractor returns the plain text of a local file. Implementations return
+// ErrUnsupported for types they cannot read.
+type Extractor interface {
+	Extract(ctx context.Context, path string, mimeType string) (string, error)
+}
+
+// Supports reports whether text can be extracted from files of mimeType.
+func Supports(mimeType string) bool {
+	return slices.Contains(supportedTypes, mimeType)
+}
+
+// SupportedTypes lists the MIME types Supports accepts.
+func SupportedTypes() []string {
+	return slices.Clone(supportedTypes)
+}
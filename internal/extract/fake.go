@@ -0,0 +1,29 @@
+package extract
+
+import "context"
+
+// Fake returns canned text without running any tool, for tests and for
+// environments without OCR.
+type Fake struct {
+	// Texts maps a file path to its text; other supported files get Text.
+	Texts map[string]string
+	Text  string
+	// Err, when set, is returned for every file.
+	Err error
+}
+
+func (f *Fake) Extract(ctx context.Context, path string, mimeType string) (string, error) {
+	if f.Err != nil {
+		return "", f.Err
+	}
+
+	if !Supports(mimeType) {
+		return "", ErrUnsupported
+	}
+
+	if text, ok := f.Texts[path]; ok {
+		return text, nil
+	}
+
+	return f.Text, nil
+}
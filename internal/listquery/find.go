@@ -0,0 +1,340 @@
+package listquery
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// FilterKind selects how a filter value is matched against its column.
+type FilterKind int
+
+const (
+	// Equals matches the column exactly.
+	Equals FilterKind = iota
+	// Contains matches the value anywhere in the column, ignoring case.
+	Contains
+	// ID matches an unsigned integer column; other values are rejected.
+	ID
+)
+
+type Filter struct {
+	Column string
+	Kind   FilterKind
+}
+
+// Spec describes what a list can be sorted and filtered by. Its columns are
+// written into SQL as they are and never come from the client.
+type Spec struct {
+	// Sorts maps sort names to the columns ordered by, most significant
+	// first. Only sorts on columns of the listed model support cursors.
+	Sorts        map[string][]string
+	DefaultSort  string
+	DefaultOrder string
+
+	Filters map[string]Filter
+
+	// IDColumn is the primary key column. It breaks ties between rows that
+	// sort equal, so pages neither overlap nor skip rows.
+	IDColumn string
+
+	// Preload adds the relations loaded with the rows. It is left out of the
+	// count.
+	Preload func(db *gorm.DB) *gorm.DB
+}
+
+// Result describes the rows Find returned. Total counts every row matching
+// the filters, not only the page.
+type Result struct {
+	Total      int64
+	Page       int
+	PageSize   int
+	NextCursor string
+}
+
+type cursor struct {
+	Sort   string            `json:"s"`
+	Order  string            `json:"o"`
+	Values []json.RawMessage `json:"v"`
+}
+
+// Find applies q to db, which holds the list's own conditions, and stores
+// the rows in dest. A query that is not paged returns every row.
+func Find[T any](db *gorm.DB, q Query, spec Spec, dest *[]T) (Result, error) {
+	sortName := q.Sort
+	if sortName == "" {
+		sortName = spec.DefaultSort
+	}
+
+	sortColumns, ok := spec.Sorts[sortName]
+	if !ok {
+		return Result{}, fmt.Errorf("%w: unknown sort %q", ErrInvalid, sortName)
+	}
+
+	order := q.Order
+	if order == "" {
+		order = spec.DefaultOrder
+	}
+	if order == "" {
+		order = OrderAsc
+	}
+
+	columns := append(append([]string{}, sortColumns...), spec.IDColumn)
+
+	base, err := spec.filter(db, q.Filters)
+	if err != nil {
+		return Result{}, err
+	}
+	base = base.Session(&gorm.Session{})
+
+	fetch := base
+	for _, column := range columns {
+		fetch = fetch.Order(column + " " + strings.ToUpper(order))
+	}
+	if spec.Preload != nil {
+		fetch = fetch.Scopes(spec.Preload)
+	}
+
+	if !q.Paged() {
+		if err := fetch.Find(dest).Error; err != nil {
+			return Result{}, err
+		}
+
+		return Result{Total: int64(len(*dest))}, nil
+	}
+
+	result := Result{Page: q.Page, PageSize: q.PageSize}
+	if result.PageSize == 0 {
+		result.PageSize = DefaultPageSize
+	}
+	if result.Page == 0 && q.Cursor == "" {
+		result.Page = 1
+	}
+
+	fields, fieldsErr := cursorFields(db, dest, columns)
+
+	var cursorValues []any
+	if q.Cursor != "" {
+		if fieldsErr != nil {
+			return Result{}, fmt.Errorf("%w: sort %q does not support cursor paging", ErrInvalid, sortName)
+		}
+
+		if cursorValues, err = decodeCursor(q.Cursor, sortName, order, fields); err != nil {
+			return Result{}, err
+		}
+	}
+
+	if err := base.Model(new(T)).Count(&result.Total).Error; err != nil {
+		return Result{}, err
+	}
+
+	if cursorValues != nil {
+		condition, args := after(columns, cursorValues, order == OrderDesc)
+		fetch = fetch.Where(condition, args...)
+	} else {
+		fetch = fetch.Offset((result.Page - 1) * result.PageSize)
+	}
+
+	if err := fetch.Limit(result.PageSize + 1).Find(dest).Error; err != nil {
+		return Result{}, err
+	}
+
+	if len(*dest) > result.PageSize {
+		*dest = (*dest)[:result.PageSize]
+
+		if fieldsErr == nil {
+			last := reflect.ValueOf(dest).Elem().Index(result.PageSize - 1)
+			if result.NextCursor, err = encodeCursor(sortName, order, fields, last); err != nil {
+				return Result{}, err
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func (spec Spec) filter(db *gorm.DB, filters map[string]string) (*gorm.DB, error) {
+	for name, value := range filters {
+		filter, ok := spec.Filters[name]
+		if !ok {
+			continue
+		}
+
+		switch filter.Kind {
+		case Contains:
+			db = db.Where(filter.Column+" ILIKE ?", "%"+escapeLike(value)+"%")
+		case ID:
+			id, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s must be an ID", ErrInvalid, name)
+			}
+			db = db.Where(filter.Column+" = ?", id)
+		default:
+			db = db.Where(filter.Column+" = ?", value)
+		}
+	}
+
+	return db, nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// after builds the condition matching rows that sort after the cursor
+// values. Postgres puts NULLs last in ascending order and first in
+// descending order, so a NULL key is compared explicitly. The last column is
+// the primary key, which is never NULL.
+func after(columns []string, values []any, desc bool) (string, []any) {
+	column, value := columns[0], values[0]
+
+	op := ">"
+	if desc {
+		op = "<"
+	}
+
+	if len(columns) == 1 {
+		return column + " " + op + " ?", []any{value}
+	}
+
+	rest, args := after(columns[1:], values[1:], desc)
+
+	if value == nil {
+		condition := "(" + column + " IS NULL AND " + rest + ")"
+		if desc {
+			condition = "(" + column + " IS NOT NULL OR " + condition + ")"
+		}
+		return condition, args
+	}
+
+	condition := column + " " + op + " ? OR (" + column + " = ? AND " + rest + ")"
+	if !desc {
+		condition += " OR " + column + " IS NULL"
+	}
+
+	return "(" + condition + ")", append([]any{value, value}, args...)
+}
+
+// cursorFields returns the model fields behind columns. It fails when a
+// column belongs to another table, such as one joined in to sort by.
+func cursorFields(db *gorm.DB, dest any, columns []string) ([]*schema.Field, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(dest); err != nil {
+		return nil, err
+	}
+
+	fields := make([]*schema.Field, 0, len(columns))
+	for _, column := range columns {
+		name := column
+		if table, field, ok := strings.Cut(column, "."); ok {
+			if table != stmt.Schema.Table {
+				return nil, fmt.Errorf("column %s is not on %s", column, stmt.Schema.Table)
+			}
+			name = field
+		}
+
+		field := stmt.Schema.LookUpField(name)
+		if field == nil {
+			return nil, fmt.Errorf("column %s is not on %s", column, stmt.Schema.Table)
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+func encodeCursor(sortName string, order string, fields []*schema.Field, row reflect.Value) (string, error) {
+	c := cursor{Sort: sortName, Order: order}
+
+	for _, field := range fields {
+		value, _ := field.ValueOf(context.Background(), row)
+
+		raw := json.RawMessage("null")
+		if !isNull(value) {
+			var err error
+			if raw, err = json.Marshal(value); err != nil {
+				return "", fmt.Errorf("encode cursor: %w", err)
+			}
+		}
+
+		c.Values = append(c.Values, raw)
+	}
+
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("encode cursor: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodeCursor returns the cursor's values as the types of fields. A cursor
+// is only valid for the sort and order it was issued for.
+func decodeCursor(s string, sortName string, order string, fields []*schema.Field) ([]any, error) {
+	invalid := fmt.Errorf("%w: cursor is not valid for this list", ErrInvalid)
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, invalid
+	}
+
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, invalid
+	}
+
+	if c.Sort != sortName || c.Order != order || len(c.Values) != len(fields) {
+		return nil, fmt.Errorf("%w: cursor was issued for another sort or order", ErrInvalid)
+	}
+
+	values := make([]any, len(fields))
+	for i, field := range fields {
+		if string(c.Values[i]) == "null" {
+			continue
+		}
+
+		ptr := reflect.New(field.FieldType)
+		if err := json.Unmarshal(c.Values[i], ptr.Interface()); err != nil {
+			return nil, invalid
+		}
+
+		values[i] = ptr.Elem().Interface()
+		if isNull(values[i]) {
+			values[i] = nil
+		}
+	}
+
+	if values[len(values)-1] == nil {
+		return nil, invalid
+	}
+
+	return values, nil
+}
+
+// isNull reports whether value is stored as NULL, such as a nil pointer or
+// a zero utils.DateOnly.
+func isNull(value any) bool {
+	if value == nil {
+		return true
+	}
+
+	if v := reflect.ValueOf(value); v.Kind() == reflect.Pointer && v.IsNil() {
+		return true
+	}
+
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		return err == nil && v == nil
+	}
+
+	return false
+}
@@ -0,0 +1,313 @@
+package listquery
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type testArchive struct {
+	ID          uint
+	Name        string
+	ArchiveDate *time.Time
+	Note        sql.NullString
+}
+
+// newDryRunDB returns a database that builds SQL without connecting and
+// records the SQL of every query.
+func newDryRunDB(t *testing.T) (*gorm.DB, *[]string) {
+	t.Helper()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	var statements []string
+	err = db.Callback().Query().After("gorm:query").Register("listquery:record", func(tx *gorm.DB) {
+		statements = append(statements, tx.Dialector.Explain(tx.Statement.SQL.String(), tx.Statement.Vars...))
+	})
+	if err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+
+	return db, &statements
+}
+
+var testArchiveSpec = Spec{
+	Sorts: map[string][]string{
+		"name":        {"test_archives.name"},
+		"archiveDate": {"archive_date"},
+		"joined":      {"departments.name"},
+	},
+	DefaultSort: "name",
+	Filters: map[string]Filter{
+		"name": {Column: "test_archives.name", Kind: Contains},
+		"id":   {Column: "test_archives.id", Kind: ID},
+	},
+	IDColumn: "test_archives.id",
+}
+
+func TestAfter(t *testing.T) {
+	date := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		values    []any
+		desc      bool
+		condition string
+		args      []any
+	}{
+		{
+			name:      "value ascending",
+			values:    []any{date, uint(7)},
+			condition: "(archive_date > ? OR (archive_date = ? AND id > ?) OR archive_date IS NULL)",
+			args:      []any{date, date, uint(7)},
+		},
+		{
+			name:      "value descending",
+			values:    []any{date, uint(7)},
+			desc:      true,
+			condition: "(archive_date < ? OR (archive_date = ? AND id < ?))",
+			args:      []any{date, date, uint(7)},
+		},
+		{
+			// NULLs sort last ascending, so only later NULL rows follow.
+			name:      "null ascending",
+			values:    []any{nil, uint(7)},
+			condition: "(archive_date IS NULL AND id > ?)",
+			args:      []any{uint(7)},
+		},
+		{
+			// NULLs sort first descending, so every dated row follows.
+			name:      "null descending",
+			values:    []any{nil, uint(7)},
+			desc:      true,
+			condition: "(archive_date IS NOT NULL OR (archive_date IS NULL AND id < ?))",
+			args:      []any{uint(7)},
+		},
+		{
+			name:      "id only",
+			values:    []any{uint(7)},
+			condition: "id > ?",
+			args:      []any{uint(7)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns := []string{"archive_date", "id"}[2-len(tt.values):]
+
+			condition, args := after(columns, tt.values, tt.desc)
+			if condition != tt.condition {
+				t.Errorf("condition = %s\nwant        %s", condition, tt.condition)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("args = %v, want %v", args, tt.args)
+			}
+		})
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	db, _ := newDryRunDB(t)
+	date := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		row   testArchive
+		order string
+		want  []any
+	}{
+		{
+			name:  "date",
+			row:   testArchive{ID: 7, ArchiveDate: &date, Note: sql.NullString{String: "x", Valid: true}},
+			order: OrderAsc,
+			want:  []any{&date, sql.NullString{String: "x", Valid: true}, uint(7)},
+		},
+		{
+			name:  "null ascending",
+			row:   testArchive{ID: 7},
+			order: OrderAsc,
+			want:  []any{nil, nil, uint(7)},
+		},
+		{
+			name:  "null descending",
+			row:   testArchive{ID: 7},
+			order: OrderDesc,
+			want:  []any{nil, nil, uint(7)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, err := cursorFields(db, &[]testArchive{}, []string{"archive_date", "note", "test_archives.id"})
+			if err != nil {
+				t.Fatalf("cursorFields: %v", err)
+			}
+
+			encoded, err := encodeCursor("archiveDate", tt.order, fields, reflect.ValueOf(tt.row))
+			if err != nil {
+				t.Fatalf("encodeCursor: %v", err)
+			}
+
+			got, err := decodeCursor(encoded, "archiveDate", tt.order, fields)
+			if err != nil {
+				t.Fatalf("decodeCursor: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("decodeCursor = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorRejectsTampering(t *testing.T) {
+	db, _ := newDryRunDB(t)
+
+	fields, err := cursorFields(db, &[]testArchive{}, []string{"test_archives.name", "test_archives.id"})
+	if err != nil {
+		t.Fatalf("cursorFields: %v", err)
+	}
+
+	encode := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "not base64", cursor: "%%%"},
+		{name: "padded base64", cursor: base64.URLEncoding.EncodeToString([]byte(`{"s":"name","o":"asc","v":["a",1]}`))},
+		{name: "not json", cursor: encode("garbage")},
+		{name: "other sort", cursor: encode(`{"s":"archiveDate","o":"asc","v":["a",1]}`)},
+		{name: "other order", cursor: encode(`{"s":"name","o":"desc","v":["a",1]}`)},
+		{name: "too few values", cursor: encode(`{"s":"name","o":"asc","v":[1]}`)},
+		{name: "too many values", cursor: encode(`{"s":"name","o":"asc","v":["a",1,2]}`)},
+		{name: "wrong type", cursor: encode(`{"s":"name","o":"asc","v":["a","1 OR 1=1"]}`)},
+		{name: "negative id", cursor: encode(`{"s":"name","o":"asc","v":["a",-1]}`)},
+		{name: "null id", cursor: encode(`{"s":"name","o":"asc","v":["a",null]}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeCursor(tt.cursor, "name", OrderAsc, fields); !errors.Is(err, ErrInvalid) {
+				t.Fatalf("decodeCursor error = %v, want ErrInvalid", err)
+			}
+		})
+	}
+}
+
+func TestFindRejectsInvalidQueries(t *testing.T) {
+	db, statements := newDryRunDB(t)
+
+	tests := []struct {
+		name  string
+		query Query
+	}{
+		{name: "unknown sort", query: Query{Sort: "password"}},
+		{name: "unknown sort unpaged", query: Query{Sort: "name; DROP TABLE users"}},
+		{name: "garbage cursor", query: Query{Cursor: "garbage"}},
+		{name: "cursor on a joined sort", query: Query{Sort: "joined", Cursor: "e30"}},
+		{name: "filter id not a number", query: Query{Filters: map[string]string{"id": "abc"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rows []testArchive
+			if _, err := Find(db.Model(&testArchive{}), tt.query, testArchiveSpec, &rows); !errors.Is(err, ErrInvalid) {
+				t.Fatalf("Find error = %v, want ErrInvalid", err)
+			}
+		})
+	}
+
+	if len(*statements) != 0 {
+		t.Fatalf("invalid queries ran SQL: %v", *statements)
+	}
+}
+
+func TestFindPaging(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  Query
+		result Result
+		want   string
+	}{
+		{
+			name:   "unpaged",
+			query:  Query{},
+			result: Result{},
+			want:   `SELECT * FROM "test_archives" ORDER BY test_archives.name ASC,test_archives.id ASC`,
+		},
+		{
+			name:   "default page size",
+			query:  Query{Page: 1},
+			result: Result{Page: 1, PageSize: DefaultPageSize},
+			want:   `ORDER BY test_archives.name ASC,test_archives.id ASC LIMIT 21`,
+		},
+		{
+			name:   "later page",
+			query:  Query{Page: 3, PageSize: 5, Order: OrderDesc},
+			result: Result{Page: 3, PageSize: 5},
+			want:   `ORDER BY test_archives.name DESC,test_archives.id DESC LIMIT 6 OFFSET 10`,
+		},
+		{
+			name:   "page size without page",
+			query:  Query{PageSize: MaxPageSize},
+			result: Result{Page: 1, PageSize: MaxPageSize},
+			want:   `LIMIT 201`,
+		},
+		{
+			name:   "filters",
+			query:  Query{Page: 1, Filters: map[string]string{"name": `50%_a\b`, "unknown": "x"}},
+			result: Result{Page: 1, PageSize: DefaultPageSize},
+			want:   `WHERE test_archives.name ILIKE '%50\%\_a\\b%'`,
+		},
+		{
+			name:   "cursor",
+			query:  Query{Sort: "archiveDate", Cursor: base64.RawURLEncoding.EncodeToString([]byte(`{"s":"archiveDate","o":"desc","v":[null,7]}`)), Order: OrderDesc},
+			result: Result{PageSize: DefaultPageSize},
+			want:   `WHERE (archive_date IS NOT NULL OR (archive_date IS NULL AND test_archives.id < 7)) ORDER BY archive_date DESC,test_archives.id DESC LIMIT 21`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, statements := newDryRunDB(t)
+
+			var rows []testArchive
+			result, err := Find(db.Model(&testArchive{}), tt.query, testArchiveSpec, &rows)
+			if err != nil {
+				t.Fatalf("Find: %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.result) {
+				t.Errorf("result = %+v, want %+v", result, tt.result)
+			}
+
+			if len(*statements) == 0 {
+				t.Fatal("no query ran")
+			}
+			fetch := (*statements)[len(*statements)-1]
+			if !strings.Contains(fetch, tt.want) {
+				t.Errorf("query = %s\nwant it to contain %s", fetch, tt.want)
+			}
+
+			// Paged lists also count every matching row.
+			if wantCount := tt.query.Paged(); wantCount != (len(*statements) == 2) {
+				t.Errorf("ran %d queries: %v", len(*statements), *statements)
+			}
+		})
+	}
+}
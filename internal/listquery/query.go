@@ -0,0 +1,107 @@
+// Package listquery implements the query parameters shared by list
+// endpoints: page and pageSize or an opaque cursor, sort and order, and
+// field filters.
+package listquery
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 200
+)
+
+// ErrInvalid is wrapped by every error caused by the parameters a client
+// sent, so handlers can answer them with 400.
+var ErrInvalid = errors.New("invalid list query")
+
+const (
+	paramPage     = "page"
+	paramPageSize = "pageSize"
+	paramCursor   = "cursor"
+	paramSort     = "sort"
+	paramOrder    = "order"
+
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
+
+// Query is a parsed list request. Filters holds every other non-empty
+// parameter; each list applies the ones it knows and ignores the rest.
+type Query struct {
+	Page     int
+	PageSize int
+	Cursor   string
+	Sort     string
+	Order    string
+	Filters  map[string]string
+}
+
+// Paged reports whether the client asked for a page. Without page,
+// pageSize or cursor the whole list is returned, as it was before paging.
+func (q Query) Paged() bool {
+	return q.Page > 0 || q.PageSize > 0 || q.Cursor != ""
+}
+
+// Parse reads a Query from URL query parameters.
+func Parse(values url.Values) (Query, error) {
+	q := Query{
+		Cursor:  values.Get(paramCursor),
+		Sort:    values.Get(paramSort),
+		Order:   strings.ToLower(values.Get(paramOrder)),
+		Filters: map[string]string{},
+	}
+
+	var err error
+	if q.Page, err = positiveInt(values, paramPage); err != nil {
+		return Query{}, err
+	}
+
+	if q.PageSize, err = positiveInt(values, paramPageSize); err != nil {
+		return Query{}, err
+	}
+
+	if q.PageSize > MaxPageSize {
+		return Query{}, fmt.Errorf("%w: pageSize must not exceed %d", ErrInvalid, MaxPageSize)
+	}
+
+	if q.Page > 0 && q.Cursor != "" {
+		return Query{}, fmt.Errorf("%w: page and cursor cannot be combined", ErrInvalid)
+	}
+
+	if q.Order != "" && q.Order != OrderAsc && q.Order != OrderDesc {
+		return Query{}, fmt.Errorf("%w: order must be %q or %q", ErrInvalid, OrderAsc, OrderDesc)
+	}
+
+	for key, vals := range values {
+		switch key {
+		case paramPage, paramPageSize, paramCursor, paramSort, paramOrder:
+			continue
+		}
+
+		if len(vals) > 0 && strings.TrimSpace(vals[0]) != "" {
+			q.Filters[key] = strings.TrimSpace(vals[0])
+		}
+	}
+
+	return q, nil
+}
+
+func positiveInt(values url.Values, key string) (int, error) {
+	raw := values.Get(key)
+	if raw == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%w: %s must be a positive integer", ErrInvalid, key)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,89 @@
+package listquery
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    Query
+		wantErr bool
+	}{
+		{
+			name:  "empty",
+			query: "",
+			want:  Query{Filters: map[string]string{}},
+		},
+		{
+			name:  "page and filters",
+			query: "page=2&pageSize=50&sort=name&order=DESC&name=+surat+&status=",
+			want:  Query{Page: 2, PageSize: 50, Sort: "name", Order: OrderDesc, Filters: map[string]string{"name": "surat"}},
+		},
+		{
+			name:  "largest page size",
+			query: "pageSize=200",
+			want:  Query{PageSize: MaxPageSize, Filters: map[string]string{}},
+		},
+		{
+			name:  "cursor",
+			query: "cursor=abc&pageSize=10",
+			want:  Query{PageSize: 10, Cursor: "abc", Filters: map[string]string{}},
+		},
+		{name: "page size too large", query: "pageSize=201", wantErr: true},
+		{name: "zero page", query: "page=0", wantErr: true},
+		{name: "negative page", query: "page=-1", wantErr: true},
+		{name: "zero page size", query: "pageSize=0", wantErr: true},
+		{name: "page not a number", query: "page=two", wantErr: true},
+		{name: "page overflows", query: "page=99999999999999999999", wantErr: true},
+		{name: "page with cursor", query: "page=2&cursor=abc", wantErr: true},
+		{name: "unknown order", query: "order=up", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := Parse(values)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalid) {
+					t.Fatalf("Parse(%q) error = %v, want ErrInvalid", tt.query, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.query, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Parse(%q) = %+v, want %+v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryPaged(t *testing.T) {
+	tests := []struct {
+		query Query
+		want  bool
+	}{
+		{query: Query{}, want: false},
+		{query: Query{Sort: "name", Filters: map[string]string{"name": "a"}}, want: false},
+		{query: Query{Page: 1}, want: true},
+		{query: Query{PageSize: 10}, want: true},
+		{query: Query{Cursor: "abc"}, want: true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.query.Paged(); got != tt.want {
+			t.Errorf("%+v.Paged() = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
@@ -7,11 +7,18 @@ type ArchiveAttachment struct {
 	ArchiveHdrID uint       `gorm:"column:archive_hdr_id;not null" json:"archiveHdrId"`
 	FileName     string     `gorm:"column:file_name;type:varchar(256);not null" json:"fileName"`
 	FileLocation string     `gorm:"column:file_location;type:text;not null" json:"fileLocation"`
+	MimeType     string     `gorm:"column:mime_type;type:varchar(128)" json:"mimeType"`
+	FileHash     string     `gorm:"column:file_hash;type:varchar(64)" json:"fileHash"`
+	SortOrder    int        `gorm:"column:sort_order;not null;default:0" json:"sortOrder"`
+	Rotation     int        `gorm:"column:rotation;not null;default:0" json:"rotation"`
 	Status       string     `gorm:"column:status;type:varchar(1);default:'Y'" json:"status"`
 	CreatedBy    string     `gorm:"column:created_by;type:varchar(128);not null" json:"createdBy"`
 	CreatedAt    time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
 	ModifiedBy   *string    `gorm:"column:modified_by;type:varchar(128)" json:"modifiedBy,omitempty"`
 	ModifiedAt   *time.Time `gorm:"column:modified_at;" json:"modifiedAt,omitempty"`
 
-	FileBase64 string `gorm:"-" json:"fileBase64"`
+	DownloadURL  string                `gorm:"-" json:"downloadUrl,omitempty"`
+	ThumbnailURL string                `gorm:"-" json:"thumbnailUrl,omitempty"`
+	FileBase64   string                `gorm:"-" json:"fileBase64,omitempty"`
+	Duplicates   []AttachmentDuplicate `gorm:"-" json:"duplicates,omitempty"`
 }
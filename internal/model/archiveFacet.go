@@ -0,0 +1,25 @@
+package model
+
+// ArchiveFacets counts the archives matching a search by each value of the
+// fields it can be narrowed by. Archives without a value for a field are
+// left out of that field's counts, so they may add up to less than Total.
+type ArchiveFacets struct {
+	Total                  int64             `json:"total"`
+	ArchiveTypes           []*FacetCount     `json:"archiveTypes"`
+	ArchiveCharacteristics []*FacetCount     `json:"archiveCharacteristics"`
+	Departments            []*FacetCount     `json:"departments"`
+	Years                  []*YearFacetCount `json:"years"`
+}
+
+// FacetCount is the number of matching archives referencing one record.
+type FacetCount struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// YearFacetCount is the number of matching archives dated in one year.
+type YearFacetCount struct {
+	Year  int   `json:"year"`
+	Count int64 `json:"count"`
+}
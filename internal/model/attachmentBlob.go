@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// AttachmentBlob is a stored file addressed by the SHA-256 of its content and
+// shared by every attachment with that content. RefCount is the number of
+// active attachments pointing at it; blobs that drop to zero are kept so a
+// soft-deleted attachment can still be restored.
+type AttachmentBlob struct {
+	ID         uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	Hash       string     `gorm:"column:hash;type:varchar(64);not null" json:"hash"`
+	StorageKey string     `gorm:"column:storage_key;type:text;not null" json:"storageKey"`
+	Size       int64      `gorm:"column:size;not null" json:"size"`
+	MimeType   string     `gorm:"column:mime_type;type:varchar(128)" json:"mimeType"`
+	RefCount   int        `gorm:"column:ref_count;not null;default:0" json:"refCount"`
+	CreatedBy  string     `gorm:"column:created_by;type:varchar(128);not null" json:"createdBy"`
+	CreatedAt  time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	ModifiedBy *string    `gorm:"column:modified_by;type:varchar(128)" json:"modifiedBy,omitempty"`
+	ModifiedAt *time.Time `gorm:"column:modified_at;" json:"modifiedAt,omitempty"`
+}
+
+// AttachmentDuplicate points at an existing attachment with the same content
+// as an uploaded file.
+type AttachmentDuplicate struct {
+	AttachmentID  uint   `json:"attachmentId"`
+	ArchiveHdrID  uint   `json:"archiveHdrId"`
+	ArchiveNumber string `json:"archiveNumber"`
+	ArchiveName   string `json:"archiveName"`
+}
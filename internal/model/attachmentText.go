@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// Attachment text extraction states.
+const (
+	AttachmentTextCompleted = "completed"
+	AttachmentTextFailed    = "failed"
+)
+
+// AttachmentText holds the text extracted from an attachment's file. The
+// table also has a generated search_vector column that full-text search
+// matches against; it is maintained by Postgres and not mapped here.
+type AttachmentText struct {
+	ID                  uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	ArchiveAttachmentID uint       `gorm:"column:archive_attachment_id;not null" json:"archiveAttachmentId"`
+	State               string     `gorm:"column:state;type:varchar(16);not null" json:"state"`
+	Content             string     `gorm:"column:content;type:text" json:"content,omitempty"`
+	Message             string     `gorm:"column:message;type:text" json:"message,omitempty"`
+	Status              string     `gorm:"column:status;type:varchar(1);default:'Y'" json:"status"`
+	CreatedBy           string     `gorm:"column:created_by;type:varchar(128);not null" json:"createdBy"`
+	CreatedAt           time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	ModifiedBy          *string    `gorm:"column:modified_by;type:varchar(128)" json:"modifiedBy,omitempty"`
+	ModifiedAt          *time.Time `gorm:"column:modified_at;" json:"modifiedAt,omitempty"`
+}
+
+// AttachmentTextMatch is an attachment whose text matched a full-text search.
+// Snippet is HTML-escaped text with the matching words wrapped in <mark>.
+type AttachmentTextMatch struct {
+	ArchiveHdrID        uint    `json:"archiveHdrId"`
+	ArchiveAttachmentID uint    `json:"archiveAttachmentId"`
+	FileName            string  `json:"fileName"`
+	Snippet             string  `json:"snippet"`
+	Rank                float64 `json:"rank"`
+}
+
+// ArchiveTextSearchResult is an archive with the attachments that matched a
+// full-text search, best match first.
+type ArchiveTextSearchResult struct {
+	Archive ArchiveHdr             `json:"archive"`
+	Rank    float64                `json:"rank"`
+	Matches []*AttachmentTextMatch `json:"matches"`
+}
@@ -1,9 +1,21 @@
 package request
 
-import "time"
+import "github.com/mugnialby/arsip-backend/internal/utils"
 
+// AdvancedSearchRequest filters archives. Empty fields are ignored; the rest
+// are combined with Operator, "and" (the default) or "or". The date range
+// counts as one filter, and role access always applies.
 type AdvancedSearchRequest struct {
-	ArchiveName   *string    `json:"archiveName"`
-	ArchiveTypeID *uint      `json:"archiveTypeId"`
-	ArchiveDate   *time.Time `json:"archiveDate"`
+	ArchiveName   *string `json:"archiveName"`
+	ArchiveNumber *string `json:"archiveNumber"`
+	// ArchiveNumberMatch is "prefix" (the default) or "exact".
+	ArchiveNumberMatch      string                 `json:"archiveNumberMatch" binding:"omitempty,oneof=prefix exact"`
+	ArchiveTypeID           *uint                  `json:"archiveTypeId"`
+	ArchiveCharacteristicID *uint                  `json:"archiveCharacteristicId"`
+	DepartmentID            *uint                  `json:"departmentId"`
+	ArchiveDate             utils.NullableDateOnly `json:"archiveDate"`
+	ArchiveDateFrom         utils.NullableDateOnly `json:"archiveDateFrom"`
+	ArchiveDateTo           utils.NullableDateOnly `json:"archiveDateTo"`
+	CreatedBy               *string                `json:"createdBy"`
+	Operator                string                 `json:"operator" binding:"omitempty,oneof=and or"`
 }
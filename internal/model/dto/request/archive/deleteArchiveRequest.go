@@ -2,5 +2,5 @@ package request
 
 type DeleteArchiveRequest struct {
 	ID          uint   `json:"id"`
-	SubmittedBy string `json:"submittedBy"`
+	SubmittedBy string `json:"-"`
 }
@@ -1,6 +0,0 @@
-package request
-
-type GetArchiveByDataRequest struct {
-	DepartmentID uint `json:"departmentId" binding:"required"`
-	RoleID       uint `json:"roleId" binding:"required"`
-}
@@ -15,5 +15,4 @@ type NewArchiveRequest struct {
 	DepartmentID            uint                                            `json:"departmentId" binding:"required"`
 	ListArchiveAttachments  []attachmentRequest.NewArchiveAttachmentRequest `json:"listArchiveAttachments"`
 	RoleAccess              []roleAccessRequest.NewArchiveRoleAccessRequest `json:"roleAccess"`
-	SubmittedBy             string                                          `json:"submittedBy"`
 }
@@ -15,5 +15,4 @@ type UpdateArchiveRequest struct {
 	ArchiveTypeID           uint                                            `json:"archiveTypeId" binding:"required"`
 	ListArchiveAttachments  []attachmentRequest.NewArchiveAttachmentRequest `json:"listArchiveAttachments"`
 	RoleAccess              []roleAccessRequest.NewArchiveRoleAccessRequest `json:"roleAccess"`
-	SubmittedBy             string                                          `json:"submittedBy"`
 }
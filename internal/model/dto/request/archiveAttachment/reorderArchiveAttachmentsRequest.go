@@ -0,0 +1,14 @@
+package request
+
+// ReorderArchiveAttachmentsRequest lists every active attachment of an archive
+// in the order it should appear in the merged PDF.
+type ReorderArchiveAttachmentsRequest struct {
+	Attachments []ArchiveAttachmentOrder `json:"attachments" binding:"required,min=1,dive"`
+}
+
+// ArchiveAttachmentOrder places one attachment. Rotation is clockwise in
+// degrees; when omitted the current rotation is kept.
+type ArchiveAttachmentOrder struct {
+	ID       uint `json:"id" binding:"required"`
+	Rotation *int `json:"rotation" binding:"omitempty,oneof=0 90 180 270"`
+}
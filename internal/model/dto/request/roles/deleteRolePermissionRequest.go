@@ -0,0 +1,6 @@
+package request
+
+type DeleteRolePermissionRequest struct {
+	ID          uint   `json:"id"`
+	SubmittedBy string `json:"-"`
+}
@@ -0,0 +1,5 @@
+package request
+
+type NewRolePermissionRequest struct {
+	Permission string `json:"permission" binding:"required"`
+}
@@ -0,0 +1,8 @@
+package request
+
+type NewUploadRequest struct {
+	ArchiveHdrID uint   `json:"archiveHdrId" binding:"required"`
+	FileName     string `json:"fileName" binding:"required"`
+	TotalSize    int64  `json:"totalSize" binding:"required,gt=0"`
+	Checksum     string `json:"checksum"`
+}
@@ -6,5 +6,4 @@ type NewUserRequest struct {
 	FullName     string `json:"fullName" binding:"required"`
 	DepartmentID uint   `json:"departmentId" binding:"required"`
 	RoleID       uint   `json:"roleId" binding:"required"`
-	SubmittedBy  string `json:"submittedBy"`
 }
@@ -6,5 +6,5 @@ type UpdateUserRequest struct {
 	FullName     string `json:"fullName" binding:"required"`
 	DepartmentID uint   `json:"departmentId" binding:"required"`
 	RoleID       uint   `json:"roleId" binding:"required"`
-	SubmittedBy  string `json:"submittedBy"`
+	Password     string `json:"password"`
 }
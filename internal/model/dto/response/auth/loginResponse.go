@@ -0,0 +1,12 @@
+package response
+
+import "github.com/mugnialby/arsip-backend/internal/model"
+
+type LoginResponse struct {
+	AccessToken      string      `json:"accessToken"`
+	TokenType        string      `json:"tokenType"`
+	ExpiresIn        int64       `json:"expiresIn"`
+	RefreshToken     string      `json:"refreshToken"`
+	RefreshExpiresIn int64       `json:"refreshExpiresIn"`
+	User             *model.User `json:"user"`
+}
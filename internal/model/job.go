@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// Job kinds.
+const (
+	JobKindPDFBuild = "pdf_build"
+)
+
+// Job states.
+const (
+	JobStateQueued    = "queued"
+	JobStateRunning   = "running"
+	JobStateCompleted = "completed"
+	JobStateFailed    = "failed"
+)
+
+// Job is a unit of background work. Queued rows are claimed by the worker
+// pool, so pending jobs survive a restart.
+type Job struct {
+	ID           uint       `gorm:"primaryKey;autoIncrement" json:"-"`
+	JobID        string     `gorm:"column:job_id;type:varchar(36);not null" json:"jobId"`
+	Kind         string     `gorm:"column:kind;type:varchar(32);not null" json:"kind"`
+	ArchiveHdrID uint       `gorm:"column:archive_hdr_id;not null" json:"archiveHdrId"`
+	State        string     `gorm:"column:state;type:varchar(16);not null" json:"state"`
+	Progress     int        `gorm:"column:progress;not null;default:0" json:"progress"`
+	Message      string     `gorm:"column:message;type:text" json:"message,omitempty"`
+	ResultURL    string     `gorm:"column:result_url;type:text" json:"resultUrl,omitempty"`
+	LeaseID      *string    `gorm:"column:lease_id;type:varchar(36)" json:"-"`
+	StartedAt    *time.Time `gorm:"column:started_at" json:"startedAt,omitempty"`
+	FinishedAt   *time.Time `gorm:"column:finished_at" json:"finishedAt,omitempty"`
+	Status       string     `gorm:"column:status;type:varchar(1);default:'Y'" json:"status"`
+	CreatedBy    string     `gorm:"column:created_by;type:varchar(128);not null" json:"createdBy"`
+	CreatedAt    time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	ModifiedBy   *string    `gorm:"column:modified_by;type:varchar(128)" json:"modifiedBy,omitempty"`
+	ModifiedAt   *time.Time `gorm:"column:modified_at;" json:"modifiedAt,omitempty"`
+}
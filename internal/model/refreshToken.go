@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+type RefreshToken struct {
+	ID           uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID       uint       `gorm:"column:user_id;not null" json:"userId"`
+	TokenHash    string     `gorm:"column:token_hash;type:varchar(64);not null" json:"-"`
+	ExpiresAt    time.Time  `gorm:"column:expires_at;not null" json:"expiresAt"`
+	RevokedAt    *time.Time `gorm:"column:revoked_at" json:"revokedAt,omitempty"`
+	ReplacedByID *uint      `gorm:"column:replaced_by_id" json:"replacedById,omitempty"`
+	Status       string     `gorm:"column:status;type:varchar(1);default:'Y'" json:"status"`
+	CreatedBy    string     `gorm:"column:created_by;type:varchar(128);not null" json:"createdBy"`
+	CreatedAt    time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	ModifiedBy   *string    `gorm:"column:modified_by;type:varchar(128)" json:"modifiedBy,omitempty"`
+	ModifiedAt   *time.Time `gorm:"column:modified_at;" json:"modifiedAt,omitempty"`
+}
@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+// Permission codes that can be granted to a role through role_permissions.
+const (
+	PermissionMasterRead       = "master:read"
+	PermissionMasterWrite      = "master:write"
+	PermissionUsersWrite       = "users:write"
+	PermissionRolesWrite       = "roles:write"
+	PermissionArchivesWrite    = "archives:write"
+	PermissionArchivesDelete   = "archives:delete"
+	PermissionAttachmentsScrub = "attachments:scrub"
+)
+
+// Permissions lists every permission code known to the application.
+var Permissions = []string{
+	PermissionMasterRead,
+	PermissionMasterWrite,
+	PermissionUsersWrite,
+	PermissionRolesWrite,
+	PermissionArchivesWrite,
+	PermissionArchivesDelete,
+	PermissionAttachmentsScrub,
+}
+
+func IsKnownPermission(permission string) bool {
+	for _, p := range Permissions {
+		if p == permission {
+			return true
+		}
+	}
+
+	return false
+}
+
+type RolePermission struct {
+	ID         uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	RoleID     uint       `gorm:"column:role_id;not null" json:"roleId"`
+	Permission string     `gorm:"column:permission;type:varchar(64);not null" json:"permission"`
+	Status     string     `gorm:"column:status;type:varchar(1);default:'Y'" json:"status"`
+	CreatedBy  string     `gorm:"column:created_by;type:varchar(128);not null" json:"createdBy"`
+	CreatedAt  time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	ModifiedBy *string    `gorm:"column:modified_by;type:varchar(128)" json:"modifiedBy,omitempty"`
+	ModifiedAt *time.Time `gorm:"column:modified_at;" json:"modifiedAt,omitempty"`
+}
@@ -0,0 +1,59 @@
+package model
+
+import "time"
+
+// Scrub report states.
+const (
+	ScrubStateRunning   = "running"
+	ScrubStateCompleted = "completed"
+	ScrubStateFailed    = "failed"
+)
+
+// Kinds of problems found by a scrub.
+const (
+	ScrubIssueMissing  = "missing"
+	ScrubIssueCorrupt  = "corrupt"
+	ScrubIssueOrphaned = "orphaned"
+	ScrubIssueError    = "error"
+
+	// ScrubIssueUnreferenced is a blob only soft-deleted attachments point
+	// at. It is kept for restores, but its space can be reclaimed.
+	ScrubIssueUnreferenced = "unreferenced"
+)
+
+// ScrubReport summarises one integrity check of the stored attachment files.
+type ScrubReport struct {
+	ID           uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	State        string     `gorm:"column:state;type:varchar(16);not null" json:"state"`
+	StartedAt    time.Time  `gorm:"column:started_at;not null" json:"startedAt"`
+	FinishedAt   *time.Time `gorm:"column:finished_at" json:"finishedAt,omitempty"`
+	Checked      int        `gorm:"column:checked;not null;default:0" json:"checked"`
+	Unverified   int        `gorm:"column:unverified;not null;default:0" json:"unverified"`
+	Missing      int        `gorm:"column:missing;not null;default:0" json:"missing"`
+	Corrupt      int        `gorm:"column:corrupt;not null;default:0" json:"corrupt"`
+	Orphaned     int        `gorm:"column:orphaned;not null;default:0" json:"orphaned"`
+	Unreferenced int        `gorm:"column:unreferenced;not null;default:0" json:"unreferenced"`
+	Errors       int        `gorm:"column:errors;not null;default:0" json:"errors"`
+	Message      string     `gorm:"column:message;type:text" json:"message,omitempty"`
+	Status       string     `gorm:"column:status;type:varchar(1);default:'Y'" json:"status"`
+	CreatedBy    string     `gorm:"column:created_by;type:varchar(128);not null" json:"createdBy"`
+	CreatedAt    time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	ModifiedBy   *string    `gorm:"column:modified_by;type:varchar(128)" json:"modifiedBy,omitempty"`
+	ModifiedAt   *time.Time `gorm:"column:modified_at;" json:"modifiedAt,omitempty"`
+
+	// Read-only relations
+	Issues []*ScrubIssue `gorm:"foreignKey:ScrubReportID;->" json:"issues,omitempty"`
+}
+
+// ScrubIssue is a single problem found by a scrub. Orphaned files have no
+// attachment.
+type ScrubIssue struct {
+	ID                  uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	ScrubReportID       uint      `gorm:"column:scrub_report_id;not null" json:"scrubReportId"`
+	Kind                string    `gorm:"column:kind;type:varchar(16);not null" json:"kind"`
+	ArchiveAttachmentID *uint     `gorm:"column:archive_attachment_id" json:"archiveAttachmentId,omitempty"`
+	ArchiveHdrID        *uint     `gorm:"column:archive_hdr_id" json:"archiveHdrId,omitempty"`
+	StorageKey          string    `gorm:"column:storage_key;type:text;not null" json:"storageKey"`
+	Detail              string    `gorm:"column:detail;type:text" json:"detail,omitempty"`
+	CreatedAt           time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+}
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// UploadSession tracks a chunked upload whose data is being collected under
+// storage/tmp until it is completed and attached to an archive.
+type UploadSession struct {
+	ID                  uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	UploadID            string     `gorm:"column:upload_id;type:varchar(36);not null" json:"uploadId"`
+	ArchiveHdrID        uint       `gorm:"column:archive_hdr_id;not null" json:"archiveHdrId"`
+	FileName            string     `gorm:"column:file_name;type:varchar(256);not null" json:"fileName"`
+	TotalSize           int64      `gorm:"column:total_size;not null" json:"totalSize"`
+	Offset              int64      `gorm:"column:upload_offset;not null;default:0" json:"offset"`
+	Checksum            string     `gorm:"column:checksum;type:varchar(64)" json:"checksum,omitempty"`
+	TempLocation        string     `gorm:"column:temp_location;type:text;not null" json:"-"`
+	ExpiresAt           time.Time  `gorm:"column:expires_at;not null" json:"expiresAt"`
+	CompletedAt         *time.Time `gorm:"column:completed_at" json:"completedAt,omitempty"`
+	ArchiveAttachmentID *uint      `gorm:"column:archive_attachment_id" json:"archiveAttachmentId,omitempty"`
+	Status              string     `gorm:"column:status;type:varchar(1);default:'Y'" json:"status"`
+	CreatedBy           string     `gorm:"column:created_by;type:varchar(128);not null" json:"createdBy"`
+	CreatedAt           time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	ModifiedBy          *string    `gorm:"column:modified_by;type:varchar(128)" json:"modifiedBy,omitempty"`
+	ModifiedAt          *time.Time `gorm:"column:modified_at;" json:"modifiedAt,omitempty"`
+}
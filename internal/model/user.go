@@ -5,7 +5,7 @@ import "time"
 type User struct {
 	ID           uint       `gorm:"primaryKey;autoIncrement" json:"id"`
 	UserId       string     `gorm:"column:user_id;type:varchar(256);not null" json:"userId"`
-	PasswordHash string     `gorm:"column:password_hash;type:varchar(255)" json:"passwordHash"`
+	PasswordHash string     `gorm:"column:password_hash;type:varchar(255)" json:"-"`
 	FullName     string     `gorm:"column:full_name;type:varchar(128)" json:"fullName"`
 	DepartmentID uint       `gorm:"column:department_id" json:"departmentId"`
 	RoleID       uint       `gorm:"column:role_id" json:"roleId"`
@@ -0,0 +1,94 @@
+package pdf
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+
+	"github.com/google/uuid"
+	"github.com/mugnialby/arsip-backend/internal/utils"
+)
+
+// ExternalMerger shells out to ImageMagick for image conversion and to
+// poppler's pdfunite for merging. Page rotation is still done in-process.
+type ExternalMerger struct {
+	magickPath   string
+	pdfunitePath string
+}
+
+// NewExternalMerger resolves both tools up front so a missing binary is
+// reported at startup. Empty paths fall back to the tools on PATH, or to the
+// usual poppler install location on Windows.
+func NewExternalMerger(magickPath string, pdfunitePath string) (*ExternalMerger, error) {
+	if magickPath == "" {
+		magickPath = "magick"
+	}
+
+	if pdfunitePath == "" {
+		pdfunitePath = "pdfunite"
+		if runtime.GOOS == "windows" {
+			pdfunitePath = `C:\poppler\Library\bin\pdfunite.exe`
+		}
+	}
+
+	resolvedMagick, err := exec.LookPath(magickPath)
+	if err != nil {
+		return nil, fmt.Errorf("imagemagick not found: %w", err)
+	}
+
+	resolvedPdfunite, err := exec.LookPath(pdfunitePath)
+	if err != nil {
+		return nil, fmt.Errorf("pdfunite not found: %w", err)
+	}
+
+	return &ExternalMerger{
+		magickPath:   resolvedMagick,
+		pdfunitePath: resolvedPdfunite,
+	}, nil
+}
+
+func (m *ExternalMerger) Merge(ctx context.Context, inputs []Input, output string) error {
+	runs, err := segments(inputs)
+	if err != nil {
+		return err
+	}
+
+	parts := make([]string, 0, len(runs))
+	for _, run := range runs {
+		if !run[0].IsImage() {
+			parts = append(parts, run[0].Path)
+			continue
+		}
+
+		imagePDF := filepath.Join(filepath.Dir(output), uuid.NewString()+".pdf")
+		defer os.Remove(imagePDF)
+
+		args := append(imagePaths(run), "pdf:"+imagePDF)
+		if err := m.run(ctx, m.magickPath, args...); err != nil {
+			return fmt.Errorf("imagemagick error: %w", err)
+		}
+
+		parts = append(parts, imagePDF)
+	}
+
+	if err := m.run(ctx, m.pdfunitePath, append(parts, output)...); err != nil {
+		return fmt.Errorf("pdfunite error: %w", err)
+	}
+
+	return rotate(ctx, inputs, output)
+}
+
+func (m *ExternalMerger) run(ctx context.Context, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	utils.ApplySysProcAttr(cmd)
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%v | %s", err, string(out))
+	}
+
+	return nil
+}
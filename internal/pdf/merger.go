@@ -0,0 +1,98 @@
+package pdf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+var ErrNoInputs = errors.New("pdf: no files to merge")
+
+// Input is one file to merge. Images become one page each; PDFs are copied
+// page by page. Rotation turns the input's pages clockwise by a multiple of
+// 90 degrees.
+type Input struct {
+	Path     string
+	MimeType string
+	Rotation int
+}
+
+// IsImage reports whether the input is converted to a page rather than
+// copied.
+func (in Input) IsImage() bool {
+	switch in.MimeType {
+	case "image/jpeg", "image/png":
+		return true
+	}
+
+	return false
+}
+
+// Merger writes the inputs, in order, into a single PDF at output.
+type Merger interface {
+	Merge(ctx context.Context, inputs []Input, output string) error
+}
+
+// Validate checks that path holds a non-empty file starting with a PDF
+// header, so broken inputs are reported by name instead of failing the
+// merge opaquely.
+func Validate(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return fmt.Errorf("file is empty or truncated: %s", path)
+	}
+
+	if string(header) != "%PDF-" {
+		return fmt.Errorf("invalid PDF header: %s", path)
+	}
+
+	return nil
+}
+
+// segments splits inputs into runs of consecutive images and single PDFs,
+// validating each PDF on the way. Both drivers convert an image run into one
+// PDF before merging.
+func segments(inputs []Input) ([][]Input, error) {
+	if len(inputs) == 0 {
+		return nil, ErrNoInputs
+	}
+
+	var result [][]Input
+	for _, input := range inputs {
+		if input.IsImage() {
+			last := len(result) - 1
+			if last >= 0 && result[last][0].IsImage() {
+				result[last] = append(result[last], input)
+				continue
+			}
+
+			result = append(result, []Input{input})
+			continue
+		}
+
+		if err := Validate(input.Path); err != nil {
+			return nil, err
+		}
+
+		result = append(result, []Input{input})
+	}
+
+	return result, nil
+}
+
+func imagePaths(inputs []Input) []string {
+	paths := make([]string, 0, len(inputs))
+	for _, input := range inputs {
+		paths = append(paths, input.Path)
+	}
+
+	return paths
+}
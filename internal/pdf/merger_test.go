@@ -0,0 +1,223 @@
+package pdf
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+)
+
+// page is what the tests check of a merged page: its media box, which for
+// an imported image follows the image's size, and its rotation.
+type page struct {
+	Width, Height int
+	Rotation      int
+}
+
+// writeImage writes a blank image of the given size, encoded by its
+// extension.
+func writeImage(t *testing.T, name string, width, height int) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	if filepath.Ext(name) == ".png" {
+		err = png.Encode(f, img)
+	} else {
+		err = jpeg.Encode(f, img, nil)
+	}
+	if err != nil {
+		t.Fatalf("encode %s: %v", name, err)
+	}
+
+	return path
+}
+
+// writePDF writes a PDF with one page per size.
+func writePDF(t *testing.T, name string, sizes ...[2]int) string {
+	t.Helper()
+
+	images := make([]string, 0, len(sizes))
+	for _, size := range sizes {
+		images = append(images, writeImage(t, "page.png", size[0], size[1]))
+	}
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := api.ImportImagesFile(images, path, nil, config()); err != nil {
+		t.Fatalf("create %s: %v", name, err)
+	}
+
+	return path
+}
+
+func readPages(t *testing.T, path string) []page {
+	t.Helper()
+
+	ctx, err := api.ReadContextFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+
+	boundaries, err := ctx.PageBoundaries(nil)
+	if err != nil {
+		t.Fatalf("page boundaries: %v", err)
+	}
+
+	pages := make([]page, 0, len(boundaries))
+	for _, boundary := range boundaries {
+		dim := boundary.MediaBox().Dimensions()
+		pages = append(pages, page{Width: int(dim.Width), Height: int(dim.Height), Rotation: boundary.Rot})
+	}
+
+	return pages
+}
+
+func TestNativeMergerMerge(t *testing.T) {
+	inputs := []Input{
+		{Path: writeImage(t, "a.png", 100, 200), MimeType: "image/png"},
+		{Path: writeImage(t, "b.jpg", 120, 80), MimeType: "image/jpeg"},
+		{Path: writePDF(t, "c.pdf", [2]int{300, 300}, [2]int{310, 320}), MimeType: "application/pdf"},
+		{Path: writeImage(t, "d.png", 50, 60), MimeType: "image/png"},
+		{Path: writePDF(t, "e.pdf", [2]int{400, 410}), MimeType: "application/pdf"},
+	}
+	output := filepath.Join(t.TempDir(), "merged.pdf")
+
+	if err := NewNativeMerger().Merge(context.Background(), inputs, output); err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+
+	want := []page{
+		{Width: 100, Height: 200},
+		{Width: 120, Height: 80},
+		{Width: 300, Height: 300},
+		{Width: 310, Height: 320},
+		{Width: 50, Height: 60},
+		{Width: 400, Height: 410},
+	}
+	if got := readPages(t, output); !reflect.DeepEqual(got, want) {
+		t.Fatalf("pages = %v\nwant    %v", got, want)
+	}
+
+	// Images converted for the merge are removed afterwards.
+	entries, err := os.ReadDir(filepath.Dir(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("output directory holds %d files, want only the merged PDF", len(entries))
+	}
+}
+
+func TestNativeMergerMergeNoInputs(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "merged.pdf")
+
+	if err := NewNativeMerger().Merge(context.Background(), nil, output); !errors.Is(err, ErrNoInputs) {
+		t.Fatalf("Merge: err = %v, want ErrNoInputs", err)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	scan := writeImage(t, "a.png", 100, 200)
+	document := writePDF(t, "b.pdf", [2]int{300, 300}, [2]int{310, 320})
+
+	inputs := []Input{
+		{Path: scan, MimeType: "image/png", Rotation: 90},
+		{Path: document, MimeType: "application/pdf", Rotation: -90},
+		{Path: scan, MimeType: "image/png"},
+		{Path: scan, MimeType: "image/png", Rotation: 450},
+		{Path: document, MimeType: "application/pdf", Rotation: 180},
+	}
+
+	unrotated := make([]Input, len(inputs))
+	for i, input := range inputs {
+		unrotated[i] = Input{Path: input.Path, MimeType: input.MimeType}
+	}
+	output := filepath.Join(t.TempDir(), "merged.pdf")
+	if err := NewNativeMerger().Merge(context.Background(), unrotated, output); err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+
+	if err := rotate(context.Background(), inputs, output); err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+
+	var got []int
+	for _, p := range readPages(t, output) {
+		got = append(got, p.Rotation)
+	}
+
+	// A PDF's rotation covers every one of its pages.
+	want := []int{90, 270, 270, 0, 90, 180, 180}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("rotations = %v, want %v", got, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{name: "pdf", path: writePDF(t, "valid.pdf", [2]int{100, 100})},
+		{name: "empty", path: write("empty.pdf", ""), wantErr: true},
+		{name: "truncated header", path: write("truncated.pdf", "%PD"), wantErr: true},
+		{name: "not a pdf", path: write("image.pdf", "\x89PNG\r\n\x1a\n"), wantErr: true},
+		{name: "missing", path: filepath.Join(dir, "missing.pdf"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.path)
+			if tt.wantErr && err == nil {
+				t.Fatalf("Validate(%s) = nil, want an error", tt.name)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+		})
+	}
+}
+
+func TestNativeMergerMergeRejectsInvalidPDF(t *testing.T) {
+	dir := t.TempDir()
+	broken := filepath.Join(dir, "broken.pdf")
+	if err := os.WriteFile(broken, []byte("<html>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	inputs := []Input{
+		{Path: writeImage(t, "a.png", 100, 200), MimeType: "image/png"},
+		{Path: broken, MimeType: "application/pdf"},
+	}
+	output := filepath.Join(dir, "merged.pdf")
+
+	if err := NewNativeMerger().Merge(context.Background(), inputs, output); err == nil {
+		t.Fatal("Merge of an invalid PDF succeeded")
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Fatalf("output written for an invalid input: %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package pdf
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+)
+
+// NativeMerger converts and merges in-process with pdfcpu, so no external
+// tools need to be installed.
+type NativeMerger struct{}
+
+func NewNativeMerger() *NativeMerger {
+	return &NativeMerger{}
+}
+
+func (m *NativeMerger) Merge(ctx context.Context, inputs []Input, output string) error {
+	runs, err := segments(inputs)
+	if err != nil {
+		return err
+	}
+
+	parts := make([]string, 0, len(runs))
+	for _, run := range runs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if !run[0].IsImage() {
+			parts = append(parts, run[0].Path)
+			continue
+		}
+
+		imagePDF := filepath.Join(filepath.Dir(output), uuid.NewString()+".pdf")
+		defer os.Remove(imagePDF)
+
+		// A nil import config sizes each page to its image, like magick does.
+		if err := api.ImportImagesFile(imagePaths(run), imagePDF, nil, config()); err != nil {
+			return fmt.Errorf("convert images to pdf: %w", err)
+		}
+
+		parts = append(parts, imagePDF)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := api.MergeCreateFile(parts, output, false, config()); err != nil {
+		return fmt.Errorf("merge pdfs: %w", err)
+	}
+
+	return rotate(ctx, inputs, output)
+}
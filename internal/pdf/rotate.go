@@ -0,0 +1,91 @@
+package pdf
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+var disableConfigDirOnce sync.Once
+
+// config returns a pdfcpu configuration for one call. pdfcpu records the
+// running command in the configuration, so it cannot be shared between
+// concurrent calls.
+func config() *model.Configuration {
+	// pdfcpu would otherwise create a config directory in the user's home.
+	disableConfigDirOnce.Do(api.DisableConfigDir)
+
+	conf := model.NewDefaultConfiguration()
+	conf.ValidationMode = model.ValidationRelaxed
+
+	return conf
+}
+
+// normalizeRotation maps a rotation in degrees to 0, 90, 180 or 270.
+func normalizeRotation(rotation int) int {
+	rotation %= 360
+	if rotation < 0 {
+		rotation += 360
+	}
+
+	return rotation / 90 * 90
+}
+
+// rotate turns the pages of output that came from rotated inputs. Images
+// become one page each; PDFs contribute all of their pages. Neither external
+// tool can rotate PDF pages without re-rendering them, so both drivers use
+// pdfcpu for this step.
+func rotate(ctx context.Context, inputs []Input, output string) error {
+	pagesByRotation := make(map[int][]string)
+
+	page := 1
+	for _, input := range inputs {
+		pageCount := 1
+		if !input.IsImage() {
+			count, err := pageCountFile(input.Path)
+			if err != nil {
+				return fmt.Errorf("count pages of %s: %w", input.Path, err)
+			}
+			pageCount = count
+		}
+
+		if rotation := normalizeRotation(input.Rotation); rotation != 0 && pageCount > 0 {
+			pagesByRotation[rotation] = append(pagesByRotation[rotation], fmt.Sprintf("%d-%d", page, page+pageCount-1))
+		}
+
+		page += pageCount
+	}
+
+	rotations := make([]int, 0, len(pagesByRotation))
+	for rotation := range pagesByRotation {
+		rotations = append(rotations, rotation)
+	}
+	sort.Ints(rotations)
+
+	for _, rotation := range rotations {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := api.RotateFile(output, "", rotation, pagesByRotation[rotation], config()); err != nil {
+			return fmt.Errorf("rotate pages: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func pageCountFile(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	return api.PageCount(f, config())
+}
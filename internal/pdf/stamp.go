@@ -0,0 +1,99 @@
+package pdf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+)
+
+var ErrEmptyStamp = errors.New("pdf: stamp has no watermark or cover page")
+
+const (
+	coverTitleStyle = "fontname:Helvetica-Bold, points:18, position:tl, offset:60 -70, scalefactor:1 abs, rotation:0, aligntext:l, fillcolor:#000000, opacity:1"
+	coverBodyStyle  = "fontname:Helvetica, points:12, position:tl, offset:60 -120, scalefactor:1 abs, rotation:0, aligntext:l, fillcolor:#000000, opacity:1"
+	watermarkStyle  = "fontname:Helvetica-Bold, scalefactor:0.8 rel, diagonal:1, aligntext:c, fillcolor:#808080, opacity:0.3"
+)
+
+// Stamp describes what is added to a PDF before it is handed out.
+type Stamp struct {
+	// Watermark is drawn diagonally across every page, the cover included,
+	// when not empty.
+	Watermark string
+
+	// CoverTitle and CoverLines are printed on a blank A4 page inserted before
+	// the first page. No cover is added when both are empty.
+	CoverTitle string
+	CoverLines []CoverLine
+}
+
+// CoverLine is one "label: value" line of a cover page.
+type CoverLine struct {
+	Label string
+	Value string
+}
+
+// IsEmpty reports whether the stamp would leave a PDF unchanged.
+func (s Stamp) IsEmpty() bool {
+	return s.Watermark == "" && !s.hasCover()
+}
+
+func (s Stamp) hasCover() bool {
+	return s.CoverTitle != "" || len(s.CoverLines) > 0
+}
+
+// Apply writes input to output with the cover page and watermark of stamp.
+// input is left untouched, so it may be a cached file.
+func Apply(ctx context.Context, input string, output string, stamp Stamp) error {
+	if stamp.IsEmpty() {
+		return ErrEmptyStamp
+	}
+
+	source := input
+
+	if stamp.hasCover() {
+		if err := api.InsertPagesFile(source, output, []string{"1"}, true, pdfcpu.DefaultPageConfiguration(), config()); err != nil {
+			return fmt.Errorf("insert cover page: %w", err)
+		}
+		source = output
+
+		if stamp.CoverTitle != "" {
+			if err := api.AddTextWatermarksFile(output, "", []string{"1"}, true, stampText(stamp.CoverTitle), coverTitleStyle, config()); err != nil {
+				return fmt.Errorf("write cover title: %w", err)
+			}
+		}
+
+		if len(stamp.CoverLines) > 0 {
+			lines := make([]string, 0, len(stamp.CoverLines))
+			for _, line := range stamp.CoverLines {
+				lines = append(lines, stampText(line.Label)+": "+stampText(line.Value))
+			}
+
+			if err := api.AddTextWatermarksFile(output, "", []string{"1"}, true, strings.Join(lines, "\n"), coverBodyStyle, config()); err != nil {
+				return fmt.Errorf("write cover lines: %w", err)
+			}
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if stamp.Watermark != "" {
+		if err := api.AddTextWatermarksFile(source, output, nil, true, stampText(stamp.Watermark), watermarkStyle, config()); err != nil {
+			return fmt.Errorf("add watermark: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// stampText keeps values on one line; pdfcpu splits text on newlines,
+// including escaped ones.
+func stampText(s string) string {
+	s = strings.ReplaceAll(s, `\n`, " ")
+	return strings.Join(strings.Fields(s), " ")
+}
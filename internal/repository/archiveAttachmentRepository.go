@@ -1,19 +1,26 @@
 package repository
 
 import (
-	"errors"
 	"time"
 
+	"github.com/mugnialby/arsip-backend/internal/listquery"
 	"github.com/mugnialby/arsip-backend/internal/model"
 	"gorm.io/gorm"
 )
 
 type ArchiveAttachmentRepository interface {
-	FindAll() ([]model.ArchiveAttachment, error)
+	FindAll(query listquery.Query) ([]model.ArchiveAttachment, listquery.Result, error)
 	FindByID(id uint) (*model.ArchiveAttachment, error)
+	FindActiveByArchiveID(archiveID uint) ([]model.ArchiveAttachment, error)
+	FindActiveInBatches(batchSize int, fn func(archiveAttachments []model.ArchiveAttachment) error) error
+	FindAllFileLocations() ([]string, error)
+	FindDuplicates(hash string, excludeID uint, access *ArchiveAccess) ([]model.AttachmentDuplicate, error)
+	NextSortOrder(archiveID uint) (int, error)
 	Create(archiveAttachment *model.ArchiveAttachment) error
 	Update(archiveAttachment *model.ArchiveAttachment) error
+	UpdateOrder(id uint, sortOrder int, rotation int, submittedBy string) error
 	DeleteArchiveAttachmentByArchiveID(archiveID uint, submittedBy string) error
+	WithTx(tx *gorm.DB) ArchiveAttachmentRepository
 }
 
 type archiveAttachmentRepository struct {
@@ -24,11 +31,29 @@ func NewArchiveAttachmentRepository(db *gorm.DB) ArchiveAttachmentRepository {
 	return &archiveAttachmentRepository{db: db}
 }
 
-func (r *archiveAttachmentRepository) FindAll() ([]model.ArchiveAttachment, error) {
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *archiveAttachmentRepository) WithTx(tx *gorm.DB) ArchiveAttachmentRepository {
+	return &archiveAttachmentRepository{db: tx}
+}
+
+var archiveAttachmentListSpec = listquery.Spec{
+	Sorts: map[string][]string{
+		"id":        {},
+		"createdAt": {"created_at"},
+	},
+	DefaultSort: "id",
+	Filters: map[string]listquery.Filter{
+		"archiveHdrId": {Column: "archive_hdr_id", Kind: listquery.ID},
+		"fileName":     {Column: "file_name", Kind: listquery.Contains},
+		"mimeType":     {Column: "mime_type", Kind: listquery.Equals},
+	},
+	IDColumn: "id",
+}
+
+func (r *archiveAttachmentRepository) FindAll(query listquery.Query) ([]model.ArchiveAttachment, listquery.Result, error) {
 	var books []model.ArchiveAttachment
-	err := r.db.Where("status = ?", "Y").
-		Find(&books).Error
-	return books, err
+	result, err := listquery.Find(r.db.Where("status = ?", "Y"), query, archiveAttachmentListSpec, &books)
+	return books, result, err
 }
 
 func (r *archiveAttachmentRepository) FindByID(id uint) (*model.ArchiveAttachment, error) {
@@ -37,6 +62,64 @@ func (r *archiveAttachmentRepository) FindByID(id uint) (*model.ArchiveAttachmen
 	return &archiveAttachment, err
 }
 
+func (r *archiveAttachmentRepository) FindActiveByArchiveID(archiveID uint) ([]model.ArchiveAttachment, error) {
+	var archiveAttachments []model.ArchiveAttachment
+	err := r.db.Where("archive_hdr_id = ?", archiveID).
+		Where("status = ?", "Y").
+		Order("sort_order, id").
+		Find(&archiveAttachments).Error
+	return archiveAttachments, err
+}
+
+// NextSortOrder returns the position after the last active attachment of the
+// archive, so new attachments are appended.
+func (r *archiveAttachmentRepository) NextSortOrder(archiveID uint) (int, error) {
+	var sortOrder int
+	err := r.db.Model(&model.ArchiveAttachment{}).
+		Where("archive_hdr_id = ?", archiveID).
+		Where("status = ?", "Y").
+		Select("COALESCE(MAX(sort_order), 0) + 1").
+		Scan(&sortOrder).Error
+	return sortOrder, err
+}
+
+// FindActiveInBatches calls fn with successive batches of active attachments
+// so callers can walk the whole table without loading it at once.
+func (r *archiveAttachmentRepository) FindActiveInBatches(batchSize int, fn func(archiveAttachments []model.ArchiveAttachment) error) error {
+	var archiveAttachments []model.ArchiveAttachment
+	return r.db.Where("status = ?", "Y").
+		FindInBatches(&archiveAttachments, batchSize, func(tx *gorm.DB, batch int) error {
+			return fn(archiveAttachments)
+		}).Error
+}
+
+// FindAllFileLocations returns the storage keys referenced by any attachment,
+// including soft-deleted ones whose files are kept.
+func (r *archiveAttachmentRepository) FindAllFileLocations() ([]string, error) {
+	var fileLocations []string
+	err := r.db.Model(&model.ArchiveAttachment{}).
+		Distinct().
+		Pluck("file_location", &fileLocations).Error
+	return fileLocations, err
+}
+
+// FindDuplicates lists active attachments other than excludeID whose content
+// hash matches, limited to archives visible through access.
+func (r *archiveAttachmentRepository) FindDuplicates(hash string, excludeID uint, access *ArchiveAccess) ([]model.AttachmentDuplicate, error) {
+	var duplicates []model.AttachmentDuplicate
+	err := r.db.Table("archive_attachments").
+		Select("archive_attachments.id AS attachment_id, archive_hdr.id AS archive_hdr_id, archive_hdr.archive_number, archive_hdr.archive_name").
+		Joins("JOIN archive_hdr ON archive_hdr.id = archive_attachments.archive_hdr_id").
+		Scopes(scopeArchiveAccess(access)).
+		Where("archive_attachments.file_hash = ?", hash).
+		Where("archive_attachments.id <> ?", excludeID).
+		Where("archive_attachments.status = ?", "Y").
+		Where("archive_hdr.status = ?", "Y").
+		Order("archive_hdr.id ASC").
+		Scan(&duplicates).Error
+	return duplicates, err
+}
+
 func (r *archiveAttachmentRepository) Create(archiveAttachment *model.ArchiveAttachment) error {
 	return r.db.Create(archiveAttachment).Error
 }
@@ -45,23 +128,24 @@ func (r *archiveAttachmentRepository) Update(archiveAttachment *model.ArchiveAtt
 	return r.db.Save(archiveAttachment).Error
 }
 
+func (r *archiveAttachmentRepository) UpdateOrder(id uint, sortOrder int, rotation int, submittedBy string) error {
+	return r.db.Model(&model.ArchiveAttachment{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"sort_order":  sortOrder,
+			"rotation":    rotation,
+			"modified_by": submittedBy,
+			"modified_at": time.Now(),
+		}).Error
+}
+
 func (r *archiveAttachmentRepository) DeleteArchiveAttachmentByArchiveID(archiveID uint, submittedBy string) error {
-	result := r.db.Model(&model.ArchiveAttachment{}).
+	return r.db.Model(&model.ArchiveAttachment{}).
 		Where("archive_hdr_id = ?", archiveID).
 		Where("status = ?", "Y").
 		Updates(map[string]interface{}{
 			"status":      "N",
 			"modified_by": submittedBy,
 			"modified_at": time.Now(),
-		})
-
-	if result.Error != nil {
-		return result.Error
-	}
-
-	if result.RowsAffected == 0 {
-		return errors.New("no data found to delete")
-	}
-
-	return nil
+		}).Error
 }
@@ -4,13 +4,14 @@ import (
 	"errors"
 	"time"
 
+	"github.com/mugnialby/arsip-backend/internal/listquery"
 	"github.com/mugnialby/arsip-backend/internal/model"
 	request "github.com/mugnialby/arsip-backend/internal/model/dto/request/archiveCharacteristic"
 	"gorm.io/gorm"
 )
 
 type ArchiveCharacteristicRepository interface {
-	FindAll() ([]model.ArchiveCharacteristic, error)
+	FindAll(query listquery.Query) ([]model.ArchiveCharacteristic, listquery.Result, error)
 	FindByID(id uint) (*model.ArchiveCharacteristic, error)
 	Create(archiveCharacteristic *model.ArchiveCharacteristic) error
 	Update(archiveCharacteristic *model.ArchiveCharacteristic) error
@@ -25,12 +26,22 @@ func NewArchiveCharacteristicRepository(db *gorm.DB) ArchiveCharacteristicReposi
 	return &archiveCharacteristicRepository{db: db}
 }
 
-func (r *archiveCharacteristicRepository) FindAll() ([]model.ArchiveCharacteristic, error) {
+var archiveCharacteristicListSpec = listquery.Spec{
+	Sorts: map[string][]string{
+		"archiveCharacteristicName": {"archive_characteristic_name"},
+		"createdAt":                 {"created_at"},
+	},
+	DefaultSort: "archiveCharacteristicName",
+	Filters: map[string]listquery.Filter{
+		"archiveCharacteristicName": {Column: "archive_characteristic_name", Kind: listquery.Contains},
+	},
+	IDColumn: "id",
+}
+
+func (r *archiveCharacteristicRepository) FindAll(query listquery.Query) ([]model.ArchiveCharacteristic, listquery.Result, error) {
 	var archiveCharacteristics []model.ArchiveCharacteristic
-	err := r.db.Where("status = ?", "Y").
-		Order("archive_characteristic_name asc").
-		Find(&archiveCharacteristics).Error
-	return archiveCharacteristics, err
+	result, err := listquery.Find(r.db.Where("status = ?", "Y"), query, archiveCharacteristicListSpec, &archiveCharacteristics)
+	return archiveCharacteristics, result, err
 }
 
 func (r *archiveCharacteristicRepository) FindByID(id uint) (*model.ArchiveCharacteristic, error) {
@@ -2,22 +2,56 @@ package repository
 
 import (
 	"errors"
+	"strings"
 	"time"
 
+	"github.com/mugnialby/arsip-backend/internal/listquery"
 	"github.com/mugnialby/arsip-backend/internal/model"
 	request "github.com/mugnialby/arsip-backend/internal/model/dto/request/archive"
 	"gorm.io/gorm"
 )
 
 type ArchiveRepository interface {
-	FindAll() ([]model.ArchiveHdr, error)
+	FindAll(access *ArchiveAccess, query listquery.Query) ([]model.ArchiveHdr, listquery.Result, error)
 	FindByID(id uint) (*model.ArchiveHdr, error)
+	FindByIDs(ids []uint) ([]model.ArchiveHdr, error)
 	Create(archive *model.ArchiveHdr) error
 	Update(archive *model.ArchiveHdr) error
 	Delete(deleteArchiveRequest *request.DeleteArchiveRequest) error
-	FindArchiveByQuery(query string) ([]model.ArchiveHdr, error)
-	FindArchiveByAdvanceQuery(advancedSearchRequest request.AdvancedSearchRequest) ([]model.ArchiveHdr, error)
-	GetAllArchivesByData(getArchiveByDataRequest request.GetArchiveByDataRequest) ([]model.ArchiveHdr, error)
+	FindArchiveByQuery(query string, access *ArchiveAccess) ([]model.ArchiveHdr, error)
+	FindArchiveByAdvanceQuery(advancedSearchRequest request.AdvancedSearchRequest, access *ArchiveAccess) ([]model.ArchiveHdr, error)
+	FindArchiveFacets(advancedSearchRequest request.AdvancedSearchRequest, access *ArchiveAccess) (*model.ArchiveFacets, error)
+	GetAllArchivesByData(access *ArchiveAccess, query listquery.Query) ([]model.ArchiveHdr, listquery.Result, error)
+	WithTx(tx *gorm.DB) ArchiveRepository
+}
+
+// ArchiveAccess restricts archive queries to archives granted to a role
+// within a department through an active archive_role_access row. A nil
+// *ArchiveAccess leaves the query unrestricted.
+type ArchiveAccess struct {
+	RoleID       uint
+	DepartmentID uint
+}
+
+func scopeArchiveAccess(access *ArchiveAccess) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if access == nil {
+			return db
+		}
+
+		return db.Where(
+			`EXISTS (
+				SELECT 1 FROM archive_role_access
+				WHERE archive_role_access.archive_hdr_id = archive_hdr.id
+				AND archive_role_access.role_id = ?
+				AND archive_role_access.department_id = ?
+				AND archive_role_access.status = ?
+			)`,
+			access.RoleID,
+			access.DepartmentID,
+			"Y",
+		)
+	}
 }
 
 type archiveRepository struct {
@@ -28,19 +62,69 @@ func NewArchiveRepository(db *gorm.DB) ArchiveRepository {
 	return &archiveRepository{db: db}
 }
 
-func (r *archiveRepository) FindAll() ([]model.ArchiveHdr, error) {
+// preloadActiveAttachments loads active attachments in the order set by the
+// clerk.
+func preloadActiveAttachments(db *gorm.DB) *gorm.DB {
+	return db.Where("status = ?", "Y").Order("sort_order, id")
+}
+
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *archiveRepository) WithTx(tx *gorm.DB) ArchiveRepository {
+	return &archiveRepository{db: tx}
+}
+
+var (
+	archiveListSorts = map[string][]string{
+		"archiveDate":   {"archive_hdr.archive_date", "archive_hdr.archive_name"},
+		"archiveName":   {"archive_hdr.archive_name"},
+		"archiveNumber": {"archive_hdr.archive_number"},
+		"createdAt":     {"archive_hdr.created_at"},
+	}
+
+	archiveListFilters = map[string]listquery.Filter{
+		"archiveName":             {Column: "archive_hdr.archive_name", Kind: listquery.Contains},
+		"archiveNumber":           {Column: "archive_hdr.archive_number", Kind: listquery.Contains},
+		"archiveTypeId":           {Column: "archive_hdr.archive_type_id", Kind: listquery.ID},
+		"archiveCharacteristicId": {Column: "archive_hdr.archive_characteristic_id", Kind: listquery.ID},
+		"departmentId":            {Column: "archive_hdr.department_id", Kind: listquery.ID},
+	}
+
+	archiveListSpec = listquery.Spec{
+		Sorts:       archiveListSorts,
+		DefaultSort: "archiveDate",
+		Filters:     archiveListFilters,
+		IDColumn:    "archive_hdr.id",
+		Preload: func(db *gorm.DB) *gorm.DB {
+			return db.Preload("ArchiveAttachments", preloadActiveAttachments).
+				Preload("ArchiveCharacteristic").
+				Preload("ArchiveType").
+				Preload("ArchiveRoleAccess", "status = ?", "Y")
+		},
+	}
+
+	// archiveByDataListSpec lists the newest archives first.
+	archiveByDataListSpec = listquery.Spec{
+		Sorts:        archiveListSorts,
+		DefaultSort:  "archiveDate",
+		DefaultOrder: listquery.OrderDesc,
+		Filters:      archiveListFilters,
+		IDColumn:     "archive_hdr.id",
+		Preload: func(db *gorm.DB) *gorm.DB {
+			return db.Preload("ArchiveAttachments", preloadActiveAttachments).
+				Preload("ArchiveCharacteristic").
+				Preload("ArchiveType")
+		},
+	}
+)
+
+func (r *archiveRepository) FindAll(access *ArchiveAccess, query listquery.Query) ([]model.ArchiveHdr, listquery.Result, error) {
 	var archives []model.ArchiveHdr
 
-	err := r.db.Model(&model.ArchiveHdr{}).
-		Where("status = ?", "Y").
-		Preload("ArchiveAttachments", "status = ?", "Y").
-		Preload("ArchiveCharacteristic").
-		Preload("ArchiveType").
-		Preload("ArchiveRoleAccess", "status = ?", "Y").
-		Order("archive_date ASC").
-		Find(&archives).Error
+	result, err := listquery.Find(r.db.Model(&model.ArchiveHdr{}).
+		Scopes(scopeArchiveAccess(access)).
+		Where("status = ?", "Y"), query, archiveListSpec, &archives)
 
-	return archives, err
+	return archives, result, err
 }
 
 func (r *archiveRepository) FindByID(id uint) (*model.ArchiveHdr, error) {
@@ -48,9 +132,10 @@ func (r *archiveRepository) FindByID(id uint) (*model.ArchiveHdr, error) {
 
 	err := r.db.Model(&model.ArchiveHdr{}).
 		Where("id = ? AND status = ?", id, "Y").
-		Preload("ArchiveAttachments", "status = ?", "Y").
+		Preload("ArchiveAttachments", preloadActiveAttachments).
 		Preload("ArchiveCharacteristic").
 		Preload("ArchiveType").
+		Preload("Department").
 		Preload("ArchiveRoleAccess", "status = ?", "Y").
 		Preload("ArchiveRoleAccess.Role").
 		First(&archive).Error
@@ -58,6 +143,20 @@ func (r *archiveRepository) FindByID(id uint) (*model.ArchiveHdr, error) {
 	return &archive, err
 }
 
+func (r *archiveRepository) FindByIDs(ids []uint) ([]model.ArchiveHdr, error) {
+	var archives []model.ArchiveHdr
+
+	err := r.db.Model(&model.ArchiveHdr{}).
+		Where("id IN ? AND status = ?", ids, "Y").
+		Preload("ArchiveAttachments", preloadActiveAttachments).
+		Preload("ArchiveCharacteristic").
+		Preload("ArchiveType").
+		Preload("ArchiveRoleAccess", "status = ?", "Y").
+		Find(&archives).Error
+
+	return archives, err
+}
+
 func (r *archiveRepository) Create(archive *model.ArchiveHdr) error {
 	return r.db.Create(archive).Error
 }
@@ -88,34 +187,46 @@ func (r *archiveRepository) Delete(deleteArchiveRequest *request.DeleteArchiveRe
 	return nil
 }
 
-func (r *archiveRepository) FindArchiveByQuery(queryStr string) ([]model.ArchiveHdr, error) {
+// FindArchiveByQuery matches queryStr against archive names and numbers and
+// tolerates typos: besides archives containing it, those with a word
+// sequence whose trigrams are similar enough to it match too (pg_trgm's <%
+// operator, see pg_trgm.word_similarity_threshold). Archives containing it
+// come first, then the rest by similarity.
+func (r *archiveRepository) FindArchiveByQuery(queryStr string, access *ArchiveAccess) ([]model.ArchiveHdr, error) {
 	var archives []model.ArchiveHdr
 
+	pattern := "%" + escapeLike(queryStr) + "%"
+
 	err := r.db.Model(&model.ArchiveHdr{}).
-		Where("status = ?", "Y").
-		Where("upper(archive_name) LIKE upper(?)", "%"+queryStr+"%").
-		Preload("ArchiveAttachments", "status = ?", "Y").
+		Select(
+			`archive_hdr.*,
+			(archive_hdr.archive_name ILIKE ? OR archive_hdr.archive_number ILIKE ?) AS contains_query,
+			GREATEST(word_similarity(?, archive_hdr.archive_name), word_similarity(?, archive_hdr.archive_number)) AS score`,
+			pattern, pattern, queryStr, queryStr,
+		).
+		Scopes(scopeArchiveAccess(access)).
+		Where("archive_hdr.status = ?", "Y").
+		Where(
+			`(archive_hdr.archive_name ILIKE ? OR archive_hdr.archive_number ILIKE ?
+			OR ? <% archive_hdr.archive_name OR ? <% archive_hdr.archive_number)`,
+			pattern, pattern, queryStr, queryStr,
+		).
+		Preload("ArchiveAttachments", preloadActiveAttachments).
 		Preload("ArchiveCharacteristic").
 		Preload("ArchiveType").
 		Preload("ArchiveRoleAccess", "status = ?", "Y").
-		Order("archive_name ASC").
+		Order("contains_query DESC, score DESC, archive_hdr.archive_name ASC").
 		Find(&archives).Error
 
 	return archives, err
 }
 
-func (r *archiveRepository) FindArchiveByAdvanceQuery(req request.AdvancedSearchRequest) ([]model.ArchiveHdr, error) {
+func (r *archiveRepository) FindArchiveByAdvanceQuery(req request.AdvancedSearchRequest, access *ArchiveAccess) ([]model.ArchiveHdr, error) {
 	var archives []model.ArchiveHdr
 
-	q := r.db.Model(&model.ArchiveHdr{}).
-		Where("status = ?", "Y")
-
-	if req.ArchiveName != nil && *req.ArchiveName != "" {
-		q = q.Where("upper(archive_name) LIKE upper(?)", "%"+*req.ArchiveName+"%")
-	}
-
-	err := q.
-		Preload("ArchiveAttachments", "status = ?", "Y").
+	err := r.db.Model(&model.ArchiveHdr{}).
+		Scopes(scopeAdvancedSearch(req, access)).
+		Preload("ArchiveAttachments", preloadActiveAttachments).
 		Preload("ArchiveCharacteristic").
 		Preload("ArchiveType").
 		Preload("ArchiveRoleAccess", "status = ?", "Y").
@@ -125,28 +236,152 @@ func (r *archiveRepository) FindArchiveByAdvanceQuery(req request.AdvancedSearch
 	return archives, err
 }
 
-func (r *archiveRepository) GetAllArchivesByData(getArchiveByDataRequest request.GetArchiveByDataRequest) ([]model.ArchiveHdr, error) {
+// FindArchiveFacets counts the archives FindArchiveByAdvanceQuery would
+// return by type, characteristic, department and year, most common first.
+func (r *archiveRepository) FindArchiveFacets(req request.AdvancedSearchRequest, access *ArchiveAccess) (*model.ArchiveFacets, error) {
+	facets := &model.ArchiveFacets{}
+
+	search := r.db.Table("archive_hdr").
+		Scopes(scopeAdvancedSearch(req, access)).
+		Session(&gorm.Session{})
+
+	if err := search.Count(&facets.Total).Error; err != nil {
+		return nil, err
+	}
+
+	err := search.
+		Select("archive_hdr.archive_type_id AS id, archive_types.archive_type_name AS name, COUNT(*) AS count").
+		Joins("LEFT JOIN archive_types ON archive_types.id = archive_hdr.archive_type_id").
+		Where("archive_hdr.archive_type_id IS NOT NULL").
+		Group("archive_hdr.archive_type_id, archive_types.archive_type_name").
+		Order("count DESC, name ASC").
+		Scan(&facets.ArchiveTypes).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = search.
+		Select("archive_hdr.archive_characteristic_id AS id, archive_characteristics.archive_characteristic_name AS name, COUNT(*) AS count").
+		Joins("LEFT JOIN archive_characteristics ON archive_characteristics.id = archive_hdr.archive_characteristic_id").
+		Where("archive_hdr.archive_characteristic_id IS NOT NULL").
+		Group("archive_hdr.archive_characteristic_id, archive_characteristics.archive_characteristic_name").
+		Order("count DESC, name ASC").
+		Scan(&facets.ArchiveCharacteristics).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = search.
+		Select("archive_hdr.department_id AS id, departments.department_name AS name, COUNT(*) AS count").
+		Joins("LEFT JOIN departments ON departments.id = archive_hdr.department_id").
+		Where("archive_hdr.department_id IS NOT NULL").
+		Group("archive_hdr.department_id, departments.department_name").
+		Order("count DESC, name ASC").
+		Scan(&facets.Departments).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = search.
+		Select("EXTRACT(YEAR FROM archive_hdr.archive_date)::int AS year, COUNT(*) AS count").
+		Where("archive_hdr.archive_date IS NOT NULL").
+		Group("year").
+		Order("year DESC").
+		Scan(&facets.Years).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return facets, nil
+}
+
+// scopeAdvancedSearch restricts a query on archive_hdr to the active
+// archives the caller can access that match req.
+func scopeAdvancedSearch(req request.AdvancedSearchRequest, access *ArchiveAccess) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Scopes(scopeArchiveAccess(access)).
+			Where("archive_hdr.status = ?", "Y")
+
+		if conditions, args := advancedSearchConditions(req); len(conditions) > 0 {
+			operator := " AND "
+			if req.Operator == "or" {
+				operator = " OR "
+			}
+
+			db = db.Where("("+strings.Join(conditions, operator)+")", args...)
+		}
+
+		return db
+	}
+}
+
+// advancedSearchConditions returns one SQL condition per filter set in req,
+// with their arguments in order.
+func advancedSearchConditions(req request.AdvancedSearchRequest) ([]string, []any) {
+	var conditions []string
+	var args []any
+
+	add := func(condition string, conditionArgs ...any) {
+		conditions = append(conditions, condition)
+		args = append(args, conditionArgs...)
+	}
+
+	if req.ArchiveName != nil && *req.ArchiveName != "" {
+		add("upper(archive_hdr.archive_name) LIKE upper(?)", "%"+escapeLike(*req.ArchiveName)+"%")
+	}
+
+	if req.ArchiveNumber != nil && *req.ArchiveNumber != "" {
+		if req.ArchiveNumberMatch == "exact" {
+			add("upper(archive_hdr.archive_number) = upper(?)", *req.ArchiveNumber)
+		} else {
+			add("upper(archive_hdr.archive_number) LIKE upper(?)", escapeLike(*req.ArchiveNumber)+"%")
+		}
+	}
+
+	if req.ArchiveTypeID != nil {
+		add("archive_hdr.archive_type_id = ?", *req.ArchiveTypeID)
+	}
+
+	if req.ArchiveCharacteristicID != nil {
+		add("archive_hdr.archive_characteristic_id = ?", *req.ArchiveCharacteristicID)
+	}
+
+	if req.DepartmentID != nil {
+		add("archive_hdr.department_id = ?", *req.DepartmentID)
+	}
+
+	if req.ArchiveDate.Valid {
+		add("archive_hdr.archive_date = ?", req.ArchiveDate)
+	}
+
+	switch {
+	case req.ArchiveDateFrom.Valid && req.ArchiveDateTo.Valid:
+		add("archive_hdr.archive_date BETWEEN ? AND ?", req.ArchiveDateFrom, req.ArchiveDateTo)
+	case req.ArchiveDateFrom.Valid:
+		add("archive_hdr.archive_date >= ?", req.ArchiveDateFrom)
+	case req.ArchiveDateTo.Valid:
+		add("archive_hdr.archive_date <= ?", req.ArchiveDateTo)
+	}
+
+	if req.CreatedBy != nil && *req.CreatedBy != "" {
+		add("upper(archive_hdr.created_by) = upper(?)", *req.CreatedBy)
+	}
+
+	return conditions, args
+}
+
+// escapeLike escapes the LIKE wildcards in s so it matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func (r *archiveRepository) GetAllArchivesByData(access *ArchiveAccess, query listquery.Query) ([]model.ArchiveHdr, listquery.Result, error) {
 	var archives []model.ArchiveHdr
 
-	err := r.db.
+	result, err := listquery.Find(r.db.
 		Model(&model.ArchiveHdr{}).
-		Joins(
-			`INNER JOIN archive_role_access
-				ON archive_role_access.archive_hdr_id = archive_hdr.id
-				AND archive_role_access.role_id = ?
-				AND archive_role_access.department_id = ?
-			`,
-			getArchiveByDataRequest.RoleID,
-			getArchiveByDataRequest.DepartmentID,
-		).
-		Where("archive_role_access.status = ?", "Y").
-		Where("archive_hdr.status = ?", "Y").
-		Preload("ArchiveAttachments", "status = ?", "Y").
-		Preload("ArchiveCharacteristic").
-		Preload("ArchiveType").
-		Order("archive_date DESC").
-		Order("archive_name ASC").
-		Find(&archives).Error
+		Scopes(scopeArchiveAccess(access)).
+		Where("archive_hdr.status = ?", "Y"), query, archiveByDataListSpec, &archives)
 
-	return archives, err
+	return archives, result, err
 }
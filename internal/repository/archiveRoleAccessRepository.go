@@ -4,18 +4,21 @@ import (
 	"errors"
 	"time"
 
+	"github.com/mugnialby/arsip-backend/internal/listquery"
 	"github.com/mugnialby/arsip-backend/internal/model"
 	request "github.com/mugnialby/arsip-backend/internal/model/dto/request/archiveRoleAccess"
 	"gorm.io/gorm"
 )
 
 type ArchiveRoleAccessRepository interface {
-	FindAll() ([]model.ArchiveRoleAccess, error)
+	FindAll(query listquery.Query) ([]model.ArchiveRoleAccess, listquery.Result, error)
 	FindByID(id uint) (*model.ArchiveRoleAccess, error)
 	Create(archiveRoleAccess *model.ArchiveRoleAccess) error
 	Update(archiveRoleAccess *model.ArchiveRoleAccess) error
 	Delete(deleteArchiveRoleAccessRequest *request.DeleteArchiveRoleAccessRequest) error
 	DeleteArchiveRoleAccessByArchiveID(archiveID uint, submittedBy string) error
+	HasActiveAccess(archiveID uint, roleID uint, departmentID uint) (bool, error)
+	WithTx(tx *gorm.DB) ArchiveRoleAccessRepository
 }
 
 type archiveRoleAccessRepository struct {
@@ -26,11 +29,29 @@ func NewArchiveRoleAccessRepository(db *gorm.DB) ArchiveRoleAccessRepository {
 	return &archiveRoleAccessRepository{db: db}
 }
 
-func (r *archiveRoleAccessRepository) FindAll() ([]model.ArchiveRoleAccess, error) {
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *archiveRoleAccessRepository) WithTx(tx *gorm.DB) ArchiveRoleAccessRepository {
+	return &archiveRoleAccessRepository{db: tx}
+}
+
+var archiveRoleAccessListSpec = listquery.Spec{
+	Sorts: map[string][]string{
+		"id":        {},
+		"createdAt": {"created_at"},
+	},
+	DefaultSort: "id",
+	Filters: map[string]listquery.Filter{
+		"archiveHdrId": {Column: "archive_hdr_id", Kind: listquery.ID},
+		"departmentId": {Column: "department_id", Kind: listquery.ID},
+		"roleId":       {Column: "role_id", Kind: listquery.ID},
+	},
+	IDColumn: "id",
+}
+
+func (r *archiveRoleAccessRepository) FindAll(query listquery.Query) ([]model.ArchiveRoleAccess, listquery.Result, error) {
 	var books []model.ArchiveRoleAccess
-	err := r.db.Where("status = ?", "Y").
-		Find(&books).Error
-	return books, err
+	result, err := listquery.Find(r.db.Where("status = ?", "Y"), query, archiveRoleAccessListSpec, &books)
+	return books, result, err
 }
 
 func (r *archiveRoleAccessRepository) FindByID(id uint) (*model.ArchiveRoleAccess, error) {
@@ -68,22 +89,23 @@ func (r *archiveRoleAccessRepository) Delete(deleteArchiveRoleAccessRequest *req
 }
 
 func (r *archiveRoleAccessRepository) DeleteArchiveRoleAccessByArchiveID(archiveID uint, submittedBy string) error {
-	result := r.db.Model(&model.ArchiveRoleAccess{}).
+	return r.db.Model(&model.ArchiveRoleAccess{}).
 		Where("archive_hdr_id = ?", archiveID).
 		Where("status = ?", "Y").
 		Updates(map[string]interface{}{
 			"status":      "N",
 			"modified_by": submittedBy,
 			"modified_at": time.Now(),
-		})
-
-	if result.Error != nil {
-		return result.Error
-	}
-
-	if result.RowsAffected == 0 {
-		return errors.New("no data found to delete")
-	}
+		}).Error
+}
 
-	return nil
+func (r *archiveRoleAccessRepository) HasActiveAccess(archiveID uint, roleID uint, departmentID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.ArchiveRoleAccess{}).
+		Where("archive_hdr_id = ?", archiveID).
+		Where("role_id = ?", roleID).
+		Where("department_id = ?", departmentID).
+		Where("status = ?", "Y").
+		Count(&count).Error
+	return count > 0, err
 }
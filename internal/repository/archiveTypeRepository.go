@@ -4,13 +4,14 @@ import (
 	"errors"
 	"time"
 
+	"github.com/mugnialby/arsip-backend/internal/listquery"
 	"github.com/mugnialby/arsip-backend/internal/model"
 	request "github.com/mugnialby/arsip-backend/internal/model/dto/request/archiveType"
 	"gorm.io/gorm"
 )
 
 type ArchiveTypeRepository interface {
-	FindAll() ([]model.ArchiveType, error)
+	FindAll(query listquery.Query) ([]model.ArchiveType, listquery.Result, error)
 	FindByID(id uint) (*model.ArchiveType, error)
 	Create(archiveType *model.ArchiveType) error
 	Delete(deleteArchiveTypeRequest *request.DeleteArchiveTypeRequest) error
@@ -25,12 +26,22 @@ func NewArchiveTypeRepository(db *gorm.DB) ArchiveTypeRepository {
 	return &archiveTypeRepository{db: db}
 }
 
-func (r *archiveTypeRepository) FindAll() ([]model.ArchiveType, error) {
+var archiveTypeListSpec = listquery.Spec{
+	Sorts: map[string][]string{
+		"archiveTypeName": {"archive_type_name"},
+		"createdAt":       {"created_at"},
+	},
+	DefaultSort: "archiveTypeName",
+	Filters: map[string]listquery.Filter{
+		"archiveTypeName": {Column: "archive_type_name", Kind: listquery.Contains},
+	},
+	IDColumn: "id",
+}
+
+func (r *archiveTypeRepository) FindAll(query listquery.Query) ([]model.ArchiveType, listquery.Result, error) {
 	var archiveTypes []model.ArchiveType
-	err := r.db.Where("status = ?", "Y").
-		Order("archive_type_name asc").
-		Find(&archiveTypes).Error
-	return archiveTypes, err
+	result, err := listquery.Find(r.db.Where("status = ?", "Y"), query, archiveTypeListSpec, &archiveTypes)
+	return archiveTypes, result, err
 }
 
 func (r *archiveTypeRepository) FindByID(id uint) (*model.ArchiveType, error) {
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/mugnialby/arsip-backend/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AttachmentBlobRepository interface {
+	WithTx(tx *gorm.DB) AttachmentBlobRepository
+	FindByHash(hash string) (*model.AttachmentBlob, error)
+	FindAllStorageKeys() ([]string, error)
+	FindUnreferenced() ([]model.AttachmentBlob, error)
+	Acquire(blob *model.AttachmentBlob) (bool, error)
+	Release(hash string, submittedBy string) error
+}
+
+type attachmentBlobRepository struct {
+	db *gorm.DB
+}
+
+func NewAttachmentBlobRepository(db *gorm.DB) AttachmentBlobRepository {
+	return &attachmentBlobRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *attachmentBlobRepository) WithTx(tx *gorm.DB) AttachmentBlobRepository {
+	return &attachmentBlobRepository{db: tx}
+}
+
+func (r *attachmentBlobRepository) FindByHash(hash string) (*model.AttachmentBlob, error) {
+	var blob model.AttachmentBlob
+	err := r.db.Where("hash = ?", hash).
+		First(&blob).Error
+	return &blob, err
+}
+
+func (r *attachmentBlobRepository) FindAllStorageKeys() ([]string, error) {
+	var storageKeys []string
+	err := r.db.Model(&model.AttachmentBlob{}).
+		Pluck("storage_key", &storageKeys).Error
+	return storageKeys, err
+}
+
+// FindUnreferenced returns the blobs no active attachment points at.
+func (r *attachmentBlobRepository) FindUnreferenced() ([]model.AttachmentBlob, error) {
+	var blobs []model.AttachmentBlob
+	err := r.db.Where("ref_count = ?", 0).
+		Order("id").
+		Find(&blobs).Error
+	return blobs, err
+}
+
+// Acquire adds a reference to the blob with blob.Hash, inserting it with a
+// single reference when it does not exist yet. It reports whether the row
+// was created, in which case the caller must store the file. blob is filled
+// with the stored row either way; a RefCount of 1 on an existing row means it
+// had no references, and its file may have been reclaimed.
+func (r *attachmentBlobRepository) Acquire(blob *model.AttachmentBlob) (bool, error) {
+	blob.RefCount = 1
+	result := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "hash"}},
+		DoNothing: true,
+	}).Create(blob)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	if result.RowsAffected > 0 {
+		return true, nil
+	}
+
+	var existing model.AttachmentBlob
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("hash = ?", blob.Hash).
+		First(&existing).Error; err != nil {
+		return false, err
+	}
+
+	timeNow := time.Now()
+	if err := r.db.Model(&model.AttachmentBlob{}).
+		Where("id = ?", existing.ID).
+		Updates(map[string]interface{}{
+			"ref_count":   gorm.Expr("ref_count + 1"),
+			"modified_by": blob.CreatedBy,
+			"modified_at": timeNow,
+		}).Error; err != nil {
+		return false, err
+	}
+
+	existing.RefCount++
+	*blob = existing
+	return false, nil
+}
+
+// Release drops one reference from the blob with the given hash. A blob left
+// without references keeps its row and file so a deleted attachment can be
+// restored; scrubs report it as unreferenced.
+func (r *attachmentBlobRepository) Release(hash string, submittedBy string) error {
+	return r.db.Model(&model.AttachmentBlob{}).
+		Where("hash = ?", hash).
+		Where("ref_count > 0").
+		Updates(map[string]interface{}{
+			"ref_count":   gorm.Expr("ref_count - 1"),
+			"modified_by": submittedBy,
+			"modified_at": time.Now(),
+		}).Error
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/mugnialby/arsip-backend/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// textSearchConfig is the Postgres text search configuration of the
+// search_vector column. Queries must use the same one to match.
+const textSearchConfig = "indonesian"
+
+// Markers ts_headline wraps matching words in. They are private-use
+// characters so the service can HTML-escape a snippet before turning them
+// into tags; extracted text is stripped of them before it is saved.
+const (
+	HighlightStart = "\uE000"
+	HighlightStop  = "\uE001"
+)
+
+type AttachmentTextRepository interface {
+	FindByAttachmentID(attachmentID uint) (*model.AttachmentText, error)
+	FindCompletedByFileHash(fileHash string) (*model.AttachmentText, error)
+	FindUnextracted(mimeTypes []string, afterID uint, limit int) ([]*model.ArchiveAttachment, error)
+	Save(attachmentText *model.AttachmentText) error
+	Search(query string, access *ArchiveAccess, limit int) ([]*model.AttachmentTextMatch, error)
+}
+
+type attachmentTextRepository struct {
+	db *gorm.DB
+}
+
+func NewAttachmentTextRepository(db *gorm.DB) AttachmentTextRepository {
+	return &attachmentTextRepository{db: db}
+}
+
+func (r *attachmentTextRepository) FindByAttachmentID(attachmentID uint) (*model.AttachmentText, error) {
+	var attachmentText model.AttachmentText
+	err := r.db.Where("archive_attachment_id = ?", attachmentID).
+		Where("status = ?", "Y").
+		First(&attachmentText).Error
+	return &attachmentText, err
+}
+
+// FindCompletedByFileHash returns text already extracted from another
+// attachment holding the same file, so shared blobs are only read once.
+func (r *attachmentTextRepository) FindCompletedByFileHash(fileHash string) (*model.AttachmentText, error) {
+	var attachmentText model.AttachmentText
+	err := r.db.Model(&model.AttachmentText{}).
+		Joins("JOIN archive_attachments ON archive_attachments.id = attachment_texts.archive_attachment_id").
+		Where("archive_attachments.file_hash = ?", fileHash).
+		Where("attachment_texts.state = ?", model.AttachmentTextCompleted).
+		Where("attachment_texts.status = ?", "Y").
+		First(&attachmentText).Error
+	return &attachmentText, err
+}
+
+// FindUnextracted returns active attachments of the given types that have no
+// extracted text yet, in ID order starting after afterID.
+func (r *attachmentTextRepository) FindUnextracted(mimeTypes []string, afterID uint, limit int) ([]*model.ArchiveAttachment, error) {
+	var archiveAttachments []*model.ArchiveAttachment
+	err := r.db.Model(&model.ArchiveAttachment{}).
+		Where("id > ?", afterID).
+		Where("status = ?", "Y").
+		Where("mime_type IN ?", mimeTypes).
+		Where("NOT EXISTS (SELECT 1 FROM attachment_texts WHERE attachment_texts.archive_attachment_id = archive_attachments.id)").
+		Order("id").
+		Limit(limit).
+		Find(&archiveAttachments).Error
+	return archiveAttachments, err
+}
+
+// Save inserts the text of an attachment or replaces the one stored earlier.
+func (r *attachmentTextRepository) Save(attachmentText *model.AttachmentText) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "archive_attachment_id"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"state":       attachmentText.State,
+			"content":     attachmentText.Content,
+			"message":     attachmentText.Message,
+			"status":      "Y",
+			"modified_by": attachmentText.CreatedBy,
+			"modified_at": time.Now(),
+		}),
+	}).Create(attachmentText).Error
+}
+
+// Search matches query against the text of active attachments of archives
+// the caller can access, best match first. query uses web search syntax:
+// quoted phrases, "or" and a leading "-" to exclude a word.
+func (r *attachmentTextRepository) Search(query string, access *ArchiveAccess, limit int) ([]*model.AttachmentTextMatch, error) {
+	var matches []*model.AttachmentTextMatch
+	err := r.db.Table("attachment_texts").
+		Select(
+			`archive_attachments.archive_hdr_id,
+			attachment_texts.archive_attachment_id,
+			archive_attachments.file_name,
+			ts_headline(?::regconfig, attachment_texts.content, search_query, ?) AS snippet,
+			ts_rank(attachment_texts.search_vector, search_query) AS rank`,
+			textSearchConfig,
+			"StartSel="+HighlightStart+", StopSel="+HighlightStop+", MaxFragments=2, MaxWords=25, MinWords=10",
+		).
+		Joins("CROSS JOIN websearch_to_tsquery(?::regconfig, ?) AS search_query", textSearchConfig, query).
+		Joins("JOIN archive_attachments ON archive_attachments.id = attachment_texts.archive_attachment_id").
+		Joins("JOIN archive_hdr ON archive_hdr.id = archive_attachments.archive_hdr_id").
+		Scopes(scopeArchiveAccess(access)).
+		Where("attachment_texts.search_vector @@ search_query").
+		Where("attachment_texts.status = ?", "Y").
+		Where("archive_attachments.status = ?", "Y").
+		Where("archive_hdr.status = ?", "Y").
+		Order("rank DESC").
+		Limit(limit).
+		Scan(&matches).Error
+	return matches, err
+}
@@ -4,13 +4,14 @@ import (
 	"errors"
 	"time"
 
+	"github.com/mugnialby/arsip-backend/internal/listquery"
 	"github.com/mugnialby/arsip-backend/internal/model"
 	request "github.com/mugnialby/arsip-backend/internal/model/dto/request/department"
 	"gorm.io/gorm"
 )
 
 type DepartmentRepository interface {
-	FindAll() ([]model.Department, error)
+	FindAll(query listquery.Query) ([]model.Department, listquery.Result, error)
 	FindByID(id uint) (*model.Department, error)
 	Create(department *model.Department) error
 	Update(department *model.Department) error
@@ -25,12 +26,22 @@ func NewDepartmentRepository(db *gorm.DB) DepartmentRepository {
 	return &departmentRepository{db: db}
 }
 
-func (r *departmentRepository) FindAll() ([]model.Department, error) {
+var departmentListSpec = listquery.Spec{
+	Sorts: map[string][]string{
+		"departmentName": {"department_name"},
+		"createdAt":      {"created_at"},
+	},
+	DefaultSort: "departmentName",
+	Filters: map[string]listquery.Filter{
+		"departmentName": {Column: "department_name", Kind: listquery.Contains},
+	},
+	IDColumn: "id",
+}
+
+func (r *departmentRepository) FindAll(query listquery.Query) ([]model.Department, listquery.Result, error) {
 	var departments []model.Department
-	err := r.db.Where("status = ?", "Y").
-		Order("department_name asc").
-		Find(&departments).Error
-	return departments, err
+	result, err := listquery.Find(r.db.Where("status = ?", "Y"), query, departmentListSpec, &departments)
+	return departments, result, err
 }
 
 func (r *departmentRepository) FindByID(id uint) (*model.Department, error) {
@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mugnialby/arsip-backend/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrJobLeaseLost is returned when a job is no longer running under the
+// caller's lease, because it was requeued and possibly claimed again.
+var ErrJobLeaseLost = errors.New("job lease lost")
+
+type JobRepository interface {
+	Create(job *model.Job) error
+	FindByJobID(jobID string) (*model.Job, error)
+	FindPending(kind string, archiveID uint) (*model.Job, error)
+	ClaimNext(workerID string) (*model.Job, error)
+	RequeueStale(staleBefore time.Time, submittedBy string) (int64, error)
+	Heartbeat(job *model.Job, submittedBy string) error
+	UpdateProgress(job *model.Job, progress int, submittedBy string) error
+	Complete(job *model.Job, resultURL string, submittedBy string) error
+	Fail(job *model.Job, message string, submittedBy string) error
+}
+
+type jobRepository struct {
+	db *gorm.DB
+}
+
+func NewJobRepository(db *gorm.DB) JobRepository {
+	return &jobRepository{db: db}
+}
+
+func (r *jobRepository) Create(job *model.Job) error {
+	return r.db.Create(job).Error
+}
+
+func (r *jobRepository) FindByJobID(jobID string) (*model.Job, error) {
+	var job model.Job
+	err := r.db.Where("job_id = ?", jobID).
+		Where("status = ?", "Y").
+		First(&job).Error
+	return &job, err
+}
+
+// FindPending returns a queued or running job of the given kind for the
+// archive, so repeated requests do not queue duplicate work.
+func (r *jobRepository) FindPending(kind string, archiveID uint) (*model.Job, error) {
+	var job model.Job
+	err := r.db.Where("kind = ?", kind).
+		Where("archive_hdr_id = ?", archiveID).
+		Where("state IN ?", []string{model.JobStateQueued, model.JobStateRunning}).
+		Where("status = ?", "Y").
+		Order("id").
+		First(&job).Error
+	return &job, err
+}
+
+// ClaimNext marks the oldest queued job as running under a new lease and
+// returns it. Rows locked by another worker are skipped, so each job is
+// claimed once. It returns gorm.ErrRecordNotFound when the queue is empty.
+func (r *jobRepository) ClaimNext(workerID string) (*model.Job, error) {
+	var job model.Job
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("state = ?", model.JobStateQueued).
+			Where("status = ?", "Y").
+			Order("id").
+			First(&job).Error
+		if err != nil {
+			return err
+		}
+
+		timeNow := time.Now()
+		leaseID := uuid.NewString()
+		job.State = model.JobStateRunning
+		job.LeaseID = &leaseID
+		job.StartedAt = &timeNow
+		job.ModifiedBy = &workerID
+		job.ModifiedAt = &timeNow
+
+		return tx.Model(&model.Job{}).
+			Where("id = ?", job.ID).
+			Updates(map[string]interface{}{
+				"state":       job.State,
+				"lease_id":    leaseID,
+				"started_at":  timeNow,
+				"modified_by": workerID,
+				"modified_at": timeNow,
+			}).Error
+	})
+	return &job, err
+}
+
+// RequeueStale puts running jobs whose worker has not sent a heartbeat since
+// staleBefore back in the queue. Their process most likely died; jobs of
+// live workers, in this process or another, keep being refreshed.
+func (r *jobRepository) RequeueStale(staleBefore time.Time, submittedBy string) (int64, error) {
+	result := r.db.Model(&model.Job{}).
+		Where("state = ?", model.JobStateRunning).
+		Where("status = ?", "Y").
+		Where("COALESCE(modified_at, started_at) < ?", staleBefore).
+		Updates(map[string]interface{}{
+			"state":       model.JobStateQueued,
+			"progress":    0,
+			"lease_id":    nil,
+			"started_at":  nil,
+			"modified_by": submittedBy,
+			"modified_at": time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
+
+// Heartbeat records that the worker running a job is still alive.
+func (r *jobRepository) Heartbeat(job *model.Job, submittedBy string) error {
+	return r.updateLeased(job, map[string]interface{}{
+		"modified_by": submittedBy,
+		"modified_at": time.Now(),
+	})
+}
+
+func (r *jobRepository) UpdateProgress(job *model.Job, progress int, submittedBy string) error {
+	return r.updateLeased(job, map[string]interface{}{
+		"progress":    progress,
+		"modified_by": submittedBy,
+		"modified_at": time.Now(),
+	})
+}
+
+func (r *jobRepository) Complete(job *model.Job, resultURL string, submittedBy string) error {
+	timeNow := time.Now()
+	return r.updateLeased(job, map[string]interface{}{
+		"state":       model.JobStateCompleted,
+		"progress":    100,
+		"result_url":  resultURL,
+		"lease_id":    nil,
+		"finished_at": timeNow,
+		"modified_by": submittedBy,
+		"modified_at": timeNow,
+	})
+}
+
+func (r *jobRepository) Fail(job *model.Job, message string, submittedBy string) error {
+	timeNow := time.Now()
+	return r.updateLeased(job, map[string]interface{}{
+		"state":       model.JobStateFailed,
+		"message":     message,
+		"lease_id":    nil,
+		"finished_at": timeNow,
+		"modified_by": submittedBy,
+		"modified_at": timeNow,
+	})
+}
+
+// updateLeased updates a job only while it is still running under the lease
+// it was claimed with. A worker whose job was requeued as stale gets
+// ErrJobLeaseLost instead of overwriting the job's new run.
+func (r *jobRepository) updateLeased(job *model.Job, values map[string]interface{}) error {
+	result := r.db.Model(&model.Job{}).
+		Where("id = ?", job.ID).
+		Where("state = ?", model.JobStateRunning).
+		Where("lease_id = ?", job.LeaseID).
+		Updates(values)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrJobLeaseLost
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/mugnialby/arsip-backend/internal/model"
+	"gorm.io/gorm"
+)
+
+type RefreshTokenRepository interface {
+	Create(refreshToken *model.RefreshToken) error
+	FindByTokenHash(tokenHash string) (*model.RefreshToken, error)
+	Revoke(id uint, replacedByID *uint, submittedBy string) (bool, error)
+	RevokeAllByUserID(userID uint, submittedBy string) error
+	WithTx(tx *gorm.DB) RefreshTokenRepository
+}
+
+type refreshTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) RefreshTokenRepository {
+	return &refreshTokenRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *refreshTokenRepository) WithTx(tx *gorm.DB) RefreshTokenRepository {
+	return &refreshTokenRepository{db: tx}
+}
+
+func (r *refreshTokenRepository) Create(refreshToken *model.RefreshToken) error {
+	return r.db.Create(refreshToken).Error
+}
+
+func (r *refreshTokenRepository) FindByTokenHash(tokenHash string) (*model.RefreshToken, error) {
+	var refreshToken model.RefreshToken
+	err := r.db.Where("token_hash = ?", tokenHash).
+		First(&refreshToken).Error
+	return &refreshToken, err
+}
+
+// Revoke marks a single token as revoked. It reports false when the token was
+// already revoked, so concurrent refreshes with the same token cannot both win.
+func (r *refreshTokenRepository) Revoke(id uint, replacedByID *uint, submittedBy string) (bool, error) {
+	timeNow := time.Now()
+	result := r.db.Model(&model.RefreshToken{}).
+		Where("id = ?", id).
+		Where("revoked_at IS NULL").
+		Updates(map[string]interface{}{
+			"revoked_at":     timeNow,
+			"replaced_by_id": replacedByID,
+			"status":         "N",
+			"modified_by":    submittedBy,
+			"modified_at":    timeNow,
+		})
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+func (r *refreshTokenRepository) RevokeAllByUserID(userID uint, submittedBy string) error {
+	timeNow := time.Now()
+	return r.db.Model(&model.RefreshToken{}).
+		Where("user_id = ?", userID).
+		Where("revoked_at IS NULL").
+		Updates(map[string]interface{}{
+			"revoked_at":  timeNow,
+			"status":      "N",
+			"modified_by": submittedBy,
+			"modified_at": timeNow,
+		}).Error
+}
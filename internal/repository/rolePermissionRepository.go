@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mugnialby/arsip-backend/internal/model"
+	request "github.com/mugnialby/arsip-backend/internal/model/dto/request/roles"
+	"gorm.io/gorm"
+)
+
+type RolePermissionRepository interface {
+	FindByRoleID(roleID uint) ([]model.RolePermission, error)
+	Create(rolePermission *model.RolePermission) error
+	Delete(deleteRolePermissionRequest *request.DeleteRolePermissionRequest) error
+	HasPermission(roleID uint, permission string) (bool, error)
+}
+
+type rolePermissionRepository struct {
+	db *gorm.DB
+}
+
+func NewRolePermissionRepository(db *gorm.DB) RolePermissionRepository {
+	return &rolePermissionRepository{db: db}
+}
+
+func (r *rolePermissionRepository) FindByRoleID(roleID uint) ([]model.RolePermission, error) {
+	var rolePermissions []model.RolePermission
+	err := r.db.Where("status = ?", "Y").
+		Where("role_id = ?", roleID).
+		Order("permission asc").
+		Find(&rolePermissions).Error
+	return rolePermissions, err
+}
+
+func (r *rolePermissionRepository) Create(rolePermission *model.RolePermission) error {
+	return r.db.Create(rolePermission).Error
+}
+
+func (r *rolePermissionRepository) Delete(deleteRolePermissionRequest *request.DeleteRolePermissionRequest) error {
+	result := r.db.Model(&model.RolePermission{}).
+		Where("id = ?", deleteRolePermissionRequest.ID).
+		Where("status = ?", "Y").
+		Updates(map[string]interface{}{
+			"status":      "N",
+			"modified_by": deleteRolePermissionRequest.SubmittedBy,
+			"modified_at": time.Now(),
+		})
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New("no data found to delete")
+	}
+
+	return nil
+}
+
+func (r *rolePermissionRepository) HasPermission(roleID uint, permission string) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.RolePermission{}).
+		Where("role_id = ?", roleID).
+		Where("permission = ?", permission).
+		Where("status = ?", "Y").
+		Count(&count).Error
+	return count > 0, err
+}
@@ -4,13 +4,14 @@ import (
 	"errors"
 	"time"
 
+	"github.com/mugnialby/arsip-backend/internal/listquery"
 	"github.com/mugnialby/arsip-backend/internal/model"
 	request "github.com/mugnialby/arsip-backend/internal/model/dto/request/roles"
 	"gorm.io/gorm"
 )
 
 type RoleRepository interface {
-	FindAll() ([]model.Role, error)
+	FindAll(query listquery.Query) ([]model.Role, listquery.Result, error)
 	FindByID(id uint) (*model.Role, error)
 	Create(book *model.Role) error
 	Update(book *model.Role) error
@@ -19,28 +20,45 @@ type RoleRepository interface {
 }
 
 type roleRepository struct {
-	db *gorm.DB
+	db              *gorm.DB
+	superuserRoleID uint
 }
 
-func NewRoleRepository(db *gorm.DB) RoleRepository {
-	return &roleRepository{db: db}
+// NewRoleRepository returns a repository whose list leaves out the
+// superuser role.
+func NewRoleRepository(db *gorm.DB, superuserRoleID uint) RoleRepository {
+	return &roleRepository{db: db, superuserRoleID: superuserRoleID}
 }
 
-func (r *roleRepository) FindAll() ([]model.Role, error) {
+// roleListSpec sorts by department first by default; that sort is on a
+// joined table, so it pages by page number only.
+var roleListSpec = listquery.Spec{
+	Sorts: map[string][]string{
+		"departmentName": {"departments.department_name", "roles.role_name"},
+		"roleName":       {"roles.role_name"},
+		"createdAt":      {"roles.created_at"},
+	},
+	DefaultSort: "departmentName",
+	Filters: map[string]listquery.Filter{
+		"roleName":     {Column: "roles.role_name", Kind: listquery.Contains},
+		"departmentId": {Column: "roles.department_id", Kind: listquery.ID},
+	},
+	IDColumn: "roles.id",
+	Preload: func(db *gorm.DB) *gorm.DB {
+		return db.Preload("Department", "status = ?", "Y")
+	},
+}
+
+func (r *roleRepository) FindAll(query listquery.Query) ([]model.Role, listquery.Result, error) {
 	var roles []model.Role
 
-	err := r.db.
+	result, err := listquery.Find(r.db.
 		Model(&model.Role{}).
 		Joins("INNER JOIN departments ON departments.id = roles.department_id").
 		Where("roles.status = ?", "Y").
-		Where("roles.id <> ?", 1).
-		Preload("Department", "status = ?", "Y").
-		Order("departments.department_name ASC").
-		Order("roles.role_name ASC").
-		Find(&roles).Error
-
-	return roles, err
+		Where("roles.id <> ?", r.superuserRoleID), query, roleListSpec, &roles)
 
+	return roles, result, err
 }
 
 func (r *roleRepository) FindByID(id uint) (*model.Role, error) {
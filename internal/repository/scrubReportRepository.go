@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"github.com/mugnialby/arsip-backend/internal/model"
+	"gorm.io/gorm"
+)
+
+type ScrubReportRepository interface {
+	FindLatest() (*model.ScrubReport, error)
+	FindByID(id uint) (*model.ScrubReport, error)
+	Create(scrubReport *model.ScrubReport) error
+	Update(scrubReport *model.ScrubReport) error
+	CreateIssues(scrubIssues []model.ScrubIssue) error
+}
+
+type scrubReportRepository struct {
+	db *gorm.DB
+}
+
+func NewScrubReportRepository(db *gorm.DB) ScrubReportRepository {
+	return &scrubReportRepository{db: db}
+}
+
+func (r *scrubReportRepository) FindLatest() (*model.ScrubReport, error) {
+	var scrubReport model.ScrubReport
+	err := r.db.Where("status = ?", "Y").
+		Preload("Issues", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id ASC")
+		}).
+		Order("id DESC").
+		First(&scrubReport).Error
+	return &scrubReport, err
+}
+
+func (r *scrubReportRepository) FindByID(id uint) (*model.ScrubReport, error) {
+	var scrubReport model.ScrubReport
+	err := r.db.Where("id = ? AND status = ?", id, "Y").
+		Preload("Issues", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id ASC")
+		}).
+		First(&scrubReport).Error
+	return &scrubReport, err
+}
+
+func (r *scrubReportRepository) Create(scrubReport *model.ScrubReport) error {
+	return r.db.Create(scrubReport).Error
+}
+
+func (r *scrubReportRepository) Update(scrubReport *model.ScrubReport) error {
+	return r.db.Save(scrubReport).Error
+}
+
+func (r *scrubReportRepository) CreateIssues(scrubIssues []model.ScrubIssue) error {
+	if len(scrubIssues) == 0 {
+		return nil
+	}
+
+	return r.db.CreateInBatches(scrubIssues, 500).Error
+}
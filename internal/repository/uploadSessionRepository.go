@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/mugnialby/arsip-backend/internal/model"
+	"gorm.io/gorm"
+)
+
+type UploadSessionRepository interface {
+	WithTx(tx *gorm.DB) UploadSessionRepository
+	Create(uploadSession *model.UploadSession) error
+	FindByUploadID(uploadID string) (*model.UploadSession, error)
+	FindExpired(now time.Time) ([]model.UploadSession, error)
+	UpdateOffset(id uint, offset int64, submittedBy string) error
+	Complete(id uint, archiveAttachmentID uint, submittedBy string) error
+	Delete(id uint, submittedBy string) error
+}
+
+type uploadSessionRepository struct {
+	db *gorm.DB
+}
+
+func NewUploadSessionRepository(db *gorm.DB) UploadSessionRepository {
+	return &uploadSessionRepository{db: db}
+}
+
+func (r *uploadSessionRepository) WithTx(tx *gorm.DB) UploadSessionRepository {
+	return &uploadSessionRepository{db: tx}
+}
+
+func (r *uploadSessionRepository) Create(uploadSession *model.UploadSession) error {
+	return r.db.Create(uploadSession).Error
+}
+
+func (r *uploadSessionRepository) FindByUploadID(uploadID string) (*model.UploadSession, error) {
+	var uploadSession model.UploadSession
+	err := r.db.Where("upload_id = ?", uploadID).
+		Where("status = ?", "Y").
+		First(&uploadSession).Error
+	return &uploadSession, err
+}
+
+// FindExpired returns pending sessions whose expiry has passed.
+func (r *uploadSessionRepository) FindExpired(now time.Time) ([]model.UploadSession, error) {
+	var uploadSessions []model.UploadSession
+	err := r.db.Where("status = ?", "Y").
+		Where("completed_at IS NULL").
+		Where("expires_at < ?", now).
+		Find(&uploadSessions).Error
+	return uploadSessions, err
+}
+
+func (r *uploadSessionRepository) UpdateOffset(id uint, offset int64, submittedBy string) error {
+	return r.db.Model(&model.UploadSession{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"upload_offset": offset,
+			"modified_by":   submittedBy,
+			"modified_at":   time.Now(),
+		}).Error
+}
+
+func (r *uploadSessionRepository) Complete(id uint, archiveAttachmentID uint, submittedBy string) error {
+	timeNow := time.Now()
+	return r.db.Model(&model.UploadSession{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"completed_at":          timeNow,
+			"archive_attachment_id": archiveAttachmentID,
+			"modified_by":           submittedBy,
+			"modified_at":           timeNow,
+		}).Error
+}
+
+func (r *uploadSessionRepository) Delete(id uint, submittedBy string) error {
+	return r.db.Model(&model.UploadSession{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":      "N",
+			"modified_by": submittedBy,
+			"modified_at": time.Now(),
+		}).Error
+}
@@ -4,38 +4,65 @@ import (
 	"errors"
 	"time"
 
+	"github.com/mugnialby/arsip-backend/internal/listquery"
 	"github.com/mugnialby/arsip-backend/internal/model"
-	authRequest "github.com/mugnialby/arsip-backend/internal/model/dto/request/auth"
 	usersRequest "github.com/mugnialby/arsip-backend/internal/model/dto/request/users"
 	"gorm.io/gorm"
 )
 
 type UserRepository interface {
-	FindAll() ([]model.User, error)
+	FindAll(query listquery.Query) ([]model.User, listquery.Result, error)
 	FindByID(id uint) (*model.User, error)
 	Create(user *model.User) error
 	Update(user *model.User) error
 	Delete(deleteUserRequest *usersRequest.DeleteUserRequest) error
-	FindUserForLoginRequest(loginRequest *authRequest.LoginRequest) (*model.User, error)
+	FindActiveByID(id uint) (*model.User, error)
+	FindActiveByUserID(userId string) (*model.User, error)
+	UpdatePasswordHash(id uint, passwordHash string) error
+	WithTx(tx *gorm.DB) UserRepository
 }
 
 type userRepository struct {
-	db *gorm.DB
+	db              *gorm.DB
+	superuserRoleID uint
 }
 
-func NewUserRepository(db *gorm.DB) UserRepository {
-	return &userRepository{db: db}
+// NewUserRepository returns a repository whose list leaves out users with
+// the superuser role.
+func NewUserRepository(db *gorm.DB, superuserRoleID uint) UserRepository {
+	return &userRepository{db: db, superuserRoleID: superuserRoleID}
 }
 
-func (r *userRepository) FindAll() ([]model.User, error) {
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *userRepository) WithTx(tx *gorm.DB) UserRepository {
+	return &userRepository{db: tx, superuserRoleID: r.superuserRoleID}
+}
+
+var userListSpec = listquery.Spec{
+	Sorts: map[string][]string{
+		"userId":    {"user_id"},
+		"fullName":  {"full_name"},
+		"createdAt": {"created_at"},
+	},
+	DefaultSort: "userId",
+	Filters: map[string]listquery.Filter{
+		"userId":       {Column: "user_id", Kind: listquery.Contains},
+		"fullName":     {Column: "full_name", Kind: listquery.Contains},
+		"departmentId": {Column: "department_id", Kind: listquery.ID},
+		"roleId":       {Column: "role_id", Kind: listquery.ID},
+	},
+	IDColumn: "id",
+	Preload: func(db *gorm.DB) *gorm.DB {
+		return db.Preload("Department", "status = ?", "Y").
+			Preload("Role", "status = ?", "Y")
+	},
+}
+
+func (r *userRepository) FindAll(query listquery.Query) ([]model.User, listquery.Result, error) {
 	var users []model.User
-	err := r.db.Where("status = ?", "Y").
-		Where("role_id not in (?)", 1).
-		Preload("Department", "status = ?", "Y").
-		Preload("Role", "status = ?", "Y").
-		Order("user_id asc").
-		Find(&users).Error
-	return users, err
+	result, err := listquery.Find(r.db.Where("status = ?", "Y").
+		Where("role_id <> ?", r.superuserRoleID), query, userListSpec, &users)
+	return users, result, err
 }
 
 func (r *userRepository) FindByID(id uint) (*model.User, error) {
@@ -72,13 +99,26 @@ func (r *userRepository) Delete(deleteUserRequest *usersRequest.DeleteUserReques
 	return nil
 }
 
-func (r *userRepository) FindUserForLoginRequest(loginRequest *authRequest.LoginRequest) (*model.User, error) {
+func (r *userRepository) FindActiveByID(id uint) (*model.User, error) {
 	var user model.User
 	err := r.db.Where("status = ?", "Y").
-		Where("user_id = ?", loginRequest.UserId).
-		Where("password_hash = ?", loginRequest.Password).
+		Where("id = ?", id).
+		First(&user).Error
+	return &user, err
+}
+
+func (r *userRepository) FindActiveByUserID(userId string) (*model.User, error) {
+	var user model.User
+	err := r.db.Where("status = ?", "Y").
+		Where("user_id = ?", userId).
 		Preload("Department", "status = ?", "Y").
 		Preload("Role", "status = ?", "Y").
 		First(&user).Error
 	return &user, err
 }
+
+func (r *userRepository) UpdatePasswordHash(id uint, passwordHash string) error {
+	return r.db.Model(&model.User{}).
+		Where("id = ?", id).
+		Update("password_hash", passwordHash).Error
+}
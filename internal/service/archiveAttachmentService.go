@@ -1,6 +1,7 @@
 package service
 
 import (
+	"github.com/mugnialby/arsip-backend/internal/listquery"
 	"github.com/mugnialby/arsip-backend/internal/model"
 	"github.com/mugnialby/arsip-backend/internal/repository"
 )
@@ -13,8 +14,8 @@ func NewArchiveAttachmentService(repo repository.ArchiveAttachmentRepository) *A
 	return &ArchiveAttachmentService{repo: repo}
 }
 
-func (s *ArchiveAttachmentService) GetAllArchiveAttachments() ([]model.ArchiveAttachment, error) {
-	return s.repo.FindAll()
+func (s *ArchiveAttachmentService) GetAllArchiveAttachments(query listquery.Query) ([]model.ArchiveAttachment, listquery.Result, error) {
+	return s.repo.FindAll(query)
 }
 
 func (s *ArchiveAttachmentService) GetArchiveAttachmentByID(id uint) (*model.ArchiveAttachment, error) {
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"github.com/mugnialby/arsip-backend/internal/listquery"
 	"github.com/mugnialby/arsip-backend/internal/model"
 	request "github.com/mugnialby/arsip-backend/internal/model/dto/request/archiveCharacteristic"
 	"github.com/mugnialby/arsip-backend/internal/repository"
@@ -14,8 +15,8 @@ func NewArchiveCharacteristicService(repo repository.ArchiveCharacteristicReposi
 	return &ArchiveCharacteristicService{repo: repo}
 }
 
-func (s *ArchiveCharacteristicService) GetAllArchiveCharacteristics() ([]model.ArchiveCharacteristic, error) {
-	return s.repo.FindAll()
+func (s *ArchiveCharacteristicService) GetAllArchiveCharacteristics(query listquery.Query) ([]model.ArchiveCharacteristic, listquery.Result, error) {
+	return s.repo.FindAll(query)
 }
 
 func (s *ArchiveCharacteristicService) GetArchiveCharacteristicByID(id uint) (*model.ArchiveCharacteristic, error) {
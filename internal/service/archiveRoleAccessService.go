@@ -1,6 +1,7 @@
 package service
 
 import (
+	"github.com/mugnialby/arsip-backend/internal/listquery"
 	"github.com/mugnialby/arsip-backend/internal/model"
 	request "github.com/mugnialby/arsip-backend/internal/model/dto/request/archiveRoleAccess"
 	"github.com/mugnialby/arsip-backend/internal/repository"
@@ -14,8 +15,8 @@ func NewArchiveRoleAccessService(repo repository.ArchiveRoleAccessRepository) *A
 	return &ArchiveRoleAccessService{repo: repo}
 }
 
-func (s *ArchiveRoleAccessService) GetAllArchiveRoleAccesss() ([]model.ArchiveRoleAccess, error) {
-	return s.repo.FindAll()
+func (s *ArchiveRoleAccessService) GetAllArchiveRoleAccesss(query listquery.Query) ([]model.ArchiveRoleAccess, listquery.Result, error) {
+	return s.repo.FindAll(query)
 }
 
 func (s *ArchiveRoleAccessService) GetArchiveRoleAccessByID(id uint) (*model.ArchiveRoleAccess, error) {
@@ -1,47 +1,739 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/mugnialby/arsip-backend/internal/listquery"
 	"github.com/mugnialby/arsip-backend/internal/model"
 	request "github.com/mugnialby/arsip-backend/internal/model/dto/request/archive"
+	attachmentRequest "github.com/mugnialby/arsip-backend/internal/model/dto/request/archiveAttachment"
+	archiveRoleAccessRequest "github.com/mugnialby/arsip-backend/internal/model/dto/request/archiveRoleAccess"
+	"github.com/mugnialby/arsip-backend/internal/pdf"
 	"github.com/mugnialby/arsip-backend/internal/repository"
+	"github.com/mugnialby/arsip-backend/internal/storage"
+	"github.com/mugnialby/arsip-backend/pkg/logger"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrArchiveNotFound        = errors.New("archive not found")
+	ErrArchiveForbidden       = errors.New("archive is not accessible to the caller")
+	ErrAttachmentNotFound     = errors.New("attachment not found")
+	ErrAttachmentNotInArchive = errors.New("attachment does not belong to the archive")
+	ErrRoleAccessNotInArchive = errors.New("role access does not belong to the archive")
+	ErrAttachmentHashInvalid  = errors.New("hash must be a hex encoded sha256")
+	ErrAttachmentOrderInvalid = errors.New("attachment order must list every attachment of the archive exactly once")
+	ErrSearchDateRangeInvalid = errors.New("archiveDateFrom is after archiveDateTo")
 )
 
 type ArchiveService struct {
-	repo repository.ArchiveRepository
+	db              *gorm.DB
+	storage         storage.Backend
+	repo            repository.ArchiveRepository
+	attachmentRepo  repository.ArchiveAttachmentRepository
+	blobRepo        repository.AttachmentBlobRepository
+	roleAccessRepo  repository.ArchiveRoleAccessRepository
+	textRepo        repository.AttachmentTextRepository
+	thumbnails      *ThumbnailService
+	texts           *TextExtractionService
+	merger          pdf.Merger
+	superuserRoleID uint
+	maxUploadSize   int64
+
+	// watermarkTemplate is the watermark of exported PDFs, see ExportPDF.
+	watermarkTemplate string
+
+	// pdfBuilds lets concurrent requests for the same merged PDF share one
+	// build.
+	pdfBuilds singleflight.Group
+}
+
+func NewArchiveService(
+	db *gorm.DB,
+	backend storage.Backend,
+	repo repository.ArchiveRepository,
+	attachmentRepo repository.ArchiveAttachmentRepository,
+	blobRepo repository.AttachmentBlobRepository,
+	roleAccessRepo repository.ArchiveRoleAccessRepository,
+	textRepo repository.AttachmentTextRepository,
+	thumbnails *ThumbnailService,
+	texts *TextExtractionService,
+	merger pdf.Merger,
+	superuserRoleID uint,
+	maxUploadSize int64,
+	watermarkTemplate string,
+) *ArchiveService {
+	return &ArchiveService{
+		db:              db,
+		storage:         backend,
+		repo:            repo,
+		attachmentRepo:  attachmentRepo,
+		blobRepo:        blobRepo,
+		roleAccessRepo:  roleAccessRepo,
+		textRepo:        textRepo,
+		thumbnails:      thumbnails,
+		texts:           texts,
+		merger:          merger,
+		superuserRoleID: superuserRoleID,
+		maxUploadSize:   maxUploadSize,
+
+		watermarkTemplate: watermarkTemplate,
+	}
+}
+
+func (s *ArchiveService) GetAllArchives(user *model.User, query listquery.Query) ([]model.ArchiveHdr, listquery.Result, error) {
+	return s.repo.FindAll(s.accessFor(user), query)
+}
+
+// GetArchiveByID returns ErrArchiveNotFound when the archive does not exist and
+// ErrArchiveForbidden when the caller has no active role access to it.
+func (s *ArchiveService) GetArchiveByID(id uint, user *model.User) (*model.ArchiveHdr, error) {
+	archive, err := s.findArchive(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.authorize(archive.ID, user); err != nil {
+		return nil, err
+	}
+
+	return archive, nil
+}
+
+// CreateArchive stores the header, its role access and its attachments in a
+// single transaction. Attachment files are only stored once every row has
+// been written. Each new attachment lists accessible attachments that already
+// hold the same file.
+func (s *ArchiveService) CreateArchive(newArchiveRequest *request.NewArchiveRequest, user *model.User) (*model.ArchiveHdr, error) {
+	uploads := make([]*attachmentUpload, 0, len(newArchiveRequest.ListArchiveAttachments))
+	for _, archiveAttachment := range newArchiveRequest.ListArchiveAttachments {
+		if !archiveAttachment.IsNew {
+			continue
+		}
+
+		upload, err := decodeBase64Attachment(archiveAttachment.FileBase64)
+		if err != nil {
+			return nil, err
+		}
+
+		uploads = append(uploads, upload)
+	}
+
+	archive := &model.ArchiveHdr{
+		ArchiveDate:             newArchiveRequest.ArchiveDate,
+		ArchiveNumber:           newArchiveRequest.ArchiveNumber,
+		ArchiveName:             newArchiveRequest.ArchiveName,
+		ArchiveCharacteristicID: newArchiveRequest.ArchiveCharacteristicID,
+		ArchiveTypeID:           newArchiveRequest.ArchiveTypeID,
+		DepartmentID:            newArchiveRequest.DepartmentID,
+		Status:                  "Y",
+		CreatedBy:               user.UserId,
+	}
+
+	err := s.withTransaction(func(tx *gorm.DB, staging *fileStaging) error {
+		if err := s.repo.WithTx(tx).Create(archive); err != nil {
+			return fmt.Errorf("create archive hdr: %w", err)
+		}
+
+		for _, roleAccess := range newArchiveRequest.RoleAccess {
+			newArchiveRoleAccess := model.ArchiveRoleAccess{
+				ArchiveHdrID: archive.ID,
+				RoleID:       roleAccess.RoleID,
+				DepartmentID: roleAccess.DepartmentID,
+				Status:       "Y",
+				CreatedBy:    user.UserId,
+			}
+
+			if err := s.roleAccessRepo.WithTx(tx).Create(&newArchiveRoleAccess); err != nil {
+				return fmt.Errorf("create archive role access: %w", err)
+			}
+		}
+
+		for _, upload := range uploads {
+			newArchiveAttachment, err := s.stageAttachment(tx, staging, archive.ID, upload, user.UserId)
+			if err != nil {
+				return err
+			}
+
+			archive.ArchiveAttachments = append(archive.ArchiveAttachments, newArchiveAttachment)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.findDuplicates(archive.ArchiveAttachments, user)
+	s.attachmentsStored(archive.ArchiveAttachments...)
+	return archive, nil
+}
+
+// UpdateArchive applies header changes, role access changes and attachment
+// additions/removals in a single transaction.
+func (s *ArchiveService) UpdateArchive(updateArchiveRequest *request.UpdateArchiveRequest, user *model.User) (*model.ArchiveHdr, error) {
+	archive, err := s.GetArchiveByID(updateArchiveRequest.ID, user)
+	if err != nil {
+		return nil, err
+	}
+
+	uploads := make(map[int]*attachmentUpload)
+	for i, archiveAttachment := range updateArchiveRequest.ListArchiveAttachments {
+		if !archiveAttachment.IsNew {
+			continue
+		}
+
+		upload, err := decodeBase64Attachment(archiveAttachment.FileBase64)
+		if err != nil {
+			return nil, err
+		}
+
+		uploads[i] = upload
+	}
+
+	submittedBy := user.UserId
+	timeNow := time.Now()
+
+	archive.ArchiveDate = updateArchiveRequest.ArchiveDate
+	archive.ArchiveNumber = updateArchiveRequest.ArchiveNumber
+	archive.ArchiveName = updateArchiveRequest.ArchiveName
+	archive.ArchiveCharacteristicID = updateArchiveRequest.ArchiveCharacteristicID
+	archive.ArchiveTypeID = updateArchiveRequest.ArchiveTypeID
+	archive.ModifiedBy = &submittedBy
+	archive.ModifiedAt = &timeNow
+
+	attachmentsChanged := false
+	var newArchiveAttachments []*model.ArchiveAttachment
+
+	err = s.withTransaction(func(tx *gorm.DB, staging *fileStaging) error {
+		if err := s.repo.WithTx(tx).Update(archive); err != nil {
+			return fmt.Errorf("update archive hdr: %w", err)
+		}
+
+		roleAccessRepo := s.roleAccessRepo.WithTx(tx)
+		for _, roleAccess := range updateArchiveRequest.RoleAccess {
+			if roleAccess.IsNew {
+				newArchiveRoleAccess := model.ArchiveRoleAccess{
+					ArchiveHdrID: archive.ID,
+					RoleID:       roleAccess.RoleID,
+					DepartmentID: roleAccess.DepartmentID,
+					Status:       "Y",
+					CreatedBy:    submittedBy,
+				}
+
+				if err := roleAccessRepo.Create(&newArchiveRoleAccess); err != nil {
+					return fmt.Errorf("create archive role access: %w", err)
+				}
+			}
+
+			if roleAccess.IsDelete {
+				existingRoleAccess, err := roleAccessRepo.FindByID(roleAccess.ID)
+				if err != nil {
+					return fmt.Errorf("get archive role access %d: %w", roleAccess.ID, err)
+				}
+
+				if existingRoleAccess.ArchiveHdrID != archive.ID {
+					return ErrRoleAccessNotInArchive
+				}
+
+				deleteArchiveRoleAccess := archiveRoleAccessRequest.DeleteArchiveRoleAccessRequest{
+					ID:          roleAccess.ID,
+					SubmittedBy: submittedBy,
+				}
+
+				if err := roleAccessRepo.Delete(&deleteArchiveRoleAccess); err != nil {
+					return fmt.Errorf("delete archive role access %d: %w", roleAccess.ID, err)
+				}
+			}
+		}
+
+		attachmentRepo := s.attachmentRepo.WithTx(tx)
+		for i, archiveAttachment := range updateArchiveRequest.ListArchiveAttachments {
+			if upload, ok := uploads[i]; ok {
+				newArchiveAttachment, err := s.stageAttachment(tx, staging, archive.ID, upload, submittedBy)
+				if err != nil {
+					return err
+				}
+
+				newArchiveAttachments = append(newArchiveAttachments, newArchiveAttachment)
+				attachmentsChanged = true
+			}
+
+			if archiveAttachment.IsDelete {
+				existingAttachment, err := attachmentRepo.FindByID(archiveAttachment.ID)
+				if err != nil {
+					return fmt.Errorf("get archive attachment %d: %w", archiveAttachment.ID, err)
+				}
+
+				if existingAttachment.ArchiveHdrID != archive.ID {
+					return ErrAttachmentNotInArchive
+				}
+
+				existingAttachment.Status = "N"
+				existingAttachment.ModifiedBy = &submittedBy
+				existingAttachment.ModifiedAt = &timeNow
+				if err := attachmentRepo.Update(existingAttachment); err != nil {
+					return fmt.Errorf("delete archive attachment %d: %w", archiveAttachment.ID, err)
+				}
+
+				if err := s.releaseBlob(tx, existingAttachment, submittedBy); err != nil {
+					return err
+				}
+
+				attachmentsChanged = true
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if attachmentsChanged {
+		s.removeMergedPDFCache(archive.ID)
+	}
+
+	s.findDuplicates(newArchiveAttachments, user)
+	s.attachmentsStored(newArchiveAttachments...)
+	archive.ArchiveAttachments = append(archive.ArchiveAttachments, newArchiveAttachments...)
+	return archive, nil
+}
+
+func (s *ArchiveService) FindArchiveByQuery(query string, user *model.User) ([]model.ArchiveHdr, error) {
+	return s.repo.FindArchiveByQuery(query, s.accessFor(user))
+}
+
+// AddAttachment streams a single uploaded file to storage and records it. The
+// file name is only used to determine the extension; the stored name is
+// generated like the base64 uploads.
+func (s *ArchiveService) AddAttachment(archiveID uint, fileName string, r io.Reader, user *model.User) (*model.ArchiveAttachment, error) {
+	if _, err := s.GetArchiveByID(archiveID, user); err != nil {
+		return nil, err
+	}
+
+	declaredExt, err := extensionFromFileName(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	mimeType, fileExt, r, err := detectReaderType(r, declaredExt)
+	if err != nil {
+		return nil, err
+	}
+
+	// The body is staged before the transaction begins; a slow client must
+	// not hold a database connection.
+	staging := newFileStaging(s.storage)
+	file, err := staging.StageReader(r, s.maxUploadSize)
+	if err != nil {
+		return nil, err
+	}
+
+	var newArchiveAttachment *model.ArchiveAttachment
+	err = s.withStagedTransaction(staging, func(tx *gorm.DB, staging *fileStaging) error {
+		newArchiveAttachment, err = s.attachStagedFile(tx, file, archiveID, fileExt, mimeType, user.UserId)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.removeMergedPDFCache(archiveID)
+	s.findDuplicates([]*model.ArchiveAttachment{newArchiveAttachment}, user)
+	s.attachmentsStored(newArchiveAttachment)
+	return newArchiveAttachment, nil
+}
+
+// ReorderAttachments sets the position of every active attachment of the
+// archive to its index in the request, and its rotation when one is given.
+// The merged PDF follows this order.
+func (s *ArchiveService) ReorderAttachments(archiveID uint, reorderRequest *attachmentRequest.ReorderArchiveAttachmentsRequest, user *model.User) (*model.ArchiveHdr, error) {
+	archive, err := s.GetArchiveByID(archiveID, user)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[uint]*model.ArchiveAttachment, len(archive.ArchiveAttachments))
+	for _, archiveAttachment := range archive.ArchiveAttachments {
+		existing[archiveAttachment.ID] = archiveAttachment
+	}
+
+	if len(reorderRequest.Attachments) != len(existing) {
+		return nil, ErrAttachmentOrderInvalid
+	}
+
+	seen := make(map[uint]bool, len(reorderRequest.Attachments))
+	for _, attachmentOrder := range reorderRequest.Attachments {
+		if existing[attachmentOrder.ID] == nil || seen[attachmentOrder.ID] {
+			return nil, ErrAttachmentOrderInvalid
+		}
+		seen[attachmentOrder.ID] = true
+	}
+
+	err = s.withTransaction(func(tx *gorm.DB, _ *fileStaging) error {
+		for i, attachmentOrder := range reorderRequest.Attachments {
+			rotation := existing[attachmentOrder.ID].Rotation
+			if attachmentOrder.Rotation != nil {
+				rotation = *attachmentOrder.Rotation
+			}
+
+			if err := s.attachmentRepo.WithTx(tx).UpdateOrder(attachmentOrder.ID, i+1, rotation, user.UserId); err != nil {
+				return fmt.Errorf("update archive attachment order: %w", err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.removeMergedPDFCache(archiveID)
+	return s.GetArchiveByID(archiveID, user)
+}
+
+// FindDuplicates lists the accessible attachments whose content has the
+// given SHA-256, so clients can warn before uploading a file again.
+func (s *ArchiveService) FindDuplicates(hash string, user *model.User) ([]model.AttachmentDuplicate, error) {
+	hash = strings.ToLower(hash)
+	if !sha256HexPattern.MatchString(hash) {
+		return nil, ErrAttachmentHashInvalid
+	}
+
+	return s.attachmentRepo.FindDuplicates(hash, 0, s.accessFor(user))
+}
+
+// GetAttachment returns an active attachment of an archive the caller can
+// access. Attachments of other archives are reported as not found.
+func (s *ArchiveService) GetAttachment(archiveID uint, attachmentID uint, user *model.User) (*model.ArchiveAttachment, error) {
+	if _, err := s.GetArchiveByID(archiveID, user); err != nil {
+		return nil, err
+	}
+
+	archiveAttachment, err := s.attachmentRepo.FindByID(attachmentID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if archiveAttachment.ArchiveHdrID != archiveID || archiveAttachment.Status != "Y" {
+		return nil, ErrAttachmentNotFound
+	}
+
+	return archiveAttachment, nil
+}
+
+// OpenAttachmentSeeker opens the stored file of an attachment for serving
+// with Range support. The returned reader must be closed.
+func (s *ArchiveService) OpenAttachmentSeeker(ctx context.Context, archiveAttachment *model.ArchiveAttachment) (*storage.ReadSeeker, *storage.ObjectInfo, error) {
+	info, err := s.storage.Stat(ctx, archiveAttachment.FileLocation)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return storage.NewReadSeeker(ctx, s.storage, archiveAttachment.FileLocation, info.Size), info, nil
+}
+
+// HasThumbnail reports whether the attachment can be served a thumbnail.
+func (s *ArchiveService) HasThumbnail(archiveAttachment *model.ArchiveAttachment) bool {
+	return s.thumbnails.HasThumbnail(archiveAttachment)
+}
+
+// OpenAttachmentThumbnail opens the thumbnail of an attachment, rendering it
+// first if it does not exist yet. The returned reader must be closed.
+func (s *ArchiveService) OpenAttachmentThumbnail(ctx context.Context, archiveAttachment *model.ArchiveAttachment) (*storage.ReadSeeker, *storage.ObjectInfo, error) {
+	return s.thumbnails.Open(ctx, archiveAttachment)
 }
 
-func NewArchiveService(repo repository.ArchiveRepository) *ArchiveService {
-	return &ArchiveService{repo: repo}
+// OpenAttachment opens the stored file of an attachment for reading.
+func (s *ArchiveService) OpenAttachment(ctx context.Context, archiveAttachment *model.ArchiveAttachment) (io.ReadCloser, error) {
+	return s.storage.Get(ctx, archiveAttachment.FileLocation)
 }
 
-func (s *ArchiveService) GetAllArchives() ([]model.ArchiveHdr, error) {
-	return s.repo.FindAll()
+// MaterializeAttachment returns a local path to the attachment's file for
+// tools that need one, downloading it into tmpDir when storage is remote.
+// cleanup must always be called.
+func (s *ArchiveService) MaterializeAttachment(ctx context.Context, archiveAttachment *model.ArchiveAttachment, tmpDir string) (string, func(), error) {
+	return storage.Materialize(ctx, s.storage, archiveAttachment.FileLocation, tmpDir)
 }
 
-func (s *ArchiveService) GetArchiveByID(id uint) (*model.ArchiveHdr, error) {
-	return s.repo.FindByID(id)
+// DeleteArchive soft deletes the header together with its attachments and
+// role access.
+func (s *ArchiveService) DeleteArchive(deleteArchiveRequest *request.DeleteArchiveRequest, user *model.User) error {
+	if _, err := s.GetArchiveByID(deleteArchiveRequest.ID, user); err != nil {
+		return err
+	}
+
+	err := s.withTransaction(func(tx *gorm.DB, _ *fileStaging) error {
+		if err := s.repo.WithTx(tx).Delete(deleteArchiveRequest); err != nil {
+			return fmt.Errorf("delete archive hdr: %w", err)
+		}
+
+		archiveAttachments, err := s.attachmentRepo.WithTx(tx).FindActiveByArchiveID(deleteArchiveRequest.ID)
+		if err != nil {
+			return fmt.Errorf("get archive attachments: %w", err)
+		}
+
+		for i := range archiveAttachments {
+			if err := s.releaseBlob(tx, &archiveAttachments[i], deleteArchiveRequest.SubmittedBy); err != nil {
+				return err
+			}
+		}
+
+		if err := s.attachmentRepo.WithTx(tx).DeleteArchiveAttachmentByArchiveID(deleteArchiveRequest.ID, deleteArchiveRequest.SubmittedBy); err != nil {
+			return fmt.Errorf("delete archive attachments: %w", err)
+		}
+
+		if err := s.roleAccessRepo.WithTx(tx).DeleteArchiveRoleAccessByArchiveID(deleteArchiveRequest.ID, deleteArchiveRequest.SubmittedBy); err != nil {
+			return fmt.Errorf("delete archive role access: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.removeMergedPDFCache(deleteArchiveRequest.ID)
+	return nil
 }
 
-func (s *ArchiveService) CreateArchive(archive *model.ArchiveHdr) error {
-	return s.repo.Create(archive)
+func (s *ArchiveService) FindArchiveByAdvanceQuery(advancedSearchRequest request.AdvancedSearchRequest, user *model.User) ([]model.ArchiveHdr, error) {
+	if err := validateAdvancedSearch(advancedSearchRequest); err != nil {
+		return nil, err
+	}
+
+	return s.repo.FindArchiveByAdvanceQuery(advancedSearchRequest, s.accessFor(user))
 }
 
-func (s *ArchiveService) UpdateArchive(archive *model.ArchiveHdr) error {
-	return s.repo.Update(archive)
+// GetArchiveFacets counts the archives matching an advanced search by type,
+// characteristic, department and year, for the archive browser's filters.
+func (s *ArchiveService) GetArchiveFacets(advancedSearchRequest request.AdvancedSearchRequest, user *model.User) (*model.ArchiveFacets, error) {
+	if err := validateAdvancedSearch(advancedSearchRequest); err != nil {
+		return nil, err
+	}
+
+	return s.repo.FindArchiveFacets(advancedSearchRequest, s.accessFor(user))
 }
 
-func (s *ArchiveService) FindArchiveByQuery(query string) ([]model.ArchiveHdr, error) {
-	return s.repo.FindArchiveByQuery(query)
+func validateAdvancedSearch(advancedSearchRequest request.AdvancedSearchRequest) error {
+	dateFrom, dateTo := advancedSearchRequest.ArchiveDateFrom, advancedSearchRequest.ArchiveDateTo
+	if dateFrom.Valid && dateTo.Valid && dateFrom.Time.After(*dateTo.Time) {
+		return ErrSearchDateRangeInvalid
+	}
+
+	return nil
+}
+
+func (s *ArchiveService) GetAllArchivesByData(user *model.User, query listquery.Query) ([]model.ArchiveHdr, listquery.Result, error) {
+	return s.repo.GetAllArchivesByData(s.accessFor(user), query)
+}
+
+// attachmentsStored starts the background work for newly stored
+// attachments.
+func (s *ArchiveService) attachmentsStored(archiveAttachments ...*model.ArchiveAttachment) {
+	s.thumbnails.Enqueue(archiveAttachments...)
+	s.texts.Enqueue(archiveAttachments...)
+}
+
+// findArchive loads an archive without checking access, for work done on
+// behalf of a caller that was authorized earlier, such as a queued job.
+func (s *ArchiveService) findArchive(id uint) (*model.ArchiveHdr, error) {
+	archive, err := s.repo.FindByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrArchiveNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return archive, nil
 }
 
-func (s *ArchiveService) DeleteArchive(deleteArchiveRequest *request.DeleteArchiveRequest) error {
-	return s.repo.Delete(deleteArchiveRequest)
+func (s *ArchiveService) isSuperuser(user *model.User) bool {
+	return user.RoleID == s.superuserRoleID
 }
 
-func (s *ArchiveService) FindArchiveByAdvanceQuery(advancedSearchRequest request.AdvancedSearchRequest) ([]model.ArchiveHdr, error) {
-	return s.repo.FindArchiveByAdvanceQuery(advancedSearchRequest)
+// accessFor returns the role access restriction for the caller, or nil for
+// the superuser role.
+func (s *ArchiveService) accessFor(user *model.User) *repository.ArchiveAccess {
+	if s.isSuperuser(user) {
+		return nil
+	}
+
+	return &repository.ArchiveAccess{
+		RoleID:       user.RoleID,
+		DepartmentID: user.DepartmentID,
+	}
+}
+
+func (s *ArchiveService) authorize(archiveID uint, user *model.User) error {
+	if s.isSuperuser(user) {
+		return nil
+	}
+
+	allowed, err := s.roleAccessRepo.HasActiveAccess(archiveID, user.RoleID, user.DepartmentID)
+	if err != nil {
+		return err
+	}
+
+	if !allowed {
+		return ErrArchiveForbidden
+	}
+
+	return nil
+}
+
+// withTransaction runs fn inside a database transaction. Files staged by fn are
+// moved into place right before the commit and removed again if anything
+// fails, so the database and the upload directory stay consistent.
+func (s *ArchiveService) withTransaction(fn func(tx *gorm.DB, staging *fileStaging) error) error {
+	return s.withStagedTransaction(newFileStaging(s.storage), fn)
+}
+
+// withStagedTransaction is withTransaction for files staged before the
+// transaction, such as a request body streamed to tmp, so no connection is
+// held while a client uploads. They are removed if the transaction fails.
+func (s *ArchiveService) withStagedTransaction(staging *fileStaging, fn func(tx *gorm.DB, staging *fileStaging) error) (err error) {
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		staging.Rollback()
+		return tx.Error
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			staging.Rollback()
+			panic(r)
+		}
+	}()
+
+	if err := fn(tx, staging); err != nil {
+		tx.Rollback()
+		staging.Rollback()
+		return err
+	}
+
+	if err := staging.Commit(); err != nil {
+		tx.Rollback()
+		staging.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		staging.Rollback()
+		return err
+	}
+
+	staging.Cleanup()
+	return nil
+}
+
+func (s *ArchiveService) stageAttachment(tx *gorm.DB, staging *fileStaging, archiveID uint, upload *attachmentUpload, submittedBy string) (*model.ArchiveAttachment, error) {
+	file, err := staging.Stage(upload.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.attachStagedFile(tx, file, archiveID, upload.Extension, upload.MimeType, submittedBy)
+}
+
+// attachStagedFile records a staged file as a new attachment of archiveID.
+// Files are stored by content hash: when a blob with the same hash already
+// exists it gains a reference and the staged copy is discarded.
+func (s *ArchiveService) attachStagedFile(tx *gorm.DB, file *stagedFile, archiveID uint, fileExt string, mimeType string, submittedBy string) (*model.ArchiveAttachment, error) {
+	blob := &model.AttachmentBlob{
+		Hash:       file.Hash,
+		StorageKey: blobKey(file.Hash, fileExt),
+		Size:       file.Size,
+		MimeType:   mimeType,
+		CreatedBy:  submittedBy,
+	}
+
+	created, err := s.blobRepo.WithTx(tx).Acquire(blob)
+	if err != nil {
+		return nil, fmt.Errorf("acquire attachment blob: %w", err)
+	}
+
+	if created {
+		file.StoreAs(blob.StorageKey, mimeType)
+	} else if blob.RefCount == 1 {
+		// The blob had no references, so scrub reported it and its file may
+		// have been reclaimed since. Store the upload again if so.
+		if _, err := s.storage.Stat(context.Background(), blob.StorageKey); errors.Is(err, storage.ErrNotFound) {
+			file.StoreAs(blob.StorageKey, mimeType)
+		} else if err != nil {
+			return nil, fmt.Errorf("stat attachment blob: %w", err)
+		}
+	}
+
+	sortOrder, err := s.attachmentRepo.WithTx(tx).NextSortOrder(archiveID)
+	if err != nil {
+		return nil, fmt.Errorf("get attachment sort order: %w", err)
+	}
+
+	newArchiveAttachment := &model.ArchiveAttachment{
+		ArchiveHdrID: archiveID,
+		FileName:     fmt.Sprintf("%d_%d.%s", archiveID, time.Now().UnixNano(), fileExt),
+		FileLocation: blob.StorageKey,
+		MimeType:     mimeType,
+		FileHash:     file.Hash,
+		SortOrder:    sortOrder,
+		Status:       "Y",
+		CreatedBy:    submittedBy,
+	}
+
+	if err := s.attachmentRepo.WithTx(tx).Create(newArchiveAttachment); err != nil {
+		return nil, fmt.Errorf("create archive attachment: %w", err)
+	}
+
+	return newArchiveAttachment, nil
+}
+
+// releaseBlob drops the blob reference held by a deleted attachment.
+// Attachments stored before deduplication have no hash and hold none.
+func (s *ArchiveService) releaseBlob(tx *gorm.DB, archiveAttachment *model.ArchiveAttachment, submittedBy string) error {
+	if archiveAttachment.FileHash == "" {
+		return nil
+	}
+
+	if err := s.blobRepo.WithTx(tx).Release(archiveAttachment.FileHash, submittedBy); err != nil {
+		return fmt.Errorf("release attachment blob: %w", err)
+	}
+
+	return nil
+}
+
+// findDuplicates fills Duplicates on freshly created attachments. A failed
+// lookup only loses the warning, so it is logged rather than returned.
+func (s *ArchiveService) findDuplicates(archiveAttachments []*model.ArchiveAttachment, user *model.User) {
+	for _, archiveAttachment := range archiveAttachments {
+		duplicates, err := s.attachmentRepo.FindDuplicates(archiveAttachment.FileHash, archiveAttachment.ID, s.accessFor(user))
+		if err != nil {
+			logger.Log.Warn("archive.attachment.find_duplicates.failed",
+				zap.Uint("attachment_id", archiveAttachment.ID),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		archiveAttachment.Duplicates = duplicates
+	}
 }
 
-func (s *ArchiveService) GetAllArchivesByData(getArchiveByDataRequest request.GetArchiveByDataRequest) ([]model.ArchiveHdr, error) {
-	return s.repo.GetAllArchivesByData(getArchiveByDataRequest)
+// blobKey is the backend key of the blob holding content with the given hash.
+func blobKey(hash string, fileExt string) string {
+	return path.Join("blobs", hash[:2], hash+"."+fileExt)
 }
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mugnialby/arsip-backend/internal/model"
+	"github.com/mugnialby/arsip-backend/internal/repository"
+	"github.com/mugnialby/arsip-backend/internal/storage"
+	"gorm.io/gorm"
+)
+
+// memoryBlobRepository keeps blobs by hash. Acquire follows the database
+// implementation: an existing row gains a reference and is returned.
+type memoryBlobRepository struct {
+	repository.AttachmentBlobRepository
+	blobs map[string]*model.AttachmentBlob
+}
+
+func (r *memoryBlobRepository) WithTx(tx *gorm.DB) repository.AttachmentBlobRepository {
+	return r
+}
+
+func (r *memoryBlobRepository) Acquire(blob *model.AttachmentBlob) (bool, error) {
+	existing, ok := r.blobs[blob.Hash]
+	if !ok {
+		blob.RefCount = 1
+		stored := *blob
+		r.blobs[blob.Hash] = &stored
+		return true, nil
+	}
+
+	existing.RefCount++
+	*blob = *existing
+	return false, nil
+}
+
+type memoryAttachmentRepository struct {
+	repository.ArchiveAttachmentRepository
+	created []*model.ArchiveAttachment
+}
+
+func (r *memoryAttachmentRepository) WithTx(tx *gorm.DB) repository.ArchiveAttachmentRepository {
+	return r
+}
+
+func (r *memoryAttachmentRepository) NextSortOrder(archiveID uint) (int, error) {
+	return len(r.created) + 1, nil
+}
+
+func (r *memoryAttachmentRepository) Create(archiveAttachment *model.ArchiveAttachment) error {
+	archiveAttachment.ID = uint(len(r.created) + 1)
+	r.created = append(r.created, archiveAttachment)
+	return nil
+}
+
+func TestAttachStagedFileRestoresReclaimedBlob(t *testing.T) {
+	ctx := context.Background()
+	backend := newTestStorage(t)
+	blobRepo := &memoryBlobRepository{blobs: map[string]*model.AttachmentBlob{}}
+	service := &ArchiveService{
+		storage:        backend,
+		blobRepo:       blobRepo,
+		attachmentRepo: &memoryAttachmentRepository{},
+	}
+
+	attach := func() *model.ArchiveAttachment {
+		t.Helper()
+
+		staging := newFileStaging(backend)
+		defer staging.Cleanup()
+
+		file, err := staging.Stage(testPDF)
+		if err != nil {
+			t.Fatalf("Stage: %v", err)
+		}
+
+		archiveAttachment, err := service.attachStagedFile(nil, file, 10, "pdf", "application/pdf", "tester")
+		if err != nil {
+			t.Fatalf("attachStagedFile: %v", err)
+		}
+
+		if err := staging.Commit(); err != nil {
+			t.Fatalf("Commit: %v", err)
+		}
+
+		return archiveAttachment
+	}
+
+	first := attach()
+	key := first.FileLocation
+	if _, err := backend.Stat(ctx, key); err != nil {
+		t.Fatalf("first upload not stored: %v", err)
+	}
+
+	// The attachment is deleted and an administrator reclaims the
+	// unreferenced blob's file.
+	blobRepo.blobs[first.FileHash].RefCount = 0
+	if err := backend.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+
+	second := attach()
+	if second.FileLocation != key {
+		t.Fatalf("second upload stored at %s, want the blob key %s", second.FileLocation, key)
+	}
+	if _, err := backend.Stat(ctx, key); err != nil {
+		t.Fatalf("reclaimed blob not stored again: %v", err)
+	}
+
+	// A blob that is still referenced is not written again.
+	if err := backend.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	attach()
+	if _, err := backend.Stat(ctx, key); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("referenced blob was stored again: err = %v", err)
+	}
+}
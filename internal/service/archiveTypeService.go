@@ -1,6 +1,7 @@
 package service
 
 import (
+	"github.com/mugnialby/arsip-backend/internal/listquery"
 	"github.com/mugnialby/arsip-backend/internal/model"
 	request "github.com/mugnialby/arsip-backend/internal/model/dto/request/archiveType"
 	"github.com/mugnialby/arsip-backend/internal/repository"
@@ -14,8 +15,8 @@ func NewArchiveTypeService(repo repository.ArchiveTypeRepository) *ArchiveTypeSe
 	return &ArchiveTypeService{repo: repo}
 }
 
-func (s *ArchiveTypeService) GetAllArchiveTypes() ([]model.ArchiveType, error) {
-	return s.repo.FindAll()
+func (s *ArchiveTypeService) GetAllArchiveTypes(query listquery.Query) ([]model.ArchiveType, listquery.Result, error) {
+	return s.repo.FindAll(query)
 }
 
 func (s *ArchiveTypeService) GetArchiveTypeByID(id uint) (*model.ArchiveType, error) {
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+var (
+	ErrAttachmentTypeNotFound   = errors.New("attachment file type not found")
+	ErrAttachmentTypeNotAllowed = errors.New("attachment file type not allowed")
+	ErrAttachmentInvalidData    = errors.New("invalid base64 attachment data")
+	ErrAttachmentTooLarge       = errors.New("attachment exceeds the maximum upload size")
+	ErrAttachmentTypeMismatch   = errors.New("attachment content does not match its declared type")
+)
+
+var allowedExtensions = map[string]bool{
+	"jpg":  true,
+	"jpeg": true,
+	"png":  true,
+	"pdf":  true,
+}
+
+// allowedMimeTypes maps the detected content types we accept to the
+// extension the file is stored with.
+var allowedMimeTypes = map[string]string{
+	"image/jpeg":      "jpg",
+	"image/png":       "png",
+	"application/pdf": "pdf",
+}
+
+// sniffLength is how many leading bytes are read to detect a file's type.
+const sniffLength = 3072
+
+// attachmentUpload is a decoded attachment waiting to be written to storage
+type attachmentUpload struct {
+	Extension string
+	MimeType  string
+	Data      []byte
+}
+
+// decodeBase64Attachment decodes a data URL sent by the front-end and checks
+// that its type is allowed.
+func decodeBase64Attachment(fileBase64 string) (*attachmentUpload, error) {
+	base64Data := fileBase64
+	if strings.Contains(base64Data, ",") {
+		parts := strings.SplitN(base64Data, ",", 2)
+		base64Data = parts[1]
+	}
+
+	fileExt := DetectBase64Extension(fileBase64)
+	if fileExt == "" {
+		return nil, ErrAttachmentTypeNotFound
+	}
+
+	if !isAllowedFileType(fileExt) {
+		return nil, ErrAttachmentTypeNotAllowed
+	}
+
+	decodedBytes, err := base64.StdEncoding.DecodeString(base64Data)
+	if err != nil {
+		return nil, ErrAttachmentInvalidData
+	}
+
+	mimeType, fileExt, err := detectFileType(decodedBytes, fileExt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &attachmentUpload{
+		Extension: fileExt,
+		MimeType:  mimeType,
+		Data:      decodedBytes,
+	}, nil
+}
+
+// detectFileType sniffs the content type from the leading bytes of a file and
+// rejects content that is not allowed or does not match the declared
+// extension. It returns the MIME type and the extension to store the file
+// with.
+func detectFileType(head []byte, declaredExt string) (string, string, error) {
+	detected := mimetype.Detect(head)
+
+	for mimeType, fileExt := range allowedMimeTypes {
+		if !detected.Is(mimeType) {
+			continue
+		}
+
+		if normalizeExtension(declaredExt) != fileExt {
+			return "", "", ErrAttachmentTypeMismatch
+		}
+
+		return mimeType, fileExt, nil
+	}
+
+	return "", "", ErrAttachmentTypeNotAllowed
+}
+
+// detectReaderType sniffs the type of a stream. The returned reader yields
+// the complete stream, including the bytes consumed for detection.
+func detectReaderType(r io.Reader, declaredExt string) (string, string, io.Reader, error) {
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", "", nil, err
+	}
+	head = head[:n]
+
+	mimeType, fileExt, err := detectFileType(head, declaredExt)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	return mimeType, fileExt, io.MultiReader(bytes.NewReader(head), r), nil
+}
+
+// detectStoredFileType sniffs the type of a file already on disk.
+func detectStoredFileType(path string, declaredExt string) (string, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", "", err
+	}
+	defer file.Close()
+
+	mimeType, fileExt, _, err := detectReaderType(file, declaredExt)
+	return mimeType, fileExt, err
+}
+
+func normalizeExtension(ext string) string {
+	ext = strings.ToLower(strings.TrimPrefix(ext, "."))
+	if ext == "jpeg" {
+		return "jpg"
+	}
+
+	return ext
+}
+
+func DetectBase64Extension(base64Str string) string {
+	header := ""
+
+	if strings.Contains(base64Str, ",") {
+		parts := strings.SplitN(base64Str, ",", 2)
+		header = parts[0]
+	} else {
+		return ""
+	}
+
+	switch {
+	case strings.Contains(header, "image/jpeg"):
+		return "jpg"
+	case strings.Contains(header, "image/png"):
+		return "png"
+	case strings.Contains(header, "application/pdf"):
+		return "pdf"
+	case strings.Contains(header, "image/webp"):
+		return "webp"
+	default:
+		return ""
+	}
+}
+
+// extensionFromFileName returns the lower-cased extension of an uploaded file
+// name and checks that it is allowed.
+func extensionFromFileName(fileName string) (string, error) {
+	fileExt := strings.ToLower(strings.TrimPrefix(filepath.Ext(fileName), "."))
+	if fileExt == "" {
+		return "", ErrAttachmentTypeNotFound
+	}
+
+	if !isAllowedFileType(fileExt) {
+		return "", ErrAttachmentTypeNotAllowed
+	}
+
+	return fileExt, nil
+}
+
+// Validate file extension
+func isAllowedFileType(ext string) bool {
+	ext = strings.ToLower(ext)
+	return allowedExtensions[ext]
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"testing"
+)
+
+var (
+	testJPEG = []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00")
+	testPNG  = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	testPDF  = []byte("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+)
+
+func TestDecodeBase64Attachment(t *testing.T) {
+	dataURL := func(mimeType string, data []byte) string {
+		return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data)
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		mimeType string
+		ext      string
+		wantErr  error
+	}{
+		{name: "jpeg", input: dataURL("image/jpeg", testJPEG), mimeType: "image/jpeg", ext: "jpg"},
+		{name: "png", input: dataURL("image/png", testPNG), mimeType: "image/png", ext: "png"},
+		{name: "pdf", input: dataURL("application/pdf", testPDF), mimeType: "application/pdf", ext: "pdf"},
+		{name: "no header", input: base64.StdEncoding.EncodeToString(testPNG), wantErr: ErrAttachmentTypeNotFound},
+		{name: "unknown type", input: dataURL("image/gif", []byte("GIF89a")), wantErr: ErrAttachmentTypeNotFound},
+		{name: "declared png, jpeg content", input: dataURL("image/png", testJPEG), wantErr: ErrAttachmentTypeMismatch},
+		{name: "declared jpeg, pdf content", input: dataURL("image/jpeg", testPDF), wantErr: ErrAttachmentTypeMismatch},
+		{name: "declared pdf, unknown content", input: dataURL("application/pdf", []byte("plain text")), wantErr: ErrAttachmentTypeNotAllowed},
+		{name: "bad base64", input: "data:image/png;base64,!!!", wantErr: ErrAttachmentInvalidData},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upload, err := decodeBase64Attachment(tt.input)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("decodeBase64Attachment: %v", err)
+			}
+			if upload.MimeType != tt.mimeType || upload.Extension != tt.ext {
+				t.Fatalf("got %s (%s), want %s (%s)", upload.MimeType, upload.Extension, tt.mimeType, tt.ext)
+			}
+		})
+	}
+}
+
+func TestDetectReaderTypeKeepsSniffedBytes(t *testing.T) {
+	content := append(append([]byte{}, testPDF...), bytes.Repeat([]byte("x"), sniffLength)...)
+
+	mimeType, fileExt, reader, err := detectReaderType(bytes.NewReader(content), ".PDF")
+	if err != nil {
+		t.Fatalf("detectReaderType: %v", err)
+	}
+	if mimeType != "application/pdf" || fileExt != "pdf" {
+		t.Fatalf("got %s (%s), want application/pdf (pdf)", mimeType, fileExt)
+	}
+
+	got, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Fatalf("reader returned %d bytes, want the whole %d", len(got), len(content))
+	}
+}
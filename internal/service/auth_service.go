@@ -1,12 +1,220 @@
 package service
 
+import (
+	"errors"
+	"time"
+
+	"github.com/mugnialby/arsip-backend/internal/model"
+	authRequest "github.com/mugnialby/arsip-backend/internal/model/dto/request/auth"
+	authResponse "github.com/mugnialby/arsip-backend/internal/model/dto/response/auth"
+	"github.com/mugnialby/arsip-backend/internal/repository"
+	"github.com/mugnialby/arsip-backend/internal/utils"
+	"github.com/mugnialby/arsip-backend/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidCredentials  = errors.New("invalid user id or password")
+	ErrInvalidAccessToken  = errors.New("invalid or expired access token")
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+)
+
 type AuthService struct {
+	userRepo         repository.UserRepository
+	refreshTokenRepo repository.RefreshTokenRepository
+	jwtService       *utils.JWTService
+	refreshExpiresIn time.Duration
+}
+
+func NewAuthService(
+	userRepo repository.UserRepository,
+	refreshTokenRepo repository.RefreshTokenRepository,
+	jwtService *utils.JWTService,
+	refreshExpiresIn time.Duration,
+) *AuthService {
+	return &AuthService{
+		userRepo:         userRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		jwtService:       jwtService,
+		refreshExpiresIn: refreshExpiresIn,
+	}
+}
+
+func (s *AuthService) Login(loginRequest *authRequest.LoginRequest) (*authResponse.LoginResponse, error) {
+	user, err := s.userRepo.FindActiveByUserID(loginRequest.UserId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.verifyPassword(user, loginRequest.Password) {
+		return nil, ErrInvalidCredentials
+	}
+
+	loginResponse, _, err := s.issueTokens(user)
+	return loginResponse, err
+}
+
+// Refresh exchanges a refresh token for a new access/refresh token pair. The
+// presented token is revoked on use; presenting an already revoked token is
+// treated as theft and revokes every session of that user.
+func (s *AuthService) Refresh(refreshTokenRequest *authRequest.RefreshTokenRequest) (*authResponse.LoginResponse, error) {
+	refreshToken, err := s.refreshTokenRepo.FindByTokenHash(utils.HashOpaqueToken(refreshTokenRequest.RefreshToken))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if refreshToken.RevokedAt != nil {
+		logger.Log.Warn("auth.refresh.token_reuse_detected",
+			zap.Uint("user_id", refreshToken.UserID),
+			zap.Uint("refresh_token_id", refreshToken.ID),
+		)
+
+		if err := s.refreshTokenRepo.RevokeAllByUserID(refreshToken.UserID, "SYSTEM"); err != nil {
+			return nil, err
+		}
+
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if time.Now().After(refreshToken.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := s.userRepo.FindActiveByID(refreshToken.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	loginResponse, newRefreshToken, err := s.issueTokens(user)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := s.refreshTokenRepo.Revoke(refreshToken.ID, &newRefreshToken.ID, user.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !revoked {
+		// Lost the race against a concurrent refresh with the same token.
+		if _, err := s.refreshTokenRepo.Revoke(newRefreshToken.ID, nil, user.UserId); err != nil {
+			return nil, err
+		}
+
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return loginResponse, nil
+}
+
+func (s *AuthService) Logout(refreshTokenRequest *authRequest.RefreshTokenRequest) error {
+	refreshToken, err := s.refreshTokenRepo.FindByTokenHash(utils.HashOpaqueToken(refreshTokenRequest.RefreshToken))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = s.refreshTokenRepo.Revoke(refreshToken.ID, nil, "SYSTEM")
+	return err
 }
 
-func NewAuthService() *AuthService {
-	return &AuthService{}
+// Authenticate validates an access token and returns the user it was issued
+// to, provided that user is still active.
+func (s *AuthService) Authenticate(accessToken string) (*model.User, error) {
+	claims, err := s.jwtService.ValidateToken(accessToken)
+	if err != nil {
+		return nil, ErrInvalidAccessToken
+	}
+
+	user, err := s.userRepo.FindActiveByID(claims.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidAccessToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
 }
 
-// func (s *AuthService) GetAuthByID(id uint) (*model.Auth, error) {
-// 	return s.repo.FindByID(id)
-// }
+func (s *AuthService) issueTokens(user *model.User) (*authResponse.LoginResponse, *model.RefreshToken, error) {
+	accessToken, err := s.jwtService.GenerateToken(user.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	refreshTokenValue, refreshTokenHash, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	refreshToken := model.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: refreshTokenHash,
+		ExpiresAt: time.Now().Add(s.refreshExpiresIn),
+		Status:    "Y",
+		CreatedBy: user.UserId,
+	}
+
+	if err := s.refreshTokenRepo.Create(&refreshToken); err != nil {
+		return nil, nil, err
+	}
+
+	return &authResponse.LoginResponse{
+		AccessToken:      accessToken,
+		TokenType:        "Bearer",
+		ExpiresIn:        int64(s.jwtService.ExpiresIn().Seconds()),
+		RefreshToken:     refreshTokenValue,
+		RefreshExpiresIn: int64(s.refreshExpiresIn.Seconds()),
+		User:             user,
+	}, &refreshToken, nil
+}
+
+// verifyPassword checks the password against the stored bcrypt hash. Rows that
+// still hold a legacy plaintext password are compared directly and, on a
+// match, rehashed in place so the next login goes through bcrypt.
+func (s *AuthService) verifyPassword(user *model.User, password string) bool {
+	if utils.IsPasswordHashed(user.PasswordHash) {
+		return utils.CheckPasswordHash(password, user.PasswordHash)
+	}
+
+	if !utils.CheckLegacyPassword(password, user.PasswordHash) {
+		return false
+	}
+
+	hashed, err := utils.HashPassword(password)
+	if err != nil {
+		logger.Log.Error("auth.login.rehash_legacy_password.failed",
+			zap.Uint("user_id", user.ID),
+			zap.Error(err),
+		)
+		return true
+	}
+
+	if err := s.userRepo.UpdatePasswordHash(user.ID, hashed); err != nil {
+		logger.Log.Error("auth.login.rehash_legacy_password.failed",
+			zap.Uint("user_id", user.ID),
+			zap.Error(err),
+		)
+		return true
+	}
+
+	user.PasswordHash = hashed
+	logger.Log.Info("auth.login.rehash_legacy_password.success",
+		zap.Uint("user_id", user.ID),
+	)
+
+	return true
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"github.com/mugnialby/arsip-backend/internal/listquery"
 	"github.com/mugnialby/arsip-backend/internal/model"
 	request "github.com/mugnialby/arsip-backend/internal/model/dto/request/department"
 	"github.com/mugnialby/arsip-backend/internal/repository"
@@ -14,8 +15,8 @@ func NewDepartmentService(repo repository.DepartmentRepository) *DepartmentServi
 	return &DepartmentService{repo: repo}
 }
 
-func (s *DepartmentService) GetAllDepartments() ([]model.Department, error) {
-	return s.repo.FindAll()
+func (s *DepartmentService) GetAllDepartments(query listquery.Query) ([]model.Department, listquery.Result, error) {
+	return s.repo.FindAll(query)
 }
 
 func (s *DepartmentService) GetDepartmentByID(id uint) (*model.Department, error) {
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+	"github.com/mugnialby/arsip-backend/internal/storage"
+	"github.com/mugnialby/arsip-backend/internal/utils"
+	"github.com/mugnialby/arsip-backend/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// stagedFile is a file waiting in the tmp area. Hash and Size are known once
+// it is staged; it is only stored in the backend after StoreAs.
+type stagedFile struct {
+	Hash string
+	Size int64
+
+	tempPath    string
+	key         string
+	contentType string
+	stored      bool
+	adopted     bool
+}
+
+// StoreAs marks the file to be stored under key when the staging commits.
+// Files that are never marked, such as duplicates of an existing blob, are
+// only cleaned up.
+func (sf *stagedFile) StoreAs(key string, contentType string) {
+	sf.key = key
+	sf.contentType = contentType
+}
+
+// fileStaging collects files written during a database transaction. Files are
+// first written to storage/tmp and only stored in the backend by Commit, so a
+// failed transaction never leaves partial uploads behind.
+type fileStaging struct {
+	backend storage.Backend
+	files   []*stagedFile
+}
+
+func newFileStaging(backend storage.Backend) *fileStaging {
+	return &fileStaging{backend: backend}
+}
+
+// Stage writes data to the tmp area.
+func (f *fileStaging) Stage(data []byte) (*stagedFile, error) {
+	return f.StageReader(bytes.NewReader(data), 0)
+}
+
+// StageReader streams r to the tmp area, hashing it on the way. When maxSize
+// is positive, more than maxSize bytes fail with ErrAttachmentTooLarge.
+func (f *fileStaging) StageReader(r io.Reader, maxSize int64) (*stagedFile, error) {
+	storageLocation, err := utils.GetStorageLocation()
+	if err != nil {
+		return nil, err
+	}
+
+	tmpDir := filepath.Join(storageLocation, "tmp")
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		return nil, fmt.Errorf("create tmp directory: %w", err)
+	}
+
+	tempPath := filepath.Join(tmpDir, uuid.NewString())
+	tempFile, err := os.OpenFile(tempPath, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("create staged file: %w", err)
+	}
+
+	if maxSize > 0 {
+		r = io.LimitReader(r, maxSize+1)
+	}
+
+	hash := sha256.New()
+	written, err := io.Copy(io.MultiWriter(tempFile, hash), r)
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tempPath)
+		return nil, fmt.Errorf("write staged file: %w", err)
+	}
+
+	if maxSize > 0 && written > maxSize {
+		_ = os.Remove(tempPath)
+		return nil, ErrAttachmentTooLarge
+	}
+
+	file := &stagedFile{
+		Hash:     hex.EncodeToString(hash.Sum(nil)),
+		Size:     written,
+		tempPath: tempPath,
+	}
+	f.files = append(f.files, file)
+	return file, nil
+}
+
+// Adopt stages a file that already exists in the tmp area, such as a
+// completed chunked upload. On rollback the file is left in place so the
+// upload can be retried.
+func (f *fileStaging) Adopt(tempPath string) (*stagedFile, error) {
+	hash, size, err := hashFile(tempPath)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &stagedFile{
+		Hash:     hash,
+		Size:     size,
+		tempPath: tempPath,
+		adopted:  true,
+	}
+	f.files = append(f.files, file)
+	return file, nil
+}
+
+// Commit stores every file marked with StoreAs in the backend. The tmp copies
+// are kept until Cleanup so adopted files survive a later rollback.
+func (f *fileStaging) Commit() error {
+	for _, file := range f.files {
+		if file.key == "" {
+			continue
+		}
+
+		if err := f.store(file); err != nil {
+			return err
+		}
+
+		file.stored = true
+	}
+
+	return nil
+}
+
+func (f *fileStaging) store(file *stagedFile) error {
+	tempFile, err := os.Open(file.tempPath)
+	if err != nil {
+		return fmt.Errorf("open staged file: %w", err)
+	}
+	defer tempFile.Close()
+
+	info, err := tempFile.Stat()
+	if err != nil {
+		return fmt.Errorf("stat staged file: %w", err)
+	}
+
+	if err := f.backend.Put(context.Background(), file.key, tempFile, info.Size(), file.contentType); err != nil {
+		return fmt.Errorf("store staged file: %w", err)
+	}
+
+	return nil
+}
+
+// Cleanup removes the tmp copies once the transaction has committed.
+func (f *fileStaging) Cleanup() {
+	for _, file := range f.files {
+		f.remove(file.tempPath)
+	}
+
+	f.files = nil
+}
+
+// Rollback deletes objects already stored in the backend and removes staged
+// tmp files. Adopted files are left in the tmp area.
+func (f *fileStaging) Rollback() {
+	for _, file := range f.files {
+		if file.stored {
+			if err := f.backend.Delete(context.Background(), file.key); err != nil {
+				logger.Log.Error("service.file_staging.rollback.failed",
+					zap.String("key", file.key),
+					zap.Error(err),
+				)
+			}
+		}
+
+		if !file.adopted {
+			f.remove(file.tempPath)
+		}
+	}
+
+	f.files = nil
+}
+
+func (f *fileStaging) remove(path string) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		logger.Log.Error("service.file_staging.remove.failed",
+			zap.String("path", path),
+			zap.Error(err),
+		)
+	}
+}
+
+func hashFile(path string) (string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
@@ -0,0 +1,295 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mugnialby/arsip-backend/internal/model"
+	"github.com/mugnialby/arsip-backend/internal/repository"
+	"github.com/mugnialby/arsip-backend/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var ErrJobNotFound = errors.New("job not found")
+
+const (
+	// jobPollInterval is how often idle workers look for jobs queued by
+	// another process or missed while all workers were busy.
+	jobPollInterval = 5 * time.Second
+
+	// A running job's worker sends a heartbeat every jobHeartbeatInterval.
+	// Jobs without one for jobLeaseTimeout are taken to belong to a process
+	// that died and are queued again.
+	jobHeartbeatInterval = 30 * time.Second
+	jobLeaseTimeout      = 2 * time.Minute
+)
+
+// JobService runs background jobs with a pool of in-process workers. Jobs are
+// rows in the jobs table, so they survive a restart; the wake channel only
+// saves idle workers from waiting for the next poll.
+type JobService struct {
+	repo           repository.JobRepository
+	archiveService *ArchiveService
+
+	wake chan struct{}
+}
+
+func NewJobService(repo repository.JobRepository, archiveService *ArchiveService) *JobService {
+	return &JobService{
+		repo:           repo,
+		archiveService: archiveService,
+		wake:           make(chan struct{}, 1),
+	}
+}
+
+// Start starts workers goroutines and a loop that requeues jobs whose
+// worker stopped sending heartbeats, such as jobs interrupted by a shutdown.
+func (s *JobService) Start(workers int) {
+	go func() {
+		ticker := time.NewTicker(jobLeaseTimeout)
+		defer ticker.Stop()
+
+		for {
+			s.requeueStale()
+			<-ticker.C
+		}
+	}()
+
+	for i := 0; i < workers; i++ {
+		go s.work("worker-" + strconv.Itoa(i+1))
+	}
+}
+
+func (s *JobService) requeueStale() {
+	requeued, err := s.repo.RequeueStale(time.Now().Add(-jobLeaseTimeout), "SYSTEM")
+	if err != nil {
+		logger.Log.Error("job.requeue.failed",
+			zap.Error(err),
+		)
+		return
+	}
+
+	if requeued > 0 {
+		logger.Log.Info("job.requeue.success",
+			zap.Int64("count", requeued),
+		)
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// EnqueuePDFBuild queues a merged PDF build for an archive the caller can
+// access. A build already queued or running for the archive is returned
+// instead of queueing another.
+func (s *JobService) EnqueuePDFBuild(archiveID uint, user *model.User) (*model.Job, error) {
+	if _, err := s.archiveService.GetArchiveByID(archiveID, user); err != nil {
+		return nil, err
+	}
+
+	pendingJob, err := s.repo.FindPending(model.JobKindPDFBuild, archiveID)
+	if err == nil {
+		return pendingJob, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	job := &model.Job{
+		JobID:        uuid.NewString(),
+		Kind:         model.JobKindPDFBuild,
+		ArchiveHdrID: archiveID,
+		State:        model.JobStateQueued,
+		Status:       "Y",
+		CreatedBy:    user.UserId,
+	}
+
+	if err := s.repo.Create(job); err != nil {
+		return nil, fmt.Errorf("create job: %w", err)
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+
+	return job, nil
+}
+
+// GetJob returns a job to any user who can access its archive, since
+// EnqueuePDFBuild hands out a pending job to everyone asking for the same
+// build. Other callers get ErrJobNotFound.
+func (s *JobService) GetJob(jobID string, user *model.User) (*model.Job, error) {
+	job, err := s.repo.FindByJobID(jobID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.archiveService.GetArchiveByID(job.ArchiveHdrID, user); err != nil {
+		if errors.Is(err, ErrArchiveForbidden) || errors.Is(err, ErrArchiveNotFound) {
+			return nil, ErrJobNotFound
+		}
+		return nil, err
+	}
+
+	return job, nil
+}
+
+func (s *JobService) work(workerID string) {
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+
+	for {
+		job, err := s.repo.ClaimNext(workerID)
+		if err == nil {
+			s.run(workerID, job)
+			continue
+		}
+
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Log.Error("job.claim.failed",
+				zap.String("worker", workerID),
+				zap.Error(err),
+			)
+		}
+
+		select {
+		case <-s.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *JobService) run(workerID string, job *model.Job) {
+	start := time.Now()
+
+	stopHeartbeat := make(chan struct{})
+	defer close(stopHeartbeat)
+	go s.heartbeat(workerID, job, stopHeartbeat)
+
+	var resultURL string
+	var err error
+	switch job.Kind {
+	case model.JobKindPDFBuild:
+		resultURL, err = s.runPDFBuild(workerID, job)
+	default:
+		err = fmt.Errorf("unknown job kind %q", job.Kind)
+	}
+
+	if err != nil {
+		logger.Log.Error("job.run.failed",
+			zap.String("job_id", job.JobID),
+			zap.String("kind", job.Kind),
+			zap.Uint("archive_id", job.ArchiveHdrID),
+			zap.Error(err),
+			zap.Duration("duration_ms", time.Since(start)),
+		)
+
+		if err := s.repo.Fail(job, err.Error(), workerID); errors.Is(err, repository.ErrJobLeaseLost) {
+			logJobLeaseLost(job)
+		} else if err != nil {
+			logger.Log.Error("job.fail.save_failed",
+				zap.String("job_id", job.JobID),
+				zap.Error(err),
+			)
+		}
+		return
+	}
+
+	err = s.repo.Complete(job, resultURL, workerID)
+	if errors.Is(err, repository.ErrJobLeaseLost) {
+		logJobLeaseLost(job)
+		return
+	}
+	if err != nil {
+		logger.Log.Error("job.complete.save_failed",
+			zap.String("job_id", job.JobID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	logger.Log.Info("job.run.success",
+		zap.String("job_id", job.JobID),
+		zap.String("kind", job.Kind),
+		zap.Uint("archive_id", job.ArchiveHdrID),
+		zap.Duration("duration_ms", time.Since(start)),
+	)
+}
+
+// heartbeat keeps the job's lease until stop is closed or the lease is lost.
+func (s *JobService) heartbeat(workerID string, job *model.Job, stop <-chan struct{}) {
+	ticker := time.NewTicker(jobHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			err := s.repo.Heartbeat(job, workerID)
+			if errors.Is(err, repository.ErrJobLeaseLost) {
+				return
+			}
+			if err != nil {
+				logger.Log.Warn("job.heartbeat.failed",
+					zap.String("job_id", job.JobID),
+					zap.Error(err),
+				)
+			}
+		}
+	}
+}
+
+// logJobLeaseLost reports a job that was requeued while this worker ran it,
+// most likely because heartbeats could not be saved. Its result is left to
+// the run that claimed it again.
+func logJobLeaseLost(job *model.Job) {
+	logger.Log.Warn("job.run.lease_lost",
+		zap.String("job_id", job.JobID),
+		zap.String("kind", job.Kind),
+		zap.Uint("archive_id", job.ArchiveHdrID),
+	)
+}
+
+// runPDFBuild fills the merged PDF cache. The result URL is the archive's
+// regular PDF endpoint, which serves the cached file until the attachments
+// change.
+func (s *JobService) runPDFBuild(workerID string, job *model.Job) (string, error) {
+	archive, err := s.archiveService.findArchive(job.ArchiveHdrID)
+	if err != nil {
+		return "", err
+	}
+
+	lastProgress := 0
+	progress := func(percent int) {
+		// Complete records 100 once the result is saved.
+		if percent == lastProgress || percent >= 100 {
+			return
+		}
+		lastProgress = percent
+
+		if err := s.repo.UpdateProgress(job, percent, workerID); err != nil {
+			logger.Log.Warn("job.progress.save_failed",
+				zap.String("job_id", job.JobID),
+				zap.Error(err),
+			)
+		}
+	}
+
+	if _, err := s.archiveService.MergedPDF(context.Background(), archive, progress); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("/api/archives/%d/pdf", archive.ID), nil
+}
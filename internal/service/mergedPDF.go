@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/mugnialby/arsip-backend/internal/model"
+	"github.com/mugnialby/arsip-backend/internal/pdf"
+	"github.com/mugnialby/arsip-backend/internal/utils"
+	"github.com/mugnialby/arsip-backend/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// MergedPDF returns the path of the archive's merged PDF, building it when no
+// cached copy exists. Cached files are named after a fingerprint of the
+// attachments they were built from, so a stale copy is never served even if an
+// invalidation is missed. Concurrent calls for the same fingerprint share one
+// build, and the result is moved into place with a rename so readers never
+// see a partial file. progress, when not nil, receives the build's completion
+// percentage.
+func (s *ArchiveService) MergedPDF(ctx context.Context, archive *model.ArchiveHdr, progress func(percent int)) (string, error) {
+	storageLocation, err := utils.GetStorageLocation()
+	if err != nil {
+		return "", err
+	}
+
+	cacheDir := mergedPDFCacheDir(storageLocation, archive.ID)
+	cachePath := filepath.Join(cacheDir, mergedPDFFingerprint(archive.ArchiveAttachments)+".pdf")
+
+	if _, err := os.Stat(cachePath); err == nil {
+		return cachePath, nil
+	}
+
+	_, err, shared := s.pdfBuilds.Do(cachePath, func() (any, error) {
+		if _, err := os.Stat(cachePath); err == nil {
+			return nil, nil
+		}
+
+		tmpDir := filepath.Join(storageLocation, "tmp")
+		if err := os.MkdirAll(tmpDir, 0755); err != nil {
+			return nil, fmt.Errorf("create tmp directory: %w", err)
+		}
+
+		// A download that disconnects does not cancel the build: a queued
+		// job or another download may share it, and the cached file serves
+		// the next request either way.
+		tmpPath := filepath.Join(tmpDir, uuid.NewString()+".pdf")
+		if err := s.BuildMergedPDF(context.WithoutCancel(ctx), archive, tmpPath, progress); err != nil {
+			return nil, err
+		}
+
+		if err := os.MkdirAll(cacheDir, 0755); err != nil {
+			_ = os.Remove(tmpPath)
+			return nil, fmt.Errorf("create cache directory: %w", err)
+		}
+
+		if err := os.Rename(tmpPath, cachePath); err != nil {
+			_ = os.Remove(tmpPath)
+			return nil, fmt.Errorf("move merged pdf into cache: %w", err)
+		}
+
+		return nil, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	logger.Log.Info("archive.merge_pdf.ready",
+		zap.Uint("archive_id", archive.ID),
+		zap.String("path", cachePath),
+		zap.Bool("shared", shared),
+	)
+
+	return cachePath, nil
+}
+
+// BuildMergedPDF merges the archive's images and PDFs into output in
+// attachment order, applying each attachment's rotation. Attachments whose
+// file cannot be read are skipped and logged; when nothing is left
+// pdf.ErrNoInputs is returned. progress may be nil.
+func (s *ArchiveService) BuildMergedPDF(ctx context.Context, archive *model.ArchiveHdr, output string, progress func(percent int)) error {
+	if progress == nil {
+		progress = func(int) {}
+	}
+
+	storageLocation, err := utils.GetStorageLocation()
+	if err != nil {
+		return err
+	}
+
+	tmpDir := filepath.Join(storageLocation, "tmp")
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		return fmt.Errorf("create tmp directory: %w", err)
+	}
+
+	// Fetching the files counts for the first half; the merge itself is not
+	// observable.
+	var inputs []pdf.Input
+	for i, archiveAttachment := range archive.ArchiveAttachments {
+		progress(i * 50 / len(archive.ArchiveAttachments))
+
+		localPath, cleanup, err := s.MaterializeAttachment(ctx, archiveAttachment, tmpDir)
+		defer cleanup()
+		if err != nil {
+			logger.Log.Warn("archive.merge_pdf.materialize_attachment.failed",
+				zap.Uint("archive_id", archive.ID),
+				zap.Uint("attachment_id", archiveAttachment.ID),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		input := pdf.Input{
+			Path:     localPath,
+			MimeType: archiveAttachment.MimeType,
+			Rotation: archiveAttachment.Rotation,
+		}
+		if input.IsImage() || archiveAttachment.MimeType == "application/pdf" {
+			inputs = append(inputs, input)
+		}
+	}
+
+	progress(50)
+	if err := s.merger.Merge(ctx, inputs, output); err != nil {
+		_ = os.Remove(output)
+		return err
+	}
+
+	progress(100)
+	return nil
+}
+
+// mergedPDFFingerprint identifies the content of a merged PDF: which
+// attachments go in, in which order and rotation, and what each one holds. Attachments
+// stored before hashing are identified by their storage key, which is never
+// reused.
+func mergedPDFFingerprint(archiveAttachments []*model.ArchiveAttachment) string {
+	hash := sha256.New()
+	for _, archiveAttachment := range archiveAttachments {
+		content := archiveAttachment.FileHash
+		if content == "" {
+			content = archiveAttachment.FileLocation
+		}
+
+		fmt.Fprintf(hash, "%d:%s:%s:%d\n", archiveAttachment.ID, content, archiveAttachment.MimeType, archiveAttachment.Rotation)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func mergedPDFCacheDir(storageLocation string, archiveID uint) string {
+	return filepath.Join(storageLocation, "cache", "archives", strconv.Itoa(int(archiveID)))
+}
+
+// removeMergedPDFCache drops every cached merged PDF of an archive after its
+// attachments changed. Stale files would never be served, since their
+// fingerprint no longer matches, but they would take up disk space.
+func (s *ArchiveService) removeMergedPDFCache(archiveID uint) {
+	storageLocation, err := utils.GetStorageLocation()
+	if err != nil {
+		logger.Log.Error("archive.cache.get_storage_location.failed",
+			zap.Uint("archive_id", archiveID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	cacheDir := mergedPDFCacheDir(storageLocation, archiveID)
+	if err := os.RemoveAll(cacheDir); err != nil {
+		logger.Log.Error("archive.cache.delete_cached_data.failed",
+			zap.String("path", cacheDir),
+			zap.Error(err),
+		)
+		return
+	}
+
+	logger.Log.Info("archive.cache.delete_cached_data.success",
+		zap.String("path", cacheDir),
+	)
+}
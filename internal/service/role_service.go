@@ -1,21 +1,41 @@
 package service
 
 import (
+	"errors"
+
+	"github.com/mugnialby/arsip-backend/internal/listquery"
 	"github.com/mugnialby/arsip-backend/internal/model"
 	request "github.com/mugnialby/arsip-backend/internal/model/dto/request/roles"
 	"github.com/mugnialby/arsip-backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRoleNotFound             = errors.New("role not found")
+	ErrUnknownPermission        = errors.New("unknown permission")
+	ErrPermissionAlreadyGranted = errors.New("permission already granted to role")
 )
 
 type RoleService struct {
-	repo repository.RoleRepository
+	repo               repository.RoleRepository
+	rolePermissionRepo repository.RolePermissionRepository
+	superuserRoleID    uint
 }
 
-func NewRoleService(repo repository.RoleRepository) *RoleService {
-	return &RoleService{repo: repo}
+func NewRoleService(
+	repo repository.RoleRepository,
+	rolePermissionRepo repository.RolePermissionRepository,
+	superuserRoleID uint,
+) *RoleService {
+	return &RoleService{
+		repo:               repo,
+		rolePermissionRepo: rolePermissionRepo,
+		superuserRoleID:    superuserRoleID,
+	}
 }
 
-func (s *RoleService) GetAllRoles() ([]model.Role, error) {
-	return s.repo.FindAll()
+func (s *RoleService) GetAllRoles(query listquery.Query) ([]model.Role, listquery.Result, error) {
+	return s.repo.FindAll(query)
 }
 
 func (s *RoleService) GetRoleByID(id uint) (*model.Role, error) {
@@ -37,3 +57,55 @@ func (s *RoleService) DeleteRole(deleteRoleRequest *request.DeleteRoleRequest) e
 func (s *RoleService) GetRoleByDepartmentID(departmentId uint) ([]model.Role, error) {
 	return s.repo.GetRoleByDepartmentID(departmentId)
 }
+
+func (s *RoleService) GetRolePermissions(roleID uint) ([]model.RolePermission, error) {
+	if err := s.ensureRoleExists(roleID); err != nil {
+		return nil, err
+	}
+
+	return s.rolePermissionRepo.FindByRoleID(roleID)
+}
+
+func (s *RoleService) AddRolePermission(rolePermission *model.RolePermission) error {
+	if !model.IsKnownPermission(rolePermission.Permission) {
+		return ErrUnknownPermission
+	}
+
+	if err := s.ensureRoleExists(rolePermission.RoleID); err != nil {
+		return err
+	}
+
+	granted, err := s.rolePermissionRepo.HasPermission(rolePermission.RoleID, rolePermission.Permission)
+	if err != nil {
+		return err
+	}
+
+	if granted {
+		return ErrPermissionAlreadyGranted
+	}
+
+	return s.rolePermissionRepo.Create(rolePermission)
+}
+
+func (s *RoleService) DeleteRolePermission(deleteRolePermissionRequest *request.DeleteRolePermissionRequest) error {
+	return s.rolePermissionRepo.Delete(deleteRolePermissionRequest)
+}
+
+// HasPermission reports whether the user's role has been granted the
+// permission. The superuser role is granted everything.
+func (s *RoleService) HasPermission(user *model.User, permission string) (bool, error) {
+	if user.RoleID == s.superuserRoleID {
+		return true, nil
+	}
+
+	return s.rolePermissionRepo.HasPermission(user.RoleID, permission)
+}
+
+func (s *RoleService) ensureRoleExists(roleID uint) error {
+	_, err := s.repo.FindByID(roleID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrRoleNotFound
+	}
+
+	return err
+}
@@ -0,0 +1,335 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/mugnialby/arsip-backend/internal/model"
+	"github.com/mugnialby/arsip-backend/internal/repository"
+	"github.com/mugnialby/arsip-backend/internal/storage"
+	"github.com/mugnialby/arsip-backend/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrScrubReportNotFound = errors.New("scrub report not found")
+	ErrScrubRunning        = errors.New("a scrub is already running")
+)
+
+// scrubBatchSize is how many attachments are loaded per query while scrubbing.
+const scrubBatchSize = 500
+
+// ScrubService verifies that every active attachment's file exists and still
+// matches the hash recorded at upload time, finds stored files that no
+// attachment refers to, and lists blobs only deleted attachments refer to.
+type ScrubService struct {
+	attachmentRepo repository.ArchiveAttachmentRepository
+	blobRepo       repository.AttachmentBlobRepository
+	reportRepo     repository.ScrubReportRepository
+	storage        storage.Backend
+
+	// orphanGracePeriod skips files younger than this when looking for
+	// orphans, since uploads store their file just before committing the row.
+	orphanGracePeriod time.Duration
+
+	running atomic.Bool
+}
+
+func NewScrubService(
+	attachmentRepo repository.ArchiveAttachmentRepository,
+	blobRepo repository.AttachmentBlobRepository,
+	reportRepo repository.ScrubReportRepository,
+	backend storage.Backend,
+	orphanGracePeriod time.Duration,
+) *ScrubService {
+	return &ScrubService{
+		attachmentRepo:    attachmentRepo,
+		blobRepo:          blobRepo,
+		reportRepo:        reportRepo,
+		storage:           backend,
+		orphanGracePeriod: orphanGracePeriod,
+	}
+}
+
+func (s *ScrubService) GetLatestReport() (*model.ScrubReport, error) {
+	scrubReport, err := s.reportRepo.FindLatest()
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrScrubReportNotFound
+	}
+
+	return scrubReport, err
+}
+
+func (s *ScrubService) GetReportByID(id uint) (*model.ScrubReport, error) {
+	scrubReport, err := s.reportRepo.FindByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrScrubReportNotFound
+	}
+
+	return scrubReport, err
+}
+
+// Start creates a report and runs the scrub in the background. Progress is
+// visible through the report's state.
+func (s *ScrubService) Start(submittedBy string) (*model.ScrubReport, error) {
+	if !s.running.CompareAndSwap(false, true) {
+		return nil, ErrScrubRunning
+	}
+
+	scrubReport, err := s.newReport(submittedBy)
+	if err != nil {
+		s.running.Store(false)
+		return nil, err
+	}
+
+	go func() {
+		defer s.running.Store(false)
+
+		if err := s.scrub(context.Background(), scrubReport); err != nil {
+			logger.Log.Error("scrub.run.failed",
+				zap.Uint("report_id", scrubReport.ID),
+				zap.Error(err),
+			)
+		}
+	}()
+
+	return scrubReport, nil
+}
+
+// Run scrubs synchronously and returns the finished report.
+func (s *ScrubService) Run(ctx context.Context, submittedBy string) (*model.ScrubReport, error) {
+	if !s.running.CompareAndSwap(false, true) {
+		return nil, ErrScrubRunning
+	}
+	defer s.running.Store(false)
+
+	scrubReport, err := s.newReport(submittedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.scrub(ctx, scrubReport); err != nil {
+		return scrubReport, err
+	}
+
+	return s.GetReportByID(scrubReport.ID)
+}
+
+func (s *ScrubService) newReport(submittedBy string) (*model.ScrubReport, error) {
+	scrubReport := &model.ScrubReport{
+		State:     model.ScrubStateRunning,
+		StartedAt: time.Now(),
+		Status:    "Y",
+		CreatedBy: submittedBy,
+	}
+
+	if err := s.reportRepo.Create(scrubReport); err != nil {
+		return nil, fmt.Errorf("create scrub report: %w", err)
+	}
+
+	return scrubReport, nil
+}
+
+// scrub fills in the report and marks it completed, or failed when the walk
+// itself could not finish.
+func (s *ScrubService) scrub(ctx context.Context, scrubReport *model.ScrubReport) error {
+	var issues []model.ScrubIssue
+
+	err := s.verifyAttachments(ctx, scrubReport, &issues)
+	if err == nil {
+		err = s.findOrphans(ctx, scrubReport, &issues)
+	}
+	if err == nil {
+		err = s.findUnreferencedBlobs(scrubReport, &issues)
+	}
+
+	finishedAt := time.Now()
+	scrubReport.FinishedAt = &finishedAt
+	scrubReport.State = model.ScrubStateCompleted
+	if err != nil {
+		scrubReport.State = model.ScrubStateFailed
+		scrubReport.Message = err.Error()
+	}
+
+	for i := range issues {
+		issues[i].ScrubReportID = scrubReport.ID
+	}
+
+	if issueErr := s.reportRepo.CreateIssues(issues); issueErr != nil && err == nil {
+		err = fmt.Errorf("save scrub issues: %w", issueErr)
+		scrubReport.State = model.ScrubStateFailed
+		scrubReport.Message = err.Error()
+	}
+
+	if updateErr := s.reportRepo.Update(scrubReport); updateErr != nil && err == nil {
+		err = fmt.Errorf("save scrub report: %w", updateErr)
+	}
+
+	logger.Log.Info("scrub.run.finished",
+		zap.Uint("report_id", scrubReport.ID),
+		zap.String("state", scrubReport.State),
+		zap.Int("checked", scrubReport.Checked),
+		zap.Int("missing", scrubReport.Missing),
+		zap.Int("corrupt", scrubReport.Corrupt),
+		zap.Int("orphaned", scrubReport.Orphaned),
+		zap.Int("unreferenced", scrubReport.Unreferenced),
+		zap.Duration("duration_ms", finishedAt.Sub(scrubReport.StartedAt)),
+	)
+
+	return err
+}
+
+// verifyAttachments hashes the file of every active attachment. Attachments
+// sharing a blob are only read once.
+func (s *ScrubService) verifyAttachments(ctx context.Context, scrubReport *model.ScrubReport, issues *[]model.ScrubIssue) error {
+	type verifiedKey struct {
+		hash string
+		err  error
+	}
+	verified := make(map[string]verifiedKey)
+
+	return s.attachmentRepo.FindActiveInBatches(scrubBatchSize, func(archiveAttachments []model.ArchiveAttachment) error {
+		for _, archiveAttachment := range archiveAttachments {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			result, ok := verified[archiveAttachment.FileLocation]
+			if !ok {
+				result.hash, result.err = s.hashObject(ctx, archiveAttachment.FileLocation)
+				verified[archiveAttachment.FileLocation] = result
+			}
+
+			scrubReport.Checked++
+
+			attachmentID := archiveAttachment.ID
+			archiveID := archiveAttachment.ArchiveHdrID
+			issue := model.ScrubIssue{
+				ArchiveAttachmentID: &attachmentID,
+				ArchiveHdrID:        &archiveID,
+				StorageKey:          archiveAttachment.FileLocation,
+			}
+
+			switch {
+			case errors.Is(result.err, storage.ErrNotFound):
+				scrubReport.Missing++
+				issue.Kind = model.ScrubIssueMissing
+			case result.err != nil:
+				scrubReport.Errors++
+				issue.Kind = model.ScrubIssueError
+				issue.Detail = result.err.Error()
+			case archiveAttachment.FileHash == "":
+				// Stored before hashes were recorded; existence is all we can check.
+				scrubReport.Unverified++
+				continue
+			case result.hash != archiveAttachment.FileHash:
+				scrubReport.Corrupt++
+				issue.Kind = model.ScrubIssueCorrupt
+				issue.Detail = fmt.Sprintf("expected sha256 %s, got %s", archiveAttachment.FileHash, result.hash)
+			default:
+				continue
+			}
+
+			*issues = append(*issues, issue)
+		}
+
+		return nil
+	})
+}
+
+// findOrphans lists stored files that neither an attachment nor a blob
+// refers to, including thumbnails of such files.
+func (s *ScrubService) findOrphans(ctx context.Context, scrubReport *model.ScrubReport, issues *[]model.ScrubIssue) error {
+	fileLocations, err := s.attachmentRepo.FindAllFileLocations()
+	if err != nil {
+		return fmt.Errorf("get attachment file locations: %w", err)
+	}
+
+	storageKeys, err := s.blobRepo.FindAllStorageKeys()
+	if err != nil {
+		return fmt.Errorf("get blob storage keys: %w", err)
+	}
+
+	referenced := make(map[string]bool, len(fileLocations)+len(storageKeys))
+	for _, key := range fileLocations {
+		referenced[key] = true
+	}
+	for _, key := range storageKeys {
+		referenced[key] = true
+	}
+
+	objects, err := s.storage.List(ctx, "")
+	if err != nil {
+		return fmt.Errorf("list stored files: %w", err)
+	}
+
+	cutoff := time.Now().Add(-s.orphanGracePeriod)
+	for _, object := range objects {
+		if referenced[object.Key] || object.ModTime.After(cutoff) {
+			continue
+		}
+
+		// Thumbnails belong to the file they were rendered from.
+		if source, ok := thumbnailSource(object.Key); ok && referenced[source] {
+			continue
+		}
+
+		scrubReport.Orphaned++
+		*issues = append(*issues, model.ScrubIssue{
+			Kind:       model.ScrubIssueOrphaned,
+			StorageKey: object.Key,
+			Detail:     fmt.Sprintf("%d bytes, modified %s", object.Size, object.ModTime.Format(time.RFC3339)),
+		})
+	}
+
+	return nil
+}
+
+// findUnreferencedBlobs lists blobs whose reference count dropped to zero.
+// Their files are kept so deleted attachments can be restored, so they are
+// reported for an administrator to reclaim rather than removed. A reclaimed
+// file is stored again when the same content is uploaded.
+func (s *ScrubService) findUnreferencedBlobs(scrubReport *model.ScrubReport, issues *[]model.ScrubIssue) error {
+	blobs, err := s.blobRepo.FindUnreferenced()
+	if err != nil {
+		return fmt.Errorf("get unreferenced blobs: %w", err)
+	}
+
+	for _, blob := range blobs {
+		since := blob.CreatedAt
+		if blob.ModifiedAt != nil {
+			since = *blob.ModifiedAt
+		}
+
+		scrubReport.Unreferenced++
+		*issues = append(*issues, model.ScrubIssue{
+			Kind:       model.ScrubIssueUnreferenced,
+			StorageKey: blob.StorageKey,
+			Detail:     fmt.Sprintf("%d bytes, unreferenced since %s", blob.Size, since.Format(time.RFC3339)),
+		})
+	}
+
+	return nil
+}
+
+func (s *ScrubService) hashObject(ctx context.Context, key string) (string, error) {
+	reader, err := s.storage.Get(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, reader); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mugnialby/arsip-backend/internal/model"
+	"github.com/mugnialby/arsip-backend/internal/pdf"
+	"github.com/mugnialby/arsip-backend/internal/utils"
+)
+
+// PDFExportOptions selects the provenance added to an exported archive PDF.
+type PDFExportOptions struct {
+	// Watermark stamps every page with the configured watermark template.
+	Watermark bool
+	// CoverPage adds a first page listing the archive's metadata.
+	CoverPage bool
+}
+
+// ExportPDF returns the path of the archive's merged PDF with the requested
+// watermark and cover page. A stamped copy names the user who downloaded it,
+// so it is written to a temporary file rather than cached; cleanup removes
+// it and must be called once the file has been sent. Without options the
+// cached merged PDF is returned and cleanup does nothing.
+func (s *ArchiveService) ExportPDF(ctx context.Context, archive *model.ArchiveHdr, user *model.User, options PDFExportOptions) (string, func(), error) {
+	noop := func() {}
+
+	mergedPDF, err := s.MergedPDF(ctx, archive, nil)
+	if err != nil {
+		return "", noop, err
+	}
+
+	var stamp pdf.Stamp
+	if options.Watermark {
+		stamp.Watermark = s.watermarkText(archive, user, time.Now())
+	}
+	if options.CoverPage {
+		stamp.CoverTitle, stamp.CoverLines = archiveCover(archive)
+	}
+
+	if stamp.IsEmpty() {
+		return mergedPDF, noop, nil
+	}
+
+	storageLocation, err := utils.GetStorageLocation()
+	if err != nil {
+		return "", noop, err
+	}
+
+	tmpDir := filepath.Join(storageLocation, "tmp")
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		return "", noop, fmt.Errorf("create tmp directory: %w", err)
+	}
+
+	stampedPDF := filepath.Join(tmpDir, uuid.NewString()+".pdf")
+	cleanup := func() { _ = os.Remove(stampedPDF) }
+
+	if err := pdf.Apply(ctx, mergedPDF, stampedPDF, stamp); err != nil {
+		cleanup()
+		return "", noop, fmt.Errorf("stamp pdf: %w", err)
+	}
+
+	return stampedPDF, cleanup, nil
+}
+
+// watermarkText fills in the watermark template: {user} is the user ID,
+// {name} the full name, {timestamp} the download time and {archiveNumber}
+// the archive's number.
+func (s *ArchiveService) watermarkText(archive *model.ArchiveHdr, user *model.User, at time.Time) string {
+	name := user.FullName
+	if name == "" {
+		name = user.UserId
+	}
+
+	return strings.NewReplacer(
+		"{user}", user.UserId,
+		"{name}", name,
+		"{timestamp}", at.Format("2006-01-02 15:04"),
+		"{archiveNumber}", archive.ArchiveNumber,
+	).Replace(s.watermarkTemplate)
+}
+
+// archiveCover lists the archive header's metadata for the cover page.
+// Relations that were not loaded are left blank.
+func archiveCover(archive *model.ArchiveHdr) (string, []pdf.CoverLine) {
+	var archiveType, characteristic, department, archiveDate string
+	if archive.ArchiveType != nil {
+		archiveType = archive.ArchiveType.ArchiveTypeName
+	}
+	if archive.ArchiveCharacteristic != nil {
+		characteristic = archive.ArchiveCharacteristic.ArchiveCharacteristicName
+	}
+	if archive.Department != nil {
+		department = archive.Department.DepartmentName
+	}
+	if !archive.ArchiveDate.IsZero() {
+		archiveDate = archive.ArchiveDate.Format("2006-01-02")
+	}
+
+	return archive.ArchiveName, []pdf.CoverLine{
+		{Label: "Number", Value: archive.ArchiveNumber},
+		{Label: "Type", Value: archiveType},
+		{Label: "Characteristic", Value: characteristic},
+		{Label: "Department", Value: department},
+		{Label: "Date", Value: archiveDate},
+		{Label: "Attachments", Value: strconv.Itoa(len(archive.ArchiveAttachments))},
+	}
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mugnialby/arsip-backend/internal/extract"
+	"github.com/mugnialby/arsip-backend/internal/model"
+	"github.com/mugnialby/arsip-backend/internal/repository"
+	"github.com/mugnialby/arsip-backend/internal/storage"
+	"github.com/mugnialby/arsip-backend/internal/utils"
+	"github.com/mugnialby/arsip-backend/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	textExtractionQueueSize = 256
+	textExtractionBatchSize = 100
+)
+
+// TextExtractionService extracts the text of image and PDF attachments so
+// they can be found by full-text search. New uploads are queued right away;
+// a periodic sweep picks up anything missed, such as attachments stored
+// before a restart or while the queue was full. Attachments whose extraction
+// failed are recorded and not retried.
+type TextExtractionService struct {
+	textRepo  repository.AttachmentTextRepository
+	storage   storage.Backend
+	extractor extract.Extractor
+
+	queue chan *model.ArchiveAttachment
+}
+
+// NewTextExtractionService returns a service that extracts nothing when
+// extractor is nil; search then only covers text extracted earlier.
+func NewTextExtractionService(
+	textRepo repository.AttachmentTextRepository,
+	backend storage.Backend,
+	extractor extract.Extractor,
+) *TextExtractionService {
+	return &TextExtractionService{
+		textRepo:  textRepo,
+		storage:   backend,
+		extractor: extractor,
+		queue:     make(chan *model.ArchiveAttachment, textExtractionQueueSize),
+	}
+}
+
+// Start runs workers goroutines that extract queued attachments and a sweep
+// that queues unextracted attachments every sweepInterval.
+func (s *TextExtractionService) Start(workers int, sweepInterval time.Duration) {
+	if s.extractor == nil {
+		return
+	}
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for archiveAttachment := range s.queue {
+				if err := s.Extract(context.Background(), archiveAttachment); err != nil {
+					logger.Log.Error("attachment_text.extract.failed",
+						zap.Uint("attachment_id", archiveAttachment.ID),
+						zap.Error(err),
+					)
+				}
+			}
+		}()
+	}
+
+	go func() {
+		ticker := time.NewTicker(sweepInterval)
+		defer ticker.Stop()
+
+		for {
+			s.sweep()
+			<-ticker.C
+		}
+	}()
+}
+
+// Enqueue schedules text extraction for the given attachments. When the queue
+// is full the attachment is skipped; the next sweep picks it up.
+func (s *TextExtractionService) Enqueue(archiveAttachments ...*model.ArchiveAttachment) {
+	if s.extractor == nil {
+		return
+	}
+
+	for _, archiveAttachment := range archiveAttachments {
+		if !extract.Supports(archiveAttachment.MimeType) {
+			continue
+		}
+
+		select {
+		case s.queue <- archiveAttachment:
+		default:
+			logger.Log.Warn("attachment_text.enqueue.queue_full",
+				zap.Uint("attachment_id", archiveAttachment.ID),
+			)
+		}
+	}
+}
+
+// Extract stores the text of an attachment unless it was extracted already.
+// Text extracted from another attachment with the same file is reused.
+// Extraction errors are recorded on the attachment's text and returned.
+func (s *TextExtractionService) Extract(ctx context.Context, archiveAttachment *model.ArchiveAttachment) error {
+	if s.extractor == nil {
+		return nil
+	}
+
+	if _, err := s.textRepo.FindByAttachmentID(archiveAttachment.ID); err == nil {
+		return nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	start := time.Now()
+
+	attachmentText := &model.AttachmentText{
+		ArchiveAttachmentID: archiveAttachment.ID,
+		State:               model.AttachmentTextCompleted,
+		Status:              "Y",
+		CreatedBy:           "SYSTEM",
+	}
+
+	reused := false
+	if archiveAttachment.FileHash != "" {
+		if existing, err := s.textRepo.FindCompletedByFileHash(archiveAttachment.FileHash); err == nil {
+			attachmentText.Content = existing.Content
+			reused = true
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+	}
+
+	var extractErr error
+	if !reused {
+		var content string
+		content, extractErr = s.extractFile(ctx, archiveAttachment)
+		if extractErr != nil {
+			attachmentText.State = model.AttachmentTextFailed
+			attachmentText.Message = extractErr.Error()
+		}
+		attachmentText.Content = cleanExtractedText(content)
+	}
+
+	if err := s.textRepo.Save(attachmentText); err != nil {
+		return fmt.Errorf("save attachment text: %w", err)
+	}
+
+	if extractErr != nil {
+		return extractErr
+	}
+
+	logger.Log.Info("attachment_text.extract.success",
+		zap.Uint("attachment_id", archiveAttachment.ID),
+		zap.Int("length", len(attachmentText.Content)),
+		zap.Bool("reused", reused),
+		zap.Duration("duration_ms", time.Since(start)),
+	)
+
+	return nil
+}
+
+func (s *TextExtractionService) extractFile(ctx context.Context, archiveAttachment *model.ArchiveAttachment) (string, error) {
+	storageLocation, err := utils.GetStorageLocation()
+	if err != nil {
+		return "", err
+	}
+
+	tmpDir := filepath.Join(storageLocation, "tmp")
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		return "", fmt.Errorf("create tmp directory: %w", err)
+	}
+
+	sourcePath, cleanup, err := storage.Materialize(ctx, s.storage, archiveAttachment.FileLocation, tmpDir)
+	defer cleanup()
+	if err != nil {
+		return "", fmt.Errorf("materialize attachment: %w", err)
+	}
+
+	return s.extractor.Extract(ctx, sourcePath, archiveAttachment.MimeType)
+}
+
+// sweep queues attachments that have no extracted text. It blocks while the
+// queue is full, so each batch is only fetched once the previous one has
+// been picked up.
+func (s *TextExtractionService) sweep() {
+	afterID := uint(0)
+	for {
+		archiveAttachments, err := s.textRepo.FindUnextracted(extract.SupportedTypes(), afterID, textExtractionBatchSize)
+		if err != nil {
+			logger.Log.Error("attachment_text.sweep.failed",
+				zap.Error(err),
+			)
+			return
+		}
+
+		if len(archiveAttachments) == 0 {
+			return
+		}
+
+		for _, archiveAttachment := range archiveAttachments {
+			s.queue <- archiveAttachment
+		}
+
+		afterID = archiveAttachments[len(archiveAttachments)-1].ID
+	}
+}
+
+// cleanExtractedText drops what Postgres text cannot hold, NUL bytes and
+// invalid UTF-8, and the characters search snippets are highlighted with.
+func cleanExtractedText(text string) string {
+	text = strings.ToValidUTF8(text, "")
+
+	return strings.NewReplacer(
+		"\x00", "",
+		repository.HighlightStart, "",
+		repository.HighlightStop, "",
+	).Replace(text)
+}
@@ -0,0 +1,266 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/mugnialby/arsip-backend/internal/extract"
+	"github.com/mugnialby/arsip-backend/internal/model"
+	"github.com/mugnialby/arsip-backend/internal/repository"
+	"github.com/mugnialby/arsip-backend/internal/storage"
+	"github.com/mugnialby/arsip-backend/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	logger.Log = zap.NewNop()
+	os.Exit(m.Run())
+}
+
+// memoryTextRepository keeps attachment texts in memory. Search matches the
+// query as a case-insensitive substring and ranks by how often it occurs,
+// standing in for Postgres full-text search.
+type memoryTextRepository struct {
+	attachments map[uint]*model.ArchiveAttachment
+	texts       map[uint]*model.AttachmentText
+	saves       int
+}
+
+func newMemoryTextRepository(archiveAttachments ...*model.ArchiveAttachment) *memoryTextRepository {
+	repo := &memoryTextRepository{
+		attachments: map[uint]*model.ArchiveAttachment{},
+		texts:       map[uint]*model.AttachmentText{},
+	}
+	for _, archiveAttachment := range archiveAttachments {
+		repo.attachments[archiveAttachment.ID] = archiveAttachment
+	}
+
+	return repo
+}
+
+func (r *memoryTextRepository) FindByAttachmentID(attachmentID uint) (*model.AttachmentText, error) {
+	if attachmentText, ok := r.texts[attachmentID]; ok {
+		return attachmentText, nil
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryTextRepository) FindCompletedByFileHash(fileHash string) (*model.AttachmentText, error) {
+	for _, attachmentText := range r.texts {
+		if attachmentText.State == model.AttachmentTextCompleted &&
+			r.attachments[attachmentText.ArchiveAttachmentID].FileHash == fileHash {
+			return attachmentText, nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryTextRepository) FindUnextracted(mimeTypes []string, afterID uint, limit int) ([]*model.ArchiveAttachment, error) {
+	return nil, nil
+}
+
+func (r *memoryTextRepository) Save(attachmentText *model.AttachmentText) error {
+	r.saves++
+	r.texts[attachmentText.ArchiveAttachmentID] = attachmentText
+	return nil
+}
+
+func (r *memoryTextRepository) Search(query string, access *repository.ArchiveAccess, limit int) ([]*model.AttachmentTextMatch, error) {
+	var matches []*model.AttachmentTextMatch
+	for _, attachmentText := range r.texts {
+		content := strings.ToLower(attachmentText.Content)
+		count := strings.Count(content, strings.ToLower(query))
+		if attachmentText.State != model.AttachmentTextCompleted || count == 0 {
+			continue
+		}
+
+		i := strings.Index(content, strings.ToLower(query))
+		archiveAttachment := r.attachments[attachmentText.ArchiveAttachmentID]
+		matches = append(matches, &model.AttachmentTextMatch{
+			ArchiveHdrID:        archiveAttachment.ArchiveHdrID,
+			ArchiveAttachmentID: archiveAttachment.ID,
+			FileName:            archiveAttachment.FileName,
+			Snippet: attachmentText.Content[:i] +
+				repository.HighlightStart + attachmentText.Content[i:i+len(query)] + repository.HighlightStop +
+				attachmentText.Content[i+len(query):],
+			Rank: float64(count),
+		})
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Rank != matches[j].Rank {
+			return matches[i].Rank > matches[j].Rank
+		}
+		return matches[i].ArchiveAttachmentID < matches[j].ArchiveAttachmentID
+	})
+
+	return matches[:min(limit, len(matches))], nil
+}
+
+// newTestStorage returns a local backend in a fresh storage directory,
+// which is also where files are staged and materialized.
+func newTestStorage(t *testing.T) *storage.LocalBackend {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "storage"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+
+	backend, err := storage.NewLocalBackend(filepath.Join(dir, "storage"))
+	if err != nil {
+		t.Fatalf("NewLocalBackend: %v", err)
+	}
+
+	return backend
+}
+
+// newTestTextExtraction stores a file for each attachment in a local backend
+// and returns the path each file is extracted from.
+func newTestTextExtraction(t *testing.T, archiveAttachments ...*model.ArchiveAttachment) (storage.Backend, map[uint]string) {
+	t.Helper()
+
+	backend := newTestStorage(t)
+
+	paths := map[uint]string{}
+	for _, archiveAttachment := range archiveAttachments {
+		if err := backend.Put(context.Background(), archiveAttachment.FileLocation, strings.NewReader("file"), -1, archiveAttachment.MimeType); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+
+		var err error
+		if paths[archiveAttachment.ID], err = backend.LocalPath(archiveAttachment.FileLocation); err != nil {
+			t.Fatalf("LocalPath: %v", err)
+		}
+	}
+
+	return backend, paths
+}
+
+func testAttachment(id uint, archiveID uint, fileName string, mimeType string, fileHash string) *model.ArchiveAttachment {
+	return &model.ArchiveAttachment{
+		ID:           id,
+		ArchiveHdrID: archiveID,
+		FileName:     fileName,
+		FileLocation: "archives/" + fileName,
+		MimeType:     mimeType,
+		FileHash:     fileHash,
+	}
+}
+
+func TestTextExtractionServiceExtract(t *testing.T) {
+	decree := testAttachment(1, 10, "decree.pdf", "application/pdf", "hash-decree")
+	copyOfDecree := testAttachment(2, 20, "decree-copy.pdf", "application/pdf", "hash-decree")
+	scan := testAttachment(3, 20, "scan.png", "image/png", "hash-scan")
+
+	backend, paths := newTestTextExtraction(t, decree, copyOfDecree, scan)
+	textRepo := newMemoryTextRepository(decree, copyOfDecree, scan)
+	extractor := &extract.Fake{
+		Texts: map[string]string{
+			paths[decree.ID]: "Surat\x00 keputusan " + repository.HighlightStart + "pegawai",
+			paths[scan.ID]:   "daftar hadir",
+		},
+		Text: "not extracted from this file",
+	}
+	service := NewTextExtractionService(textRepo, backend, extractor)
+
+	ctx := context.Background()
+	for _, archiveAttachment := range []*model.ArchiveAttachment{decree, copyOfDecree, scan} {
+		if err := service.Extract(ctx, archiveAttachment); err != nil {
+			t.Fatalf("Extract(%d): %v", archiveAttachment.ID, err)
+		}
+	}
+
+	tests := []struct {
+		attachment *model.ArchiveAttachment
+		want       string
+	}{
+		// Text is cleaned of NUL bytes and highlight markers.
+		{attachment: decree, want: "Surat keputusan pegawai"},
+		// A file with the same hash reuses the text instead of extracting.
+		{attachment: copyOfDecree, want: "Surat keputusan pegawai"},
+		{attachment: scan, want: "daftar hadir"},
+	}
+	for _, tt := range tests {
+		attachmentText, err := textRepo.FindByAttachmentID(tt.attachment.ID)
+		if err != nil {
+			t.Fatalf("no text saved for attachment %d", tt.attachment.ID)
+		}
+		if attachmentText.State != model.AttachmentTextCompleted || attachmentText.Content != tt.want {
+			t.Errorf("attachment %d: state %q, content %q; want completed, %q",
+				tt.attachment.ID, attachmentText.State, attachmentText.Content, tt.want)
+		}
+	}
+
+	// Extracted attachments are skipped.
+	extractor.Err = errors.New("extractor called again")
+	if err := service.Extract(ctx, decree); err != nil {
+		t.Fatalf("Extract of an extracted attachment: %v", err)
+	}
+	if textRepo.saves != 3 {
+		t.Fatalf("saves = %d, want 3", textRepo.saves)
+	}
+}
+
+func TestTextExtractionServiceRecordsFailures(t *testing.T) {
+	scan := testAttachment(1, 10, "scan.png", "image/png", "hash-scan")
+
+	backend, _ := newTestTextExtraction(t, scan)
+	textRepo := newMemoryTextRepository(scan)
+	extractErr := errors.New("tesseract error: exit status 1")
+	service := NewTextExtractionService(textRepo, backend, &extract.Fake{Err: extractErr})
+
+	if err := service.Extract(context.Background(), scan); !errors.Is(err, extractErr) {
+		t.Fatalf("Extract: err = %v, want %v", err, extractErr)
+	}
+
+	attachmentText, err := textRepo.FindByAttachmentID(scan.ID)
+	if err != nil {
+		t.Fatal("failure was not saved")
+	}
+	if attachmentText.State != model.AttachmentTextFailed || attachmentText.Message != extractErr.Error() {
+		t.Fatalf("state %q, message %q; want failed, %q", attachmentText.State, attachmentText.Message, extractErr)
+	}
+
+	// A failed attachment is not retried.
+	if err := service.Extract(context.Background(), scan); err != nil {
+		t.Fatalf("Extract of a failed attachment: %v", err)
+	}
+}
+
+func TestTextExtractionServiceWithoutExtractor(t *testing.T) {
+	scan := testAttachment(1, 10, "scan.png", "image/png", "hash-scan")
+	textRepo := newMemoryTextRepository(scan)
+	service := NewTextExtractionService(textRepo, nil, nil)
+
+	service.Enqueue(scan)
+	if err := service.Extract(context.Background(), scan); err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+
+	if textRepo.saves != 0 || len(service.queue) != 0 {
+		t.Fatalf("saves = %d, queued = %d; want nothing done", textRepo.saves, len(service.queue))
+	}
+}
+
+func TestTextExtractionServiceEnqueueSkipsUnsupported(t *testing.T) {
+	service := NewTextExtractionService(newMemoryTextRepository(), nil, &extract.Fake{})
+
+	service.Enqueue(
+		testAttachment(1, 10, "decree.pdf", "application/pdf", ""),
+		testAttachment(2, 10, "notes.docx", "application/vnd.openxmlformats-officedocument.wordprocessingml.document", ""),
+	)
+
+	if len(service.queue) != 1 || (<-service.queue).ID != 1 {
+		t.Fatal("want only the PDF queued")
+	}
+}
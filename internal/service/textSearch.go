@@ -0,0 +1,79 @@
+package service
+
+import (
+	"errors"
+	"html"
+	"strings"
+
+	"github.com/mugnialby/arsip-backend/internal/model"
+	"github.com/mugnialby/arsip-backend/internal/repository"
+)
+
+// textSearchMaxMatches caps how many matching attachments one full-text
+// search returns.
+const textSearchMaxMatches = 200
+
+var ErrSearchQueryEmpty = errors.New("search query is empty")
+
+var snippetHighlighter = strings.NewReplacer(
+	repository.HighlightStart, "<mark>",
+	repository.HighlightStop, "</mark>",
+)
+
+// SearchArchiveText finds accessible archives whose attachment text matches
+// query, best match first. Each result lists its matching attachments with
+// a highlighted snippet.
+func (s *ArchiveService) SearchArchiveText(query string, user *model.User) ([]*model.ArchiveTextSearchResult, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, ErrSearchQueryEmpty
+	}
+
+	matches, err := s.textRepo.Search(query, s.accessFor(user), textSearchMaxMatches)
+	if err != nil {
+		return nil, err
+	}
+
+	// Matches come best first, so an archive ranks by its best attachment.
+	var archiveIDs []uint
+	matchesByArchive := make(map[uint][]*model.AttachmentTextMatch)
+	for _, match := range matches {
+		if _, ok := matchesByArchive[match.ArchiveHdrID]; !ok {
+			archiveIDs = append(archiveIDs, match.ArchiveHdrID)
+		}
+
+		match.Snippet = snippetHighlighter.Replace(html.EscapeString(match.Snippet))
+		matchesByArchive[match.ArchiveHdrID] = append(matchesByArchive[match.ArchiveHdrID], match)
+	}
+
+	results := make([]*model.ArchiveTextSearchResult, 0, len(archiveIDs))
+	if len(archiveIDs) == 0 {
+		return results, nil
+	}
+
+	archives, err := s.repo.FindByIDs(archiveIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	archivesByID := make(map[uint]model.ArchiveHdr, len(archives))
+	for _, archive := range archives {
+		archivesByID[archive.ID] = archive
+	}
+
+	for _, archiveID := range archiveIDs {
+		archive, ok := archivesByID[archiveID]
+		if !ok {
+			continue
+		}
+
+		archiveMatches := matchesByArchive[archiveID]
+		results = append(results, &model.ArchiveTextSearchResult{
+			Archive: archive,
+			Rank:    archiveMatches[0].Rank,
+			Matches: archiveMatches,
+		})
+	}
+
+	return results, nil
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mugnialby/arsip-backend/internal/extract"
+	"github.com/mugnialby/arsip-backend/internal/model"
+	"github.com/mugnialby/arsip-backend/internal/repository"
+)
+
+// memoryArchiveRepository serves FindByIDs from memory; SearchArchiveText
+// calls nothing else.
+type memoryArchiveRepository struct {
+	repository.ArchiveRepository
+	archives map[uint]model.ArchiveHdr
+}
+
+func (r *memoryArchiveRepository) FindByIDs(ids []uint) ([]model.ArchiveHdr, error) {
+	var archives []model.ArchiveHdr
+	for _, id := range ids {
+		if archive, ok := r.archives[id]; ok {
+			archives = append(archives, archive)
+		}
+	}
+
+	return archives, nil
+}
+
+func TestSearchArchiveText(t *testing.T) {
+	decree := testAttachment(1, 10, "decree.pdf", "application/pdf", "")
+	attendance := testAttachment(2, 20, "attendance.png", "image/png", "")
+	appendix := testAttachment(3, 10, "appendix.pdf", "application/pdf", "")
+	deleted := testAttachment(4, 30, "deleted.pdf", "application/pdf", "")
+
+	backend, paths := newTestTextExtraction(t, decree, attendance, appendix, deleted)
+	textRepo := newMemoryTextRepository(decree, attendance, appendix, deleted)
+	extractor := &extract.Fake{
+		Texts: map[string]string{
+			paths[decree.ID]:     "Keputusan <b>pengangkatan</b> Pegawai",
+			paths[attendance.ID]: "Daftar hadir pegawai, pegawai, pegawai",
+			paths[appendix.ID]:   "Lampiran: pegawai & pegawai",
+			paths[deleted.ID]:    "pegawai pegawai pegawai pegawai",
+		},
+	}
+	texts := NewTextExtractionService(textRepo, backend, extractor)
+	for _, archiveAttachment := range []*model.ArchiveAttachment{decree, attendance, appendix, deleted} {
+		if err := texts.Extract(context.Background(), archiveAttachment); err != nil {
+			t.Fatalf("Extract(%d): %v", archiveAttachment.ID, err)
+		}
+	}
+
+	// Archive 30 matches best but is gone by the time archives are loaded.
+	archiveRepo := &memoryArchiveRepository{archives: map[uint]model.ArchiveHdr{
+		10: {ID: 10, ArchiveName: "Keputusan"},
+		20: {ID: 20, ArchiveName: "Daftar hadir"},
+	}}
+	service := &ArchiveService{repo: archiveRepo, textRepo: textRepo, superuserRoleID: 1}
+
+	results, err := service.SearchArchiveText("  pegawai ", &model.User{RoleID: 1})
+	if err != nil {
+		t.Fatalf("SearchArchiveText: %v", err)
+	}
+
+	// Archives rank by their best attachment: archive 20 matches three
+	// times, archive 10 at most twice.
+	if len(results) != 2 || results[0].Archive.ID != 20 || results[1].Archive.ID != 10 {
+		t.Fatalf("results = %+v, want archives 20 and 10", results)
+	}
+	if results[0].Rank != 3 || results[1].Rank != 2 {
+		t.Fatalf("ranks = %v, %v; want 3, 2", results[0].Rank, results[1].Rank)
+	}
+
+	tests := []struct {
+		match *model.AttachmentTextMatch
+		id    uint
+		want  string
+	}{
+		{match: results[0].Matches[0], id: attendance.ID, want: "Daftar hadir <mark>pegawai</mark>, pegawai, pegawai"},
+		{match: results[1].Matches[0], id: appendix.ID, want: "Lampiran: <mark>pegawai</mark> &amp; pegawai"},
+		// Extracted text is escaped; only the highlight becomes markup.
+		{match: results[1].Matches[1], id: decree.ID, want: "Keputusan &lt;b&gt;pengangkatan&lt;/b&gt; <mark>Pegawai</mark>"},
+	}
+	if len(results[0].Matches) != 1 || len(results[1].Matches) != 2 {
+		t.Fatalf("match counts = %d, %d; want 1, 2", len(results[0].Matches), len(results[1].Matches))
+	}
+	for _, tt := range tests {
+		if tt.match.ArchiveAttachmentID != tt.id || tt.match.Snippet != tt.want {
+			t.Errorf("match = attachment %d, %q; want attachment %d, %q",
+				tt.match.ArchiveAttachmentID, tt.match.Snippet, tt.id, tt.want)
+		}
+	}
+}
+
+func TestSearchArchiveTextNoMatches(t *testing.T) {
+	service := &ArchiveService{repo: &memoryArchiveRepository{}, textRepo: newMemoryTextRepository(), superuserRoleID: 1}
+	user := &model.User{RoleID: 1}
+
+	if _, err := service.SearchArchiveText("   ", user); !errors.Is(err, ErrSearchQueryEmpty) {
+		t.Fatalf("blank query: err = %v, want ErrSearchQueryEmpty", err)
+	}
+
+	results, err := service.SearchArchiveText("pegawai", user)
+	if err != nil {
+		t.Fatalf("SearchArchiveText: %v", err)
+	}
+	if results == nil || len(results) != 0 {
+		t.Fatalf("results = %#v, want an empty slice", results)
+	}
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/mugnialby/arsip-backend/internal/model"
+	"github.com/mugnialby/arsip-backend/internal/storage"
+	"github.com/mugnialby/arsip-backend/internal/utils"
+	"github.com/mugnialby/arsip-backend/pkg/logger"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+var ErrThumbnailUnsupported = errors.New("attachment type has no thumbnail")
+
+const (
+	// thumbnailPrefix holds every thumbnail, keyed by the key of the file it
+	// was rendered from, so blobs shared by several attachments share one
+	// thumbnail.
+	thumbnailPrefix = "thumbnails/"
+	thumbnailSize   = 320
+
+	thumbnailQueueSize = 256
+)
+
+// ThumbnailService renders a small JPEG of each image attachment and of the
+// first page of each PDF attachment. Thumbnails are rendered in the
+// background after upload and on demand when one is missing.
+type ThumbnailService struct {
+	storage      storage.Backend
+	magickPath   string
+	pdftoppmPath string
+	queue        chan *model.ArchiveAttachment
+
+	// renders lets concurrent requests for the same thumbnail share one
+	// render.
+	renders singleflight.Group
+}
+
+// NewThumbnailService renders images with ImageMagick and PDFs with
+// poppler's pdftoppm, run from the given resolved paths. An empty path leaves
+// that kind of attachment without thumbnails.
+func NewThumbnailService(backend storage.Backend, magickPath string, pdftoppmPath string) *ThumbnailService {
+	return &ThumbnailService{
+		storage:      backend,
+		magickPath:   magickPath,
+		pdftoppmPath: pdftoppmPath,
+		queue:        make(chan *model.ArchiveAttachment, thumbnailQueueSize),
+	}
+}
+
+// Start runs workers goroutines that render queued thumbnails.
+func (s *ThumbnailService) Start(workers int) {
+	for i := 0; i < workers; i++ {
+		go func() {
+			for archiveAttachment := range s.queue {
+				if err := s.Generate(context.Background(), archiveAttachment); err != nil && !errors.Is(err, ErrThumbnailUnsupported) {
+					logger.Log.Error("thumbnail.generate.failed",
+						zap.Uint("attachment_id", archiveAttachment.ID),
+						zap.String("file_location", archiveAttachment.FileLocation),
+						zap.Error(err),
+					)
+				}
+			}
+		}()
+	}
+}
+
+// Enqueue schedules thumbnails for the given attachments. When the queue is
+// full the attachment is skipped; its thumbnail is rendered on first request.
+func (s *ThumbnailService) Enqueue(archiveAttachments ...*model.ArchiveAttachment) {
+	for _, archiveAttachment := range archiveAttachments {
+		if !s.HasThumbnail(archiveAttachment) {
+			continue
+		}
+
+		select {
+		case s.queue <- archiveAttachment:
+		default:
+			logger.Log.Warn("thumbnail.enqueue.queue_full",
+				zap.Uint("attachment_id", archiveAttachment.ID),
+			)
+		}
+	}
+}
+
+// Open opens the thumbnail of an attachment, rendering it first if needed.
+// The returned reader must be closed.
+func (s *ThumbnailService) Open(ctx context.Context, archiveAttachment *model.ArchiveAttachment) (*storage.ReadSeeker, *storage.ObjectInfo, error) {
+	if !s.HasThumbnail(archiveAttachment) {
+		return nil, nil, ErrThumbnailUnsupported
+	}
+
+	key := thumbnailKey(archiveAttachment.FileLocation)
+
+	info, err := s.storage.Stat(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		if err := s.Generate(ctx, archiveAttachment); err != nil {
+			return nil, nil, err
+		}
+
+		info, err = s.storage.Stat(ctx, key)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return storage.NewReadSeeker(ctx, s.storage, key, info.Size), info, nil
+}
+
+// Generate renders and stores the thumbnail of an attachment unless it
+// already exists.
+func (s *ThumbnailService) Generate(ctx context.Context, archiveAttachment *model.ArchiveAttachment) error {
+	if !s.HasThumbnail(archiveAttachment) {
+		return ErrThumbnailUnsupported
+	}
+
+	key := thumbnailKey(archiveAttachment.FileLocation)
+
+	// Open renders on demand while the queue worker may be rendering the
+	// same key, so a cancelled thumbnail request must not fail the render
+	// for the worker or for other viewers of the attachment.
+	_, err, _ := s.renders.Do(key, func() (any, error) {
+		return nil, s.render(context.WithoutCancel(ctx), archiveAttachment, key)
+	})
+
+	return err
+}
+
+func (s *ThumbnailService) render(ctx context.Context, archiveAttachment *model.ArchiveAttachment, key string) error {
+	if _, err := s.storage.Stat(ctx, key); err == nil {
+		return nil
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+
+	storageLocation, err := utils.GetStorageLocation()
+	if err != nil {
+		return err
+	}
+
+	tmpDir := filepath.Join(storageLocation, "tmp")
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		return fmt.Errorf("create tmp directory: %w", err)
+	}
+
+	sourcePath, cleanup, err := storage.Materialize(ctx, s.storage, archiveAttachment.FileLocation, tmpDir)
+	defer cleanup()
+	if err != nil {
+		return fmt.Errorf("materialize attachment: %w", err)
+	}
+
+	thumbnailPath := filepath.Join(tmpDir, uuid.NewString()+".jpg")
+	defer os.Remove(thumbnailPath)
+
+	if archiveAttachment.MimeType == "application/pdf" {
+		err = s.renderPDFThumbnail(sourcePath, thumbnailPath)
+	} else {
+		err = s.renderImageThumbnail(sourcePath, thumbnailPath)
+	}
+	if err != nil {
+		return err
+	}
+
+	thumbnailFile, err := os.Open(thumbnailPath)
+	if err != nil {
+		return fmt.Errorf("open thumbnail: %w", err)
+	}
+	defer thumbnailFile.Close()
+
+	info, err := thumbnailFile.Stat()
+	if err != nil {
+		return fmt.Errorf("stat thumbnail: %w", err)
+	}
+
+	if err := s.storage.Put(ctx, key, thumbnailFile, info.Size(), "image/jpeg"); err != nil {
+		return fmt.Errorf("store thumbnail: %w", err)
+	}
+
+	logger.Log.Info("thumbnail.generate.success",
+		zap.Uint("attachment_id", archiveAttachment.ID),
+		zap.String("key", key),
+		zap.Int64("size", info.Size()),
+	)
+
+	return nil
+}
+
+// HasThumbnail reports whether a thumbnail can be rendered for the
+// attachment's type with the tools that were found.
+func (s *ThumbnailService) HasThumbnail(archiveAttachment *model.ArchiveAttachment) bool {
+	if archiveAttachment.FileLocation == "" {
+		return false
+	}
+
+	switch archiveAttachment.MimeType {
+	case "image/jpeg", "image/png":
+		return s.magickPath != ""
+	case "application/pdf":
+		return s.pdftoppmPath != ""
+	}
+
+	return false
+}
+
+func thumbnailKey(fileLocation string) string {
+	return thumbnailPrefix + fileLocation + ".jpg"
+}
+
+// thumbnailSource returns the key a thumbnail key was rendered from.
+func thumbnailSource(key string) (string, bool) {
+	if !strings.HasPrefix(key, thumbnailPrefix) || !strings.HasSuffix(key, ".jpg") {
+		return "", false
+	}
+
+	return strings.TrimSuffix(strings.TrimPrefix(key, thumbnailPrefix), ".jpg"), true
+}
+
+func (s *ThumbnailService) renderImageThumbnail(sourcePath string, thumbnailPath string) error {
+	size := strconv.Itoa(thumbnailSize)
+
+	// [0] keeps multi-frame images to their first frame.
+	cmd := exec.Command(s.magickPath, sourcePath+"[0]",
+		"-auto-orient",
+		"-thumbnail", size+"x"+size+">",
+		"-strip",
+		"-quality", "80",
+		"jpg:"+thumbnailPath,
+	)
+
+	utils.ApplySysProcAttr(cmd)
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("imagemagick error: %v | %s", err, string(out))
+	}
+
+	return nil
+}
+
+func (s *ThumbnailService) renderPDFThumbnail(sourcePath string, thumbnailPath string) error {
+	// pdftoppm appends the extension to the output prefix itself.
+	cmd := exec.Command(s.pdftoppmPath,
+		"-f", "1", "-l", "1",
+		"-singlefile",
+		"-jpeg", "-jpegopt", "quality=80",
+		"-scale-to", strconv.Itoa(thumbnailSize),
+		sourcePath,
+		strings.TrimSuffix(thumbnailPath, ".jpg"),
+	)
+
+	utils.ApplySysProcAttr(cmd)
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("pdftoppm error: %v | %s", err, string(out))
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mugnialby/arsip-backend/internal/model"
+)
+
+func TestThumbnailServiceHasThumbnail(t *testing.T) {
+	pdf := &model.ArchiveAttachment{FileLocation: "blobs/ab/ab.pdf", MimeType: "application/pdf"}
+	png := &model.ArchiveAttachment{FileLocation: "blobs/cd/cd.png", MimeType: "image/png"}
+	docx := &model.ArchiveAttachment{FileLocation: "blobs/ef/ef.docx", MimeType: "application/vnd.openxmlformats-officedocument.wordprocessingml.document"}
+	unstored := &model.ArchiveAttachment{MimeType: "application/pdf"}
+
+	tests := []struct {
+		name         string
+		magickPath   string
+		pdftoppmPath string
+		attachment   *model.ArchiveAttachment
+		want         bool
+	}{
+		{name: "pdf", pdftoppmPath: "/usr/bin/pdftoppm", attachment: pdf, want: true},
+		{name: "pdf without pdftoppm", magickPath: "/usr/bin/magick", attachment: pdf, want: false},
+		{name: "image", magickPath: "/usr/bin/magick", attachment: png, want: true},
+		{name: "image without imagemagick", pdftoppmPath: "/usr/bin/pdftoppm", attachment: png, want: false},
+		{name: "unsupported type", magickPath: "/usr/bin/magick", pdftoppmPath: "/usr/bin/pdftoppm", attachment: docx, want: false},
+		{name: "no file", magickPath: "/usr/bin/magick", pdftoppmPath: "/usr/bin/pdftoppm", attachment: unstored, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewThumbnailService(nil, tt.magickPath, tt.pdftoppmPath)
+			if got := service.HasThumbnail(tt.attachment); got != tt.want {
+				t.Fatalf("HasThumbnail = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThumbnailServiceWithoutTools(t *testing.T) {
+	service := NewThumbnailService(nil, "", "")
+	pdf := &model.ArchiveAttachment{ID: 1, FileLocation: "blobs/ab/ab.pdf", MimeType: "application/pdf"}
+
+	if _, _, err := service.Open(context.Background(), pdf); !errors.Is(err, ErrThumbnailUnsupported) {
+		t.Fatalf("Open: err = %v, want ErrThumbnailUnsupported", err)
+	}
+
+	service.Enqueue(pdf)
+	if len(service.queue) != 0 {
+		t.Fatal("attachment queued without a tool to render it")
+	}
+}
@@ -0,0 +1,365 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mugnialby/arsip-backend/internal/model"
+	request "github.com/mugnialby/arsip-backend/internal/model/dto/request/uploads"
+	"github.com/mugnialby/arsip-backend/internal/repository"
+	"github.com/mugnialby/arsip-backend/internal/utils"
+	"github.com/mugnialby/arsip-backend/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUploadNotFound         = errors.New("upload not found")
+	ErrUploadCompleted        = errors.New("upload is already completed")
+	ErrUploadOffsetMismatch   = errors.New("upload offset does not match")
+	ErrUploadIncomplete       = errors.New("upload is not complete")
+	ErrUploadChecksumInvalid  = errors.New("checksum must be a hex encoded sha256")
+	ErrUploadChecksumMismatch = errors.New("upload checksum does not match")
+)
+
+var sha256HexPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// UploadService implements resumable chunked uploads. Chunks are appended to
+// a file under storage/tmp/uploads, and completing the upload moves the file
+// into the archive's upload directory and creates the ArchiveAttachment.
+type UploadService struct {
+	repo           repository.UploadSessionRepository
+	archiveService *ArchiveService
+	maxSize        int64
+	expiresIn      time.Duration
+
+	// locks serialises chunk writes and completion per upload
+	locks sync.Map
+}
+
+func NewUploadService(
+	repo repository.UploadSessionRepository,
+	archiveService *ArchiveService,
+	maxSize int64,
+	expiresIn time.Duration,
+) *UploadService {
+	return &UploadService{
+		repo:           repo,
+		archiveService: archiveService,
+		maxSize:        maxSize,
+		expiresIn:      expiresIn,
+	}
+}
+
+// InitUpload opens a new upload session for an archive the caller can access.
+// The checksum is optional; when given, the completed file must match it.
+func (s *UploadService) InitUpload(newUploadRequest *request.NewUploadRequest, user *model.User) (*model.UploadSession, error) {
+	if _, err := s.archiveService.GetArchiveByID(newUploadRequest.ArchiveHdrID, user); err != nil {
+		return nil, err
+	}
+
+	if _, err := extensionFromFileName(newUploadRequest.FileName); err != nil {
+		return nil, err
+	}
+
+	if s.maxSize > 0 && newUploadRequest.TotalSize > s.maxSize {
+		return nil, ErrAttachmentTooLarge
+	}
+
+	checksum := strings.ToLower(newUploadRequest.Checksum)
+	if checksum != "" && !sha256HexPattern.MatchString(checksum) {
+		return nil, ErrUploadChecksumInvalid
+	}
+
+	storageLocation, err := utils.GetStorageLocation()
+	if err != nil {
+		return nil, err
+	}
+
+	uploadDir := filepath.Join(storageLocation, "tmp", "uploads")
+	if err := os.MkdirAll(uploadDir, 0755); err != nil {
+		return nil, fmt.Errorf("create upload directory: %w", err)
+	}
+
+	uploadID := uuid.NewString()
+	tempLocation := filepath.Join(uploadDir, uploadID)
+
+	tempFile, err := os.OpenFile(tempLocation, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("create upload file: %w", err)
+	}
+	tempFile.Close()
+
+	uploadSession := &model.UploadSession{
+		UploadID:     uploadID,
+		ArchiveHdrID: newUploadRequest.ArchiveHdrID,
+		FileName:     filepath.Base(newUploadRequest.FileName),
+		TotalSize:    newUploadRequest.TotalSize,
+		Checksum:     checksum,
+		TempLocation: tempLocation,
+		ExpiresAt:    time.Now().Add(s.expiresIn),
+		Status:       "Y",
+		CreatedBy:    user.UserId,
+	}
+
+	if err := s.repo.Create(uploadSession); err != nil {
+		_ = os.Remove(tempLocation)
+		return nil, err
+	}
+
+	return uploadSession, nil
+}
+
+// GetUpload returns an upload session owned by the caller, which clients use
+// to find the offset to resume from.
+func (s *UploadService) GetUpload(uploadID string, user *model.User) (*model.UploadSession, error) {
+	uploadSession, err := s.repo.FindByUploadID(uploadID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if uploadSession.CreatedBy != user.UserId {
+		return nil, ErrUploadNotFound
+	}
+
+	if uploadSession.CompletedAt == nil && time.Now().After(uploadSession.ExpiresAt) {
+		return nil, ErrUploadNotFound
+	}
+
+	return uploadSession, nil
+}
+
+// UploadChunk appends r at offset, which must equal the number of bytes
+// received so far. When chunkChecksum is given the chunk is verified against
+// it and discarded on mismatch. On ErrUploadOffsetMismatch the returned
+// session carries the offset the client should resume from.
+func (s *UploadService) UploadChunk(uploadID string, offset int64, chunkChecksum string, r io.Reader, user *model.User) (*model.UploadSession, error) {
+	unlock := s.lock(uploadID)
+	defer unlock()
+
+	uploadSession, err := s.GetUpload(uploadID, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if uploadSession.CompletedAt != nil {
+		return nil, ErrUploadCompleted
+	}
+
+	if offset != uploadSession.Offset {
+		return uploadSession, ErrUploadOffsetMismatch
+	}
+
+	chunkChecksum = strings.ToLower(chunkChecksum)
+	if chunkChecksum != "" && !sha256HexPattern.MatchString(chunkChecksum) {
+		return nil, ErrUploadChecksumInvalid
+	}
+
+	tempFile, err := os.OpenFile(uploadSession.TempLocation, os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open upload file: %w", err)
+	}
+	defer tempFile.Close()
+
+	// Drop anything left behind by an interrupted chunk before appending.
+	if err := tempFile.Truncate(offset); err != nil {
+		return nil, fmt.Errorf("truncate upload file: %w", err)
+	}
+
+	if _, err := tempFile.Seek(offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("seek upload file: %w", err)
+	}
+
+	remaining := uploadSession.TotalSize - offset
+	hash := sha256.New()
+	written, err := io.Copy(io.MultiWriter(tempFile, hash), io.LimitReader(r, remaining+1))
+	if err != nil {
+		_ = tempFile.Truncate(offset)
+		return nil, fmt.Errorf("write upload chunk: %w", err)
+	}
+
+	if written > remaining {
+		_ = tempFile.Truncate(offset)
+		return nil, ErrAttachmentTooLarge
+	}
+
+	if chunkChecksum != "" && hex.EncodeToString(hash.Sum(nil)) != chunkChecksum {
+		_ = tempFile.Truncate(offset)
+		return nil, ErrUploadChecksumMismatch
+	}
+
+	uploadSession.Offset = offset + written
+	if err := s.repo.UpdateOffset(uploadSession.ID, uploadSession.Offset, user.UserId); err != nil {
+		_ = tempFile.Truncate(offset)
+		return nil, err
+	}
+
+	return uploadSession, nil
+}
+
+// CompleteUpload verifies the received file and attaches it to the archive
+// in one transaction.
+func (s *UploadService) CompleteUpload(uploadID string, user *model.User) (*model.ArchiveAttachment, error) {
+	unlock := s.lock(uploadID)
+	defer unlock()
+
+	uploadSession, err := s.GetUpload(uploadID, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if uploadSession.CompletedAt != nil {
+		return nil, ErrUploadCompleted
+	}
+
+	if uploadSession.Offset != uploadSession.TotalSize {
+		return nil, ErrUploadIncomplete
+	}
+
+	if _, err := s.archiveService.GetArchiveByID(uploadSession.ArchiveHdrID, user); err != nil {
+		return nil, err
+	}
+
+	declaredExt, err := extensionFromFileName(uploadSession.FileName)
+	if err != nil {
+		return nil, err
+	}
+
+	mimeType, fileExt, err := detectStoredFileType(uploadSession.TempLocation, declaredExt)
+	if err != nil {
+		return nil, err
+	}
+
+	var newArchiveAttachment *model.ArchiveAttachment
+	err = s.archiveService.withTransaction(func(tx *gorm.DB, staging *fileStaging) error {
+		file, err := staging.Adopt(uploadSession.TempLocation)
+		if err != nil {
+			return err
+		}
+
+		if uploadSession.Checksum != "" && file.Hash != uploadSession.Checksum {
+			return ErrUploadChecksumMismatch
+		}
+
+		newArchiveAttachment, err = s.archiveService.attachStagedFile(tx, file, uploadSession.ArchiveHdrID, fileExt, mimeType, user.UserId)
+		if err != nil {
+			return err
+		}
+
+		if err := s.repo.WithTx(tx).Complete(uploadSession.ID, newArchiveAttachment.ID, user.UserId); err != nil {
+			return fmt.Errorf("complete upload session: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.locks.Delete(uploadID)
+	s.archiveService.removeMergedPDFCache(uploadSession.ArchiveHdrID)
+	s.archiveService.findDuplicates([]*model.ArchiveAttachment{newArchiveAttachment}, user)
+	s.archiveService.attachmentsStored(newArchiveAttachment)
+	return newArchiveAttachment, nil
+}
+
+// CancelUpload discards a pending upload and its data.
+func (s *UploadService) CancelUpload(uploadID string, user *model.User) error {
+	unlock := s.lock(uploadID)
+	defer unlock()
+
+	uploadSession, err := s.GetUpload(uploadID, user)
+	if err != nil {
+		return err
+	}
+
+	if uploadSession.CompletedAt != nil {
+		return ErrUploadCompleted
+	}
+
+	if err := s.repo.Delete(uploadSession.ID, user.UserId); err != nil {
+		return err
+	}
+
+	s.locks.Delete(uploadID)
+	if err := os.Remove(uploadSession.TempLocation); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove upload file: %w", err)
+	}
+
+	return nil
+}
+
+// PurgeExpiredUploads removes pending uploads whose session has expired.
+func (s *UploadService) PurgeExpiredUploads() error {
+	uploadSessions, err := s.repo.FindExpired(time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, uploadSession := range uploadSessions {
+		// Holding the lock keeps a late chunk from writing to the file
+		// being removed.
+		unlock := s.lock(uploadSession.UploadID)
+		err := s.repo.Delete(uploadSession.ID, "SYSTEM")
+		if err == nil {
+			s.locks.Delete(uploadSession.UploadID)
+		}
+		unlock()
+
+		if err != nil {
+			return err
+		}
+
+		if err := os.Remove(uploadSession.TempLocation); err != nil && !os.IsNotExist(err) {
+			logger.Log.Error("upload.purge.remove_file.failed",
+				zap.String("upload_id", uploadSession.UploadID),
+				zap.Error(err),
+			)
+		}
+	}
+
+	if len(uploadSessions) > 0 {
+		logger.Log.Info("upload.purge.success",
+			zap.Int("count", len(uploadSessions)),
+		)
+	}
+
+	return nil
+}
+
+// StartExpiredUploadCleanup purges expired uploads every interval for the
+// lifetime of the process.
+func (s *UploadService) StartExpiredUploadCleanup(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := s.PurgeExpiredUploads(); err != nil {
+				logger.Log.Error("upload.purge.failed",
+					zap.Error(err),
+				)
+			}
+		}
+	}()
+}
+
+func (s *UploadService) lock(uploadID string) func() {
+	value, _ := s.locks.LoadOrStore(uploadID, &sync.Mutex{})
+	mu := value.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
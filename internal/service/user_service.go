@@ -1,40 +1,67 @@
 package service
 
 import (
+	"github.com/mugnialby/arsip-backend/internal/listquery"
 	"github.com/mugnialby/arsip-backend/internal/model"
-	authRequest "github.com/mugnialby/arsip-backend/internal/model/dto/request/auth"
 	usersRequest "github.com/mugnialby/arsip-backend/internal/model/dto/request/users"
 	"github.com/mugnialby/arsip-backend/internal/repository"
+	"github.com/mugnialby/arsip-backend/internal/utils"
+	"gorm.io/gorm"
 )
 
 type UserService struct {
-	repo repository.UserRepository
+	db               *gorm.DB
+	repo             repository.UserRepository
+	refreshTokenRepo repository.RefreshTokenRepository
 }
 
-func NewUserService(repo repository.UserRepository) *UserService {
-	return &UserService{repo: repo}
+func NewUserService(db *gorm.DB, repo repository.UserRepository, refreshTokenRepo repository.RefreshTokenRepository) *UserService {
+	return &UserService{db: db, repo: repo, refreshTokenRepo: refreshTokenRepo}
 }
 
-func (s *UserService) GetAllUsers() ([]model.User, error) {
-	return s.repo.FindAll()
+func (s *UserService) GetAllUsers(query listquery.Query) ([]model.User, listquery.Result, error) {
+	return s.repo.FindAll(query)
 }
 
 func (s *UserService) GetUserByID(id uint) (*model.User, error) {
 	return s.repo.FindByID(id)
 }
 
-func (s *UserService) CreateUser(user *model.User) error {
+// CreateUser stores the user with the given plain password hashed using bcrypt
+func (s *UserService) CreateUser(user *model.User, password string) error {
+	passwordHash, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	user.PasswordHash = passwordHash
 	return s.repo.Create(user)
 }
 
-func (s *UserService) UpdateUser(user *model.User) error {
+// UpdateUser saves the user and, when password is not empty, replaces the
+// stored hash with a bcrypt hash of the new password
+func (s *UserService) UpdateUser(user *model.User, password string) error {
+	if password != "" {
+		passwordHash, err := utils.HashPassword(password)
+		if err != nil {
+			return err
+		}
+
+		user.PasswordHash = passwordHash
+	}
+
 	return s.repo.Update(user)
 }
 
+// DeleteUser deactivates the user and revokes every refresh token issued to
+// them, so their sessions end immediately. Both happen in one transaction,
+// so a user is never left deactivated with live sessions.
 func (s *UserService) DeleteUser(deleteUserRequest *usersRequest.DeleteUserRequest) error {
-	return s.repo.Delete(deleteUserRequest)
-}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.repo.WithTx(tx).Delete(deleteUserRequest); err != nil {
+			return err
+		}
 
-func (s *UserService) CheckUserLoginRequest(loginRequest *authRequest.LoginRequest) (*model.User, error) {
-	return s.repo.FindUserForLoginRequest(loginRequest)
+		return s.refreshTokenRepo.WithTx(tx).RevokeAllByUserID(deleteUserRequest.ID, deleteUserRequest.SubmittedBy)
+	})
 }
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrNotFound = errors.New("storage: object not found")
+
+// ObjectInfo describes a stored object.
+type ObjectInfo struct {
+	Key         string
+	Size        int64
+	ModTime     time.Time
+	ContentType string
+}
+
+// Backend stores attachment files under backend-relative keys such as
+// "archives/12/12_1700000000.pdf". Keys always use forward slashes.
+type Backend interface {
+	// Put stores r under key. size may be -1 when unknown.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the whole object for reading.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// OpenRange opens length bytes starting at offset. A negative length
+	// reads to the end of the object.
+	OpenRange(ctx context.Context, key string, offset int64, length int64) (io.ReadCloser, error)
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	// Delete removes the object. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// List returns every object whose key starts with prefix.
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+}
+
+// LocalFiler is implemented by backends whose objects are plain files on the
+// local disk, so callers that need a path can skip copying them.
+type LocalFiler interface {
+	LocalPath(key string) (string, error)
+}
+
+// Materialize returns a local path holding the object, downloading it into
+// tmpDir when the backend is not local. cleanup removes any downloaded copy
+// and must always be called.
+func Materialize(ctx context.Context, backend Backend, key string, tmpDir string) (string, func(), error) {
+	if localFiler, ok := backend.(LocalFiler); ok {
+		localPath, err := localFiler.LocalPath(key)
+		if err != nil {
+			return "", func() {}, err
+		}
+
+		if _, err := os.Stat(localPath); err != nil {
+			if os.IsNotExist(err) {
+				return "", func() {}, ErrNotFound
+			}
+			return "", func() {}, err
+		}
+
+		return localPath, func() {}, nil
+	}
+
+	reader, err := backend.Get(ctx, key)
+	if err != nil {
+		return "", func() {}, err
+	}
+	defer reader.Close()
+
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		return "", func() {}, err
+	}
+
+	localPath := filepath.Join(tmpDir, uuid.NewString()+path.Ext(key))
+	cleanup := func() { _ = os.Remove(localPath) }
+
+	file, err := os.Create(localPath)
+	if err != nil {
+		return "", func() {}, err
+	}
+
+	_, err = io.Copy(file, reader)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		cleanup()
+		return "", func() {}, err
+	}
+
+	return localPath, cleanup, nil
+}
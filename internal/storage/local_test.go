@@ -0,0 +1,216 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func newTestLocalBackend(t *testing.T) *LocalBackend {
+	t.Helper()
+
+	backend, err := NewLocalBackend(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalBackend: %v", err)
+	}
+
+	return backend
+}
+
+func TestLocalPathRejectsEscapingKeys(t *testing.T) {
+	backend := newTestLocalBackend(t)
+
+	tests := []struct {
+		key     string
+		wantErr bool
+	}{
+		{key: "archives/12/12_1.pdf"},
+		{key: "archives/../archives/12/12_1.pdf"},
+		{key: "../outside.pdf"},
+		{key: "archives/../../outside.pdf"},
+		{key: "/etc/passwd"},
+		{key: "", wantErr: true},
+		{key: "/", wantErr: true},
+		{key: "..", wantErr: true},
+		{key: `archives\..\..\outside.pdf`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			localPath, err := backend.LocalPath(tt.key)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("LocalPath(%q) = %q, want an error", tt.key, localPath)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("LocalPath(%q): %v", tt.key, err)
+			}
+
+			if !strings.HasPrefix(localPath, backend.root+string(filepath.Separator)) {
+				t.Fatalf("LocalPath(%q) = %q, outside root %q", tt.key, localPath, backend.root)
+			}
+		})
+	}
+}
+
+func TestLocalBackendRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	backend := newTestLocalBackend(t)
+
+	const key = "archives/12/12_1.pdf"
+	const content = "0123456789"
+
+	if err := backend.Put(ctx, key, strings.NewReader(content), int64(len(content)), "application/pdf"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	reader, err := backend.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got := readAll(t, reader); got != content {
+		t.Fatalf("Get = %q, want %q", got, content)
+	}
+
+	ranges := []struct {
+		offset, length int64
+		want           string
+	}{
+		{offset: 0, length: 4, want: "0123"},
+		{offset: 3, length: 4, want: "3456"},
+		{offset: 7, length: -1, want: "789"},
+		{offset: 8, length: 10, want: "89"},
+		{offset: 2, length: 0, want: ""},
+	}
+	for _, tt := range ranges {
+		reader, err := backend.OpenRange(ctx, key, tt.offset, tt.length)
+		if err != nil {
+			t.Fatalf("OpenRange(%d, %d): %v", tt.offset, tt.length, err)
+		}
+		if got := readAll(t, reader); got != tt.want {
+			t.Errorf("OpenRange(%d, %d) = %q, want %q", tt.offset, tt.length, got, tt.want)
+		}
+	}
+
+	info, err := backend.Stat(ctx, key)
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if info.Size != int64(len(content)) || info.ContentType != "application/pdf" {
+		t.Fatalf("Stat = %+v", info)
+	}
+
+	if err := backend.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := backend.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete of a missing key: %v", err)
+	}
+	if _, err := backend.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after Delete: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestLocalBackendList(t *testing.T) {
+	ctx := context.Background()
+	backend := newTestLocalBackend(t)
+
+	for _, key := range []string{"archives/1/a.pdf", "archives/2/b.png", "thumbnails/1.jpg"} {
+		if err := backend.Put(ctx, key, strings.NewReader(key), -1, ""); err != nil {
+			t.Fatalf("Put(%q): %v", key, err)
+		}
+	}
+
+	// A temporary file left by an interrupted Put is not an object.
+	if err := os.WriteFile(filepath.Join(backend.root, "archives", "1", ".partial.tmp"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	objects, err := backend.List(ctx, "archives/")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+
+	var keys []string
+	for _, object := range objects {
+		keys = append(keys, object.Key)
+	}
+	sort.Strings(keys)
+
+	if got, want := strings.Join(keys, ","), "archives/1/a.pdf,archives/2/b.png"; got != want {
+		t.Fatalf("List keys = %s, want %s", got, want)
+	}
+}
+
+func TestLocalBackendNotFound(t *testing.T) {
+	ctx := context.Background()
+	backend := newTestLocalBackend(t)
+
+	const key = "archives/missing.pdf"
+
+	if _, err := backend.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get: err = %v, want ErrNotFound", err)
+	}
+	if _, err := backend.OpenRange(ctx, key, 5, 10); !errors.Is(err, ErrNotFound) {
+		t.Errorf("OpenRange: err = %v, want ErrNotFound", err)
+	}
+	if _, err := backend.Stat(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Stat: err = %v, want ErrNotFound", err)
+	}
+	if _, _, err := Materialize(ctx, backend, key, t.TempDir()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Materialize: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestReadSeeker(t *testing.T) {
+	ctx := context.Background()
+	backend := newTestLocalBackend(t)
+
+	const key = "archives/12/12_1.pdf"
+	const content = "0123456789"
+	if err := backend.Put(ctx, key, strings.NewReader(content), int64(len(content)), ""); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	readSeeker := NewReadSeeker(ctx, backend, key, int64(len(content)))
+	defer readSeeker.Close()
+
+	if _, err := readSeeker.Seek(-3, io.SeekEnd); err != nil {
+		t.Fatalf("Seek: %v", err)
+	}
+	if got := readAll(t, io.NopCloser(readSeeker)); got != "789" {
+		t.Fatalf("read after SeekEnd = %q, want %q", got, "789")
+	}
+
+	if _, err := readSeeker.Seek(2, io.SeekStart); err != nil {
+		t.Fatalf("Seek: %v", err)
+	}
+	buf := make([]byte, 3)
+	if _, err := io.ReadFull(readSeeker, buf); err != nil || string(buf) != "234" {
+		t.Fatalf("read after SeekStart = %q, %v", buf, err)
+	}
+
+	if _, err := readSeeker.Seek(-10, io.SeekCurrent); err == nil {
+		t.Fatal("Seek before the start succeeded")
+	}
+}
+
+func readAll(t *testing.T, reader io.ReadCloser) string {
+	t.Helper()
+	defer reader.Close()
+
+	b, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	return string(b)
+}
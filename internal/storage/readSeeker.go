@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var errNegativeOffset = errors.New("storage: negative offset")
+
+// ReadSeeker adapts a stored object to io.ReadSeeker so it can be served with
+// http.ServeContent. Seeking is free; the object is only opened, through
+// OpenRange, on the first Read after a seek.
+type ReadSeeker struct {
+	ctx     context.Context
+	backend Backend
+	key     string
+	size    int64
+
+	offset int64
+	reader io.ReadCloser
+}
+
+// NewReadSeeker returns a ReadSeeker over the object stored under key. size
+// must be the object's size, as reported by Stat. Close must be called.
+func NewReadSeeker(ctx context.Context, backend Backend, key string, size int64) *ReadSeeker {
+	return &ReadSeeker{ctx: ctx, backend: backend, key: key, size: size}
+}
+
+func (r *ReadSeeker) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+
+	if r.reader == nil {
+		reader, err := r.backend.OpenRange(r.ctx, r.key, r.offset, -1)
+		if err != nil {
+			return 0, err
+		}
+		r.reader = reader
+	}
+
+	n, err := r.reader.Read(p)
+	r.offset += int64(n)
+	return n, err
+}
+
+func (r *ReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	}
+
+	if offset < 0 {
+		return 0, errNegativeOffset
+	}
+
+	if offset != r.offset {
+		r.closeReader()
+		r.offset = offset
+	}
+
+	return offset, nil
+}
+
+func (r *ReadSeeker) Close() error {
+	return r.closeReader()
+}
+
+func (r *ReadSeeker) closeReader() error {
+	if r.reader == nil {
+		return nil
+	}
+
+	err := r.reader.Close()
+	r.reader = nil
+	return err
+}
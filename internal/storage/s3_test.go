@@ -0,0 +1,341 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 is a small stand-in for an S3-compatible service, covering the
+// bucket and object calls S3Backend makes. Signatures are not checked.
+type fakeS3 struct {
+	mu      sync.Mutex
+	buckets map[string]bool
+	objects map[string]fakeS3Object
+}
+
+type fakeS3Object struct {
+	data        []byte
+	contentType string
+	modTime     time.Time
+}
+
+func newFakeS3(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	fake := &fakeS3{buckets: map[string]bool{}, objects: map[string]fakeS3Object{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+
+	if key == "" {
+		f.serveBucket(w, r, bucket)
+		return
+	}
+
+	if !f.buckets[bucket] {
+		writeS3Error(w, r, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		data, err := readS3Body(r)
+		if err != nil {
+			writeS3Error(w, r, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+
+		f.objects[bucket+"/"+key] = fakeS3Object{
+			data:        data,
+			contentType: r.Header.Get("Content-Type"),
+			modTime:     time.Now().UTC().Truncate(time.Second),
+		}
+		w.Header().Set("ETag", `"`+strconv.Itoa(len(data))+`"`)
+		w.WriteHeader(http.StatusOK)
+
+	case http.MethodGet, http.MethodHead:
+		object, ok := f.objects[bucket+"/"+key]
+		if !ok {
+			writeS3Error(w, r, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+
+		data := object.data
+		status := http.StatusOK
+		if start, end, ok := parseRange(r.Header.Get("Range"), int64(len(data))); ok {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+			data = data[start : end+1]
+			status = http.StatusPartialContent
+		}
+
+		w.Header().Set("Content-Type", object.contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Header().Set("Last-Modified", object.modTime.Format(http.TimeFormat))
+		w.Header().Set("ETag", `"`+strconv.Itoa(len(object.data))+`"`)
+		w.WriteHeader(status)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(data)
+		}
+
+	case http.MethodDelete:
+		delete(f.objects, bucket+"/"+key)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeS3Error(w, r, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+func (f *fakeS3) serveBucket(w http.ResponseWriter, r *http.Request, bucket string) {
+	switch r.Method {
+	case http.MethodHead:
+		if !f.buckets[bucket] {
+			writeS3Error(w, r, http.StatusNotFound, "NoSuchBucket")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+
+	case http.MethodPut:
+		f.buckets[bucket] = true
+		w.WriteHeader(http.StatusOK)
+
+	case http.MethodGet:
+		if !f.buckets[bucket] {
+			writeS3Error(w, r, http.StatusNotFound, "NoSuchBucket")
+			return
+		}
+
+		type contents struct {
+			Key          string
+			Size         int64
+			LastModified string
+			ETag         string
+		}
+		result := struct {
+			XMLName     xml.Name `xml:"ListBucketResult"`
+			Name        string
+			Prefix      string
+			KeyCount    int
+			MaxKeys     int
+			IsTruncated bool
+			Contents    []contents
+		}{Name: bucket, Prefix: r.URL.Query().Get("prefix"), MaxKeys: 1000}
+
+		var keys []string
+		for objectKey := range f.objects {
+			objectBucket, name, _ := strings.Cut(objectKey, "/")
+			if objectBucket == bucket && strings.HasPrefix(name, result.Prefix) {
+				keys = append(keys, name)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, name := range keys {
+			object := f.objects[bucket+"/"+name]
+			result.Contents = append(result.Contents, contents{
+				Key:          name,
+				Size:         int64(len(object.data)),
+				LastModified: object.modTime.Format(time.RFC3339),
+				ETag:         `"` + strconv.Itoa(len(object.data)) + `"`,
+			})
+		}
+		result.KeyCount = len(result.Contents)
+
+		w.Header().Set("Content-Type", "application/xml")
+		_ = xml.NewEncoder(w).Encode(result)
+
+	default:
+		writeS3Error(w, r, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+// readS3Body reads a request body, decoding the aws-chunked encoding the
+// client uses to stream signed uploads over plain HTTP.
+func readS3Body(r *http.Request) ([]byte, error) {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return io.ReadAll(r.Body)
+	}
+
+	var data []byte
+	reader := bufio.NewReader(r.Body)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		sizeField, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		size, err := strconv.ParseInt(sizeField, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return data, nil
+		}
+
+		chunk := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, chunk); err != nil {
+			return nil, err
+		}
+		data = append(data, chunk[:size]...)
+	}
+}
+
+func parseRange(header string, size int64) (int64, int64, bool) {
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok {
+		return 0, 0, false
+	}
+
+	startField, endField, _ := strings.Cut(spec, "-")
+	start, err := strconv.ParseInt(startField, 10, 64)
+	if err != nil || start >= size {
+		return 0, 0, false
+	}
+
+	end := size - 1
+	if endField != "" {
+		if end, err = strconv.ParseInt(endField, 10, 64); err != nil {
+			return 0, 0, false
+		}
+		end = min(end, size-1)
+	}
+
+	return start, end, true
+}
+
+func writeS3Error(w http.ResponseWriter, r *http.Request, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+	}
+}
+
+func newTestS3Backend(t *testing.T) *S3Backend {
+	t.Helper()
+
+	server := newFakeS3(t)
+
+	backend, err := NewS3Backend(context.Background(), S3Options{
+		Endpoint:        strings.TrimPrefix(server.URL, "http://"),
+		AccessKeyID:     "test",
+		SecretAccessKey: "testtesttest",
+		Bucket:          "arsip",
+		Region:          "us-east-1",
+	})
+	if err != nil {
+		t.Fatalf("NewS3Backend: %v", err)
+	}
+
+	return backend
+}
+
+func TestS3BackendRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	backend := newTestS3Backend(t)
+
+	const key = "archives/12/12_1.pdf"
+	const content = "0123456789"
+
+	if err := backend.Put(ctx, key, strings.NewReader(content), int64(len(content)), "application/pdf"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	reader, err := backend.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got := readAll(t, reader); got != content {
+		t.Fatalf("Get = %q, want %q", got, content)
+	}
+
+	ranges := []struct {
+		offset, length int64
+		want           string
+	}{
+		{offset: 0, length: 4, want: "0123"},
+		{offset: 3, length: 4, want: "3456"},
+		{offset: 7, length: -1, want: "789"},
+		{offset: 2, length: 0, want: ""},
+	}
+	for _, tt := range ranges {
+		reader, err := backend.OpenRange(ctx, key, tt.offset, tt.length)
+		if err != nil {
+			t.Fatalf("OpenRange(%d, %d): %v", tt.offset, tt.length, err)
+		}
+		if got := readAll(t, reader); got != tt.want {
+			t.Errorf("OpenRange(%d, %d) = %q, want %q", tt.offset, tt.length, got, tt.want)
+		}
+	}
+
+	info, err := backend.Stat(ctx, key)
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if info.Size != int64(len(content)) || info.ContentType != "application/pdf" {
+		t.Fatalf("Stat = %+v", info)
+	}
+
+	if err := backend.Put(ctx, "thumbnails/12.jpg", strings.NewReader("jpg"), 3, "image/jpeg"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	objects, err := backend.List(ctx, "archives/")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(objects) != 1 || objects[0].Key != key || objects[0].Size != int64(len(content)) {
+		t.Fatalf("List = %+v", objects)
+	}
+
+	if err := backend.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := backend.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after Delete: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestS3BackendNotFound(t *testing.T) {
+	ctx := context.Background()
+	backend := newTestS3Backend(t)
+
+	const key = "archives/missing.pdf"
+
+	if _, err := backend.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get: err = %v, want ErrNotFound", err)
+	}
+	if _, err := backend.OpenRange(ctx, key, 5, 10); !errors.Is(err, ErrNotFound) {
+		t.Errorf("OpenRange: err = %v, want ErrNotFound", err)
+	}
+	if _, err := backend.OpenRange(ctx, key, 0, 0); !errors.Is(err, ErrNotFound) {
+		t.Errorf("OpenRange of zero bytes: err = %v, want ErrNotFound", err)
+	}
+	if _, err := backend.Stat(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Stat: err = %v, want ErrNotFound", err)
+	}
+	if _, _, err := Materialize(ctx, backend, key, t.TempDir()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Materialize: err = %v, want ErrNotFound", err)
+	}
+}
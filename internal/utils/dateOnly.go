@@ -13,8 +13,10 @@ type DateOnly struct {
 
 // JSON → Struct
 func (d *DateOnly) UnmarshalJSON(b []byte) error {
-	s := string(b)
-	s = s[1 : len(s)-1] // remove quotes
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
 
 	// Accept empty
 	if s == "" || s == "null" {
@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"crypto/subtle"
+
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -16,6 +18,19 @@ func CheckPasswordHash(password, hash string) bool {
 	return err == nil
 }
 
+// IsPasswordHashed reports whether the stored value is a bcrypt hash rather
+// than a legacy plaintext password.
+func IsPasswordHashed(stored string) bool {
+	_, err := bcrypt.Cost([]byte(stored))
+	return err == nil
+}
+
+// CheckLegacyPassword compares a plain password against a legacy plaintext
+// value in constant time.
+func CheckLegacyPassword(password, stored string) bool {
+	return subtle.ConstantTimeCompare([]byte(password), []byte(stored)) == 1
+}
+
 // usage
 // hashed, _ := utils.HashPassword("mysecret")
 // isValid := utils.CheckPasswordHash("mysecret", hashed)
@@ -10,12 +10,14 @@ import (
 type JWTService struct {
 	secretKey string
 	issuer    string
+	expiresIn time.Duration
 }
 
-func NewJWTService(secret, issuer string) *JWTService {
+func NewJWTService(secret, issuer string, expiresIn time.Duration) *JWTService {
 	return &JWTService{
 		secretKey: secret,
 		issuer:    issuer,
+		expiresIn: expiresIn,
 	}
 }
 
@@ -24,14 +26,20 @@ type Claim struct {
 	jwt.RegisteredClaims
 }
 
+// ExpiresIn returns the lifetime of the access tokens issued by this service
+func (j *JWTService) ExpiresIn() time.Duration {
+	return j.expiresIn
+}
+
 // GenerateToken creates a signed JWT token
 func (j *JWTService) GenerateToken(userID uint) (string, error) {
+	now := time.Now()
 	claims := &Claim{
 		UserID: userID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    j.issuer,
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(now.Add(j.expiresIn)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
@@ -43,7 +51,11 @@ func (j *JWTService) GenerateToken(userID uint) (string, error) {
 func (j *JWTService) ValidateToken(tokenStr string) (*Claim, error) {
 	token, err := jwt.ParseWithClaims(tokenStr, &Claim{}, func(token *jwt.Token) (interface{}, error) {
 		return []byte(j.secretKey), nil
-	})
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(j.issuer),
+		jwt.WithExpirationRequired(),
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -57,6 +69,6 @@ func (j *JWTService) ValidateToken(tokenStr string) (*Claim, error) {
 }
 
 // usage
-// jwtSvc := utils.NewJWTService(cfg.JWTSecret, "go-gin-app")
+// jwtSvc := utils.NewJWTService(cfg.JWTSecret, cfg.AppName, time.Duration(cfg.JWTExpiresIn)*time.Minute)
 // token, _ := jwtSvc.GenerateToken(user.ID)
 // claims, _ := jwtSvc.ValidateToken(token)
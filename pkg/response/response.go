@@ -7,8 +7,18 @@ import (
 )
 
 type APIResponse struct {
-	Message string      `json:"message"`
-	Data    interface{} `json:"data,omitempty"`
+	Message    string      `json:"message"`
+	Data       interface{} `json:"data,omitempty"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+}
+
+// Pagination describes the page of a list response. Total counts every
+// matching row; NextCursor is empty on the last page.
+type Pagination struct {
+	Total      int64  `json:"total"`
+	Page       int    `json:"page,omitempty"`
+	PageSize   int    `json:"pageSize,omitempty"`
+	NextCursor string `json:"nextCursor,omitempty"`
 }
 
 // Success sends a success JSON response.
@@ -19,6 +29,31 @@ func Success(c *gin.Context, data any) {
 	})
 }
 
+// Paginated sends a success JSON response with a page of a list.
+func Paginated(c *gin.Context, data any, pagination Pagination) {
+	c.JSON(http.StatusOK, APIResponse{
+		Message:    "success",
+		Data:       data,
+		Pagination: &pagination,
+	})
+}
+
+// Created sends a 201 JSON response with the created resource.
+func Created(c *gin.Context, data any) {
+	c.JSON(http.StatusCreated, APIResponse{
+		Message: "success",
+		Data:    data,
+	})
+}
+
+// Accepted sends a 202 JSON response for work that continues in the background.
+func Accepted(c *gin.Context, data any) {
+	c.JSON(http.StatusAccepted, APIResponse{
+		Message: "success",
+		Data:    data,
+	})
+}
+
 // Error sends an error JSON response.
 func Error(c *gin.Context, code int, message string) {
 	c.JSON(code, APIResponse{